@@ -273,8 +273,11 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 	if !found {
 		return nil, ErrLocked
 	}
-//根据链ID的存在，用EIP155或宅基地签名
-	if chainID != nil {
+//费用市场交易和访问列表交易自带链ID，DPoS操作总是用EIP155签名，其余根据链ID的存在，用EIP155或宅基地签名
+	if tx.IsDynamicFee() || tx.EnvelopeType() == types.AccessListTxType {
+		return types.SignTx(tx, types.NewFeeMarketSigner(tx.ChainId()), unlockedKey.PrivateKey)
+	}
+	if chainID != nil || tx.EnvelopeType() == types.DposTxType {
 		return types.SignTx(tx, types.NewEIP155Signer(chainID), unlockedKey.PrivateKey)
 	}
 	return types.SignTx(tx, types.HomesteadSigner{}, unlockedKey.PrivateKey)
@@ -301,8 +304,11 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	}
 	defer zeroKey(key.PrivateKey)
 
-//根据链ID的存在，用EIP155或宅基地签名
-	if chainID != nil {
+//费用市场交易和访问列表交易自带链ID，DPoS操作总是用EIP155签名，其余根据链ID的存在，用EIP155或宅基地签名
+	if tx.IsDynamicFee() || tx.EnvelopeType() == types.AccessListTxType {
+		return types.SignTx(tx, types.NewFeeMarketSigner(tx.ChainId()), key.PrivateKey)
+	}
+	if chainID != nil || tx.EnvelopeType() == types.DposTxType {
 		return types.SignTx(tx, types.NewEIP155Signer(chainID), key.PrivateKey)
 	}
 	return types.SignTx(tx, types.HomesteadSigner{}, key.PrivateKey)
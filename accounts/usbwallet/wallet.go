@@ -517,6 +517,10 @@ w.stateLock.RLock() //通信有自己的互斥，这是用于状态字段
 	if !ok {
 		return nil, accounts.ErrUnknownAccount
 	}
+//硬件钱包只能签名传统编码的交易
+	if tx.EnvelopeType() != types.LegacyTxType {
+		return nil, types.ErrTxTypeNotSupported
+	}
 //收集的所有信息和元数据均已签出，请求签名
 	<-w.commsLock
 	defer func() { w.commsLock <- struct{}{} }()
@@ -111,6 +111,10 @@ var (
 			"This means that an STDIN/STDOUT is used for RPC-communication with a e.g. a graphical user " +
 			"interface, and can be used when Clef is started by an external process.",
 	}
+	sealerFlag = cli.StringFlag{
+		Name:  "sealer",
+		Usage: "Comma separated list of DPoS validator accounts allowed to seal blocks via the sealer API (requires master seed)",
+	}
 	testFlag = cli.BoolFlag{
 		Name:  "stdio-ui-test",
 		Usage: "Mechanism to test interface between Clef and UI. Requires 'stdio-ui'.",
@@ -186,6 +190,7 @@ func init() {
 		auditLogFlag,
 		ruleFlag,
 		stdiouiFlag,
+		sealerFlag,
 		testFlag,
 	}
 	app.Action = signer
@@ -328,7 +333,9 @@ func signer(c *cli.Context) error {
 	log.Info("Loaded 4byte db", "signatures", db.Size(), "file", c.String("4bytedb"))
 
 	var (
-		api core.ExternalAPI
+		api         core.ExternalAPI
+		sealHistory storage.Storage
+		sealPwds    storage.Storage
 	)
 
 	configDir := c.String(configdirFlag.Name)
@@ -351,6 +358,11 @@ func signer(c *cli.Context) error {
 		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
 		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
 
+//出块签名历史，用于防止重复签名
+		sealkey := crypto.Keccak256([]byte("sealer"), stretchedKey)
+		sealHistory = storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "sealer.json"), sealkey)
+		sealPwds = pwStorage
+
 //我们有规则文件吗？
 		ruleJS, err := ioutil.ReadFile(c.String(ruleFlag.Name))
 		if err != nil {
@@ -404,6 +416,26 @@ func signer(c *cli.Context) error {
 			Service:   api,
 			Version:   "1.0"},
 	}
+	rpcModules := []string{"account"}
+	if c.IsSet(sealerFlag.Name) {
+		if sealHistory == nil {
+			utils.Fatalf("Block sealing requires a master seed to persist the signing history")
+		}
+		var validators []common.Address
+		for _, sealer := range splitAndTrim(c.String(sealerFlag.Name)) {
+			if !common.IsHexAddress(sealer) {
+				utils.Fatalf("Invalid sealer account %q", sealer)
+			}
+			validators = append(validators, common.HexToAddress(sealer))
+		}
+		rpcAPI = append(rpcAPI, rpc.API{
+			Namespace: "sealer",
+			Public:    true,
+			Service:   core.NewSealerAPI(apiImpl, validators, sealHistory, sealPwds),
+			Version:   "1.0"})
+		rpcModules = append(rpcModules, "sealer")
+		log.Info("Block sealing enabled", "validators", len(validators))
+	}
 	if c.Bool(utils.RPCEnabledFlag.Name) {
 
 		vhosts := splitAndTrim(c.GlobalString(utils.RPCVirtualHostsFlag.Name))
@@ -411,7 +443,7 @@ func signer(c *cli.Context) error {
 
 //
 		httpEndpoint := fmt.Sprintf("%s:%d", c.String(utils.RPCListenAddrFlag.Name), c.Int(rpcPortFlag.Name))
-		listener, _, err := rpc.StartHTTPEndpoint(httpEndpoint, rpcAPI, []string{"account"}, cors, vhosts, rpc.DefaultHTTPTimeouts)
+		listener, _, err := rpc.StartHTTPEndpoint(httpEndpoint, rpcAPI, rpcModules, cors, vhosts, rpc.DefaultHTTPTimeouts, nil)
 		if err != nil {
 			utils.Fatalf("Could not start RPC api: %v", err)
 		}
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/dashboard"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
@@ -167,6 +168,17 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+//如果需要，添加GraphQL服务。
+	if cfg.Node.GraphQLEndpoint() != "" {
+		auth, err := cfg.Node.RPCAuthenticator()
+		if err != nil {
+			utils.Fatalf("Failed to load the RPC authentication: %v", err)
+		}
+		if err := graphql.RegisterGraphQLService(stack, cfg.Node.GraphQLEndpoint(), cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPModules, cfg.Node.HTTPTimeouts, auth, cfg.Node.RPCRateLimits); err != nil {
+			utils.Fatalf("Failed to register the GraphQL service: %v", err)
+		}
+	}
+
 //如果需要，添加ethereum stats守护进程。
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
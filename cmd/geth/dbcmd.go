@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Low level database operations",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "migrate",
+				Usage:     "Copy the chain database into another storage engine",
+				ArgsUsage: "<engine>",
+				Action:    utils.MigrateFlags(migrateDB),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+				},
+				Description: `
+    geth db migrate badger
+
+copies every entry of the chain database into a new database using the given
+storage engine and swaps it in place. The old database is kept next to the new
+one (suffixed with its engine name) and can be removed once the node has been
+verified to run on the new engine. The node must not be running.`,
+			},
+		},
+	}
+)
+
+//migrateDB把链数据库复制到另一个存储引擎，完成后用新数据库替换旧数据库
+func migrateDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the target engine as argument (available: %v)", ethdb.Engines())
+	}
+	target := ctx.Args().First()
+
+	stack, _ := makeConfigNode(ctx)
+	name := "chaindata"
+	if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		name = "lightchaindata"
+	}
+	path := stack.ResolvePath(name)
+	if !common.FileExist(path) {
+		utils.Fatalf("Database %s doesn't exist", path)
+	}
+	source := ethdb.DetectEngine(path)
+	if source == target {
+		utils.Fatalf("Database %s already uses the %s engine", path, target)
+	}
+//先写到临时目录，复制完成后再替换，失败时不影响原数据库
+	tmp := path + ".migrate"
+	if common.FileExist(tmp) {
+		utils.Fatalf("Unfinished migration found at %s, remove it first", tmp)
+	}
+	backup := path + "." + source
+	if common.FileExist(backup) {
+		utils.Fatalf("Backup location %s already exists, remove it first", backup)
+	}
+	cache := ctx.GlobalInt(utils.CacheFlag.Name)
+
+	src, err := ethdb.Open(source, path, cache/2, 256)
+	if err != nil {
+		utils.Fatalf("Could not open source database: %v", err)
+	}
+	dst, err := ethdb.Open(target, tmp, cache/2, 256)
+	if err != nil {
+		src.Close()
+		utils.Fatalf("Could not create target database: %v", err)
+	}
+	log.Info("Migrating database", "path", path, "from", source, "to", target)
+
+	start := time.Now()
+	count, err := ethdb.Copy(dst, src)
+	src.Close()
+	dst.Close()
+	if err != nil {
+		os.RemoveAll(tmp)
+		utils.Fatalf("Migration failed: %v", err)
+	}
+	if err := os.Rename(path, backup); err != nil {
+		utils.Fatalf("Failed to move old database to %s: %v", backup, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		utils.Fatalf("Failed to move new database to %s: %v", path, err)
+	}
+	fmt.Printf("Migrated %d entries in %v, old database kept at %s\n", count, common.PrettyDuration(time.Since(start)), backup)
+	return nil
+}
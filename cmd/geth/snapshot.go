@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	bloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter used for pruning",
+		Value: 2048,
+	}
+
+	snapshotCommand = cli.Command{
+		Name:      "snapshot",
+		Usage:     "Offline state maintenance",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Delete all state not reachable from the given block",
+				ArgsUsage: "[<blockNum>|<blockHash>]",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.AncientFlag,
+					utils.TestnetFlag,
+					utils.RinkebyFlag,
+					bloomFilterSizeFlag,
+				},
+				Description: `
+    geth snapshot prune-state [<blockNum>|<blockHash>]
+
+marks every account trie node, storage trie node, contract code and DPoS trie
+node reachable from the given canonical block (the current head by default) and
+deletes all other state from the chain database. States of other blocks are no
+longer available afterwards. If the target is below the current head, the chain
+head is rewound to the target before anything is deleted and the blocks above it
+have to be synced again. The target cannot be below the ancient store. A flat
+state snapshot of a different block is dropped and regenerated on the next start.
+
+The node must be stopped: the command refuses to run while another process holds
+the database. Reachable state is marked completely before anything is deleted,
+so an interrupted run can simply be restarted. A larger bloom filter leaves less
+garbage behind.`,
+			},
+		},
+	}
+)
+
+//pruneState删除目标区块不可达的状态数据
+func pruneState(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command accepts at most one argument")
+	}
+	stack, _ := makeConfigNode(ctx)
+
+//数据库目录被运行中的节点锁定时打开会失败，这保证了节点已经停止
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	store, ok := chainDb.(ethdb.Store)
+	if !ok {
+		utils.Fatalf("Database engine doesn't support iteration, pruning impossible")
+	}
+	header, err := canonicalHeader(chainDb, ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Invalid pruning target: %v", err)
+	}
+//冻结的区块不可逆，也无法从冻结表中删除，不能回退到冻结高度以下
+	if ancients, ok := chainDb.(rawdb.AncientReader); ok {
+		if frozen, err := ancients.Ancients(); err == nil && frozen > header.Number.Uint64()+1 {
+			utils.Fatalf("Pruning target %d is below the ancient store (%d frozen blocks)", header.Number, frozen)
+		}
+	}
+	if head := rawdb.ReadHeaderNumber(chainDb, rawdb.ReadHeadBlockHash(chainDb)); head != nil && *head > header.Number.Uint64() {
+		log.Warn("Pruning target below head, blocks above it will be dropped", "target", header.Number, "head", *head)
+	}
+	if _, err := state.New(header.Root, state.NewDatabase(chainDb)); err != nil {
+		utils.Fatalf("State of block %d is not available: %v", header.Number, err)
+	}
+	log.Info("Pruning state", "number", header.Number, "hash", header.Hash(), "root", header.Root)
+
+	start := time.Now()
+	if err := pruner.NewPruner(store, ctx.Uint64(bloomFilterSizeFlag.Name)).Prune(header); err != nil {
+		utils.Fatalf("Pruning failed: %v", err)
+	}
+	fmt.Printf("Pruned state of block %d in %v\n", header.Number, common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+//canonicalHeader解析命令行指定的区块，arg为空时使用当前头区块。
+//目标必须是不高于头区块的规范区块
+func canonicalHeader(db ethdb.Database, arg string) (*types.Header, error) {
+	headHash := rawdb.ReadHeadBlockHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		return nil, fmt.Errorf("head block missing")
+	}
+	var (
+		hash   = headHash
+		number = *headNumber
+	)
+	if arg != "" {
+		if hashish(arg) {
+			hash = common.HexToHash(arg)
+			n := rawdb.ReadHeaderNumber(db, hash)
+			if n == nil {
+				return nil, fmt.Errorf("block %x not found", hash)
+			}
+			number = *n
+		} else {
+			n, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			number, hash = n, rawdb.ReadCanonicalHash(db, n)
+		}
+	}
+	if number > *headNumber {
+		return nil, fmt.Errorf("block %d above head %d", number, *headNumber)
+	}
+	if rawdb.ReadCanonicalHash(db, number) != hash {
+		return nil, fmt.Errorf("block %d is not canonical", number)
+	}
+	header := rawdb.ReadHeader(db, hash, number)
+	if header == nil {
+		return nil, fmt.Errorf("header %d missing", number)
+	}
+	return header, nil
+}
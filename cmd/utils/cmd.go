@@ -17,6 +17,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"os/signal"
 	"runtime"
@@ -24,8 +25,10 @@ import (
 	"syscall"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -234,7 +237,7 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 }
 
 //importpreimages将一批导出的哈希预映像导入数据库。
-func ImportPreimages(db *ethdb.LDBDatabase, fn string) error {
+func ImportPreimages(db ethdb.Database, fn string) error {
 	log.Info("Importing preimages", "file", fn)
 
 //打开文件句柄并可能打开gzip流
@@ -281,7 +284,7 @@ func ImportPreimages(db *ethdb.LDBDatabase, fn string) error {
 
 //exportpreimages将所有已知的哈希preimages导出到指定的文件中，
 //
-func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
+func ExportPreimages(db ethdb.Store, fn string) error {
 	log.Info("Exporting preimages", "file", fn)
 
 //打开文件句柄并可能使用gzip流进行包装
@@ -298,6 +301,8 @@ func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
 	}
 //迭代预映像并导出它们
 	it := db.NewIteratorWithPrefix([]byte("secure-key-"))
+	defer it.Release()
+
 	for it.Next() {
 		if err := rlp.Encode(writer, it.Value()); err != nil {
 			return err
@@ -307,3 +312,161 @@ func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
 	return nil
 }
 
+
+//stateExportVersion是状态导出文件的格式版本
+const stateExportVersion = 2
+
+//stateExportHeader是状态导出文件的第一项，记录状态所属的链和区块。
+//之后是state.ExportState写入的状态流。
+type stateExportHeader struct {
+	Version   uint64
+	Genesis   common.Hash
+	Block     *types.Block
+	Td        *big.Int
+Ancestors []*types.Header //从父块向前直到最近一个携带验证人列表的区块头
+}
+
+//validatorAncestors返回从header的父块向前直到最近一个携带验证人列表的祖先区块头。
+//DPOS从这些区块头回溯子区块的验证人集合，导入时必须一并写入，否则无法验证后续区块。
+//header本身携带列表或在验证人列表分叉之前（验证人集合取自导入的DposContext）时返回空
+func validatorAncestors(db ethdb.Database, header *types.Header) ([]*types.Header, error) {
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil || config.Dpos == nil {
+		return nil, nil
+	}
+	var ancestors []*types.Header
+	for header.Number.Sign() > 0 && config.Dpos.IsValidatorList(header.Number) {
+		validators, err := dpos.ExtraValidators(header)
+		if err != nil {
+			return nil, err
+		}
+		if len(validators) > 0 {
+			break
+		}
+		number := header.Number.Uint64() - 1
+		if header = rawdb.ReadHeader(db, header.ParentHash, number); header == nil {
+			return nil, fmt.Errorf("header %d missing", number)
+		}
+		ancestors = append(ancestors, header)
+	}
+	return ancestors, nil
+}
+
+//ExportState把block的状态（账户、存储、代码和DPoS上下文）导出到指定文件，
+//文件名以.gz结尾时压缩。
+func ExportState(db ethdb.Database, block *types.Block, fn string) error {
+	log.Info("Exporting state", "number", block.Number(), "hash", block.Hash(), "file", fn)
+
+	td := rawdb.ReadTd(db, block.Hash(), block.NumberU64())
+	if td == nil {
+		return fmt.Errorf("total difficulty of block %d missing", block.Number())
+	}
+	ancestors, err := validatorAncestors(db, block.Header())
+	if err != nil {
+		return err
+	}
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	header := &stateExportHeader{
+		Version:   stateExportVersion,
+		Genesis:   rawdb.ReadCanonicalHash(db, 0),
+		Block:     block,
+		Td:        td,
+		Ancestors: ancestors,
+	}
+	if err := rlp.Encode(writer, header); err != nil {
+		return err
+	}
+	if err := state.ExportState(db, block.Root(), block.Header().DposContext, writer); err != nil {
+		return err
+	}
+	log.Info("Exported state", "file", fn)
+	return nil
+}
+
+//ImportState从ExportState导出的文件导入状态，并校验状态根和DPoS上下文与区块头一致。
+//区块已经是本地的规范区块时只恢复它的状态；数据库中只有创世区块时，
+//还会写入该区块以及回溯验证人集合所需的祖先区块头，并把链头设为该区块，
+//从这个高度开始继续同步或出块。
+func ImportState(db ethdb.Database, fn string) (*types.Block, error) {
+	log.Info("Importing state", "file", fn)
+
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	var header stateExportHeader
+	if err := stream.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Version != stateExportVersion {
+		return nil, fmt.Errorf("unsupported state export version %d", header.Version)
+	}
+	if genesis := rawdb.ReadCanonicalHash(db, 0); genesis != header.Genesis {
+		return nil, fmt.Errorf("genesis mismatch: have %x, want %x", genesis, header.Genesis)
+	}
+	block := header.Block
+	canonical := rawdb.ReadCanonicalHash(db, block.NumberU64()) == block.Hash()
+	if !canonical {
+		if head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db)); head == nil || *head != 0 {
+			return nil, fmt.Errorf("database already contains a different chain, use a freshly initialised data directory")
+		}
+	}
+	root, dpos, err := state.ImportState(db, stream)
+	if err != nil {
+		return nil, err
+	}
+	if root != block.Root() {
+		return nil, fmt.Errorf("state root mismatch: have %x, want %x", root, block.Root())
+	}
+	if want := block.Header().DposContext; want == nil || *dpos != *want {
+		return nil, fmt.Errorf("dpos context mismatch: have %+v, want %+v", dpos, want)
+	}
+	if !canonical {
+		if err := writeAncestors(db, block.Header(), header.Td, header.Ancestors); err != nil {
+			return nil, err
+		}
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteTd(db, block.Hash(), block.NumberU64(), header.Td)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteHeadHeaderHash(db, block.Hash())
+		rawdb.WriteHeadFastBlockHash(db, block.Hash())
+	}
+	log.Info("Imported state", "number", block.Number(), "hash", block.Hash(), "root", root)
+	return block, nil
+}
+
+//writeAncestors检查祖先区块头依次相连，并作为规范链写入它们的区块头和总难度
+func writeAncestors(db ethdb.Database, child *types.Header, td *big.Int, ancestors []*types.Header) error {
+	for _, header := range ancestors {
+		if header.Hash() != child.ParentHash || header.Number.Uint64()+1 != child.Number.Uint64() {
+			return fmt.Errorf("ancestor header %d does not match block %d", header.Number, child.Number)
+		}
+		td = new(big.Int).Sub(td, child.Difficulty)
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteTd(db, header.Hash(), header.Number.Uint64(), td)
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		child = header
+	}
+	return nil
+}
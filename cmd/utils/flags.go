@@ -33,6 +33,7 @@ import (
 //“github.com/ethereum/go-ethereum/consultance/ethash”
 	"github.com/ethereum/go-ethereum/consensus/dpos"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -118,6 +119,32 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Storage engine for new databases (" + strings.Join(ethdb.Engines(), ", ") + "); existing databases keep their engine",
+		Value: "",
+	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Directory for frozen irreversible blocks (default = \"ancient\" inside the datadir)",
+	}
+	AncientMarginFlag = cli.Uint64Flag{
+		Name:  "ancient.margin",
+		Usage: "Number of blocks behind the irreversible block kept in the key-value database",
+		Value: eth.DefaultConfig.FreezerMargin,
+	}
+	NoAncientFlag = cli.BoolFlag{
+		Name:  "ancient.disable",
+		Usage: "Disables moving irreversible blocks into the ancient store",
+	}
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "index.address",
+		Usage: "Maintains a per-address transaction index (enables eth_getTransactionsByAddress)",
+	}
+	ParallelTxsFlag = cli.IntFlag{
+		Name:  "parallel.txs",
+		Usage: "Number of threads executing block transactions optimistically in parallel (0 or 1 = sequential)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -245,6 +272,10 @@ var (
 		Usage: "Disk journal for local transaction to survive node restarts",
 		Value: core.DefaultTxPoolConfig.Journal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote (including queued) transactions to survive node restarts (disabled if empty)",
+	}
 	TxPoolRejournalFlag = cli.DurationFlag{
 		Name:  "txpool.rejournal",
 		Usage: "Time interval to regenerate the local transaction journal",
@@ -285,6 +316,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolDposSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.dposslots",
+		Usage: "Maximum number of DPoS transactions protected from price based eviction",
+		Value: eth.DefaultConfig.TxPool.DposSlots,
+	}
 //性能调整设置
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -350,6 +386,10 @@ var (
 		Usage: "Public address for block mining signer (default = first account created)",
 		Value: "0",
 	}
+	DposSignerFlag = cli.StringFlag{
+		Name:  "dpos.signer",
+		Usage: "External signer endpoint (clef IPC path or HTTP URL) used to seal DPoS blocks instead of a local unlocked account",
+	}
 	CoinbaseFlag = cli.StringFlag{
 		Name:  "coinbase",
 		Usage: "Public address for block mining rewards (default = first account created)",
@@ -373,6 +413,11 @@ var (
 		Usage: "Time interval to recreate the block being mined.",
 		Value: eth.DefaultConfig.MinerRecommit,
 	}
+	MinerDposQuotaFlag = cli.IntFlag{
+		Name:  "miner.dposquota",
+		Usage: "Number of DPoS transactions included ahead of price ordering in each block (0 = disabled)",
+		Value: eth.DefaultConfig.MinerDposQuota,
+	}
 //帐户设置
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -427,11 +472,40 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded 32 byte secret; HTTP and WS RPC requests must carry an HS256 JWT signed with it and issued (iat) within 60 seconds",
+		Value: "",
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -761,6 +835,30 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+//JWT密钥同时作用于HTTP和WebSocket终结点
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.RPCJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+}
+
+//setGraphQL从命令行标志创建GraphQL侦听器接口字符串，
+//如果未启用GraphQL，则保持为空。
+func setGraphQL(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(GraphQLEnabledFlag.Name) && cfg.GraphQLHost == "" {
+		cfg.GraphQLHost = "127.0.0.1"
+		if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+			cfg.GraphQLHost = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.GraphQLPort = ctx.GlobalInt(GraphQLPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.GraphQLCors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
 }
 
 //setws从集合创建websocket rpc侦听器接口字符串
@@ -971,6 +1069,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetP2PConfig(ctx, &cfg.P2P)
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
+	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
@@ -985,6 +1084,9 @@ cfg.DataDir = "" //除非明确要求，否则使用内存数据库
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "rinkeby")
 	}
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DBEngine = ctx.GlobalString(DBEngineFlag.Name)
+	}
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
@@ -1022,6 +1124,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
 		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
@@ -1046,6 +1151,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolDposSlotsFlag.Name) {
+		cfg.DposSlots = ctx.GlobalUint64(TxPoolDposSlotsFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1147,6 +1255,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientMarginFlag.Name) {
+		cfg.FreezerMargin = ctx.GlobalUint64(AncientMarginFlag.Name)
+	}
+	if ctx.GlobalIsSet(NoAncientFlag.Name) {
+		cfg.NoFreezer = ctx.GlobalBool(NoAncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(ParallelTxsFlag.Name) {
+		cfg.ParallelTxs = ctx.GlobalInt(ParallelTxsFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1168,6 +1291,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
+	if ctx.GlobalIsSet(DposSignerFlag.Name) {
+		cfg.DposSigner = ctx.GlobalString(DposSignerFlag.Name)
+	}
 	if ctx.GlobalIsSet(MinerLegacyExtraDataFlag.Name) {
 		cfg.MinerExtraData = []byte(ctx.GlobalString(MinerLegacyExtraDataFlag.Name))
 	}
@@ -1183,6 +1309,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(MinerRecommitIntervalFlag.Name) {
 		cfg.MinerRecommit = ctx.Duration(MinerRecommitIntervalFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerDposQuotaFlag.Name) {
+		cfg.MinerDposQuota = ctx.GlobalInt(MinerDposQuotaFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 //TODO（FJL）：强制启用--dev模式
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
@@ -1339,6 +1468,16 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+//全节点的旧区块可能已经移入冻结表，离线命令也要能读到
+	if store, ok := chainDb.(ethdb.Store); ok && name == "chaindata" {
+		dir := ctx.GlobalString(AncientFlag.Name)
+		if dir == "" {
+			dir = stack.ResolvePath("ancient")
+		}
+		if chainDb, err = rawdb.NewDatabaseWithFreezer(store, dir); err != nil {
+			Fatalf("Could not open ancient database: %v", err)
+		}
+	}
 	return chainDb
 }
 
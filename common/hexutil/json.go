@@ -68,6 +68,25 @@ func (b Bytes) String() string {
 	return Encode(b)
 }
 
+//ImplementsGraphQLType在b实现指定的GraphQL类型时返回true。
+func (b Bytes) ImplementsGraphQLType(name string) bool { return name == "Bytes" }
+
+//UnmarshalGraphQL解析GraphQL查询中的参数。
+func (b *Bytes) UnmarshalGraphQL(input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case string:
+		var data []byte
+		data, err = Decode(input)
+		if err == nil {
+			*b = data
+		}
+	default:
+		err = fmt.Errorf("unexpected type %T for Bytes", input)
+	}
+	return err
+}
+
 //unmarshalfixedjson将输入解码为带0x前缀的字符串。输出长度
 //确定所需的输入长度。此函数通常用于实现
 //固定大小类型的unmashaljson方法。
@@ -183,6 +202,25 @@ func (b *Big) String() string {
 	return EncodeBig(b.ToInt())
 }
 
+//ImplementsGraphQLType在b实现指定的GraphQL类型时返回true。
+func (b Big) ImplementsGraphQLType(name string) bool { return name == "BigInt" }
+
+//UnmarshalGraphQL解析GraphQL查询中的参数。
+func (b *Big) UnmarshalGraphQL(input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case string:
+		return b.UnmarshalText([]byte(input))
+	case int32:
+		var num big.Int
+		num.SetInt64(int64(input))
+		*b = Big(num)
+	default:
+		err = fmt.Errorf("unexpected type %T for BigInt", input)
+	}
+	return err
+}
+
 //uint64以带有0x前缀的JSON字符串封送/取消封送。
 //零值封送为“0x0”。
 type Uint64 uint64
@@ -230,6 +268,23 @@ func (b Uint64) String() string {
 	return EncodeUint64(uint64(b))
 }
 
+//ImplementsGraphQLType在b实现指定的GraphQL类型时返回true。
+func (b Uint64) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+//UnmarshalGraphQL解析GraphQL查询中的参数。
+func (b *Uint64) UnmarshalGraphQL(input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case string:
+		return b.UnmarshalText([]byte(input))
+	case int32:
+		*b = Uint64(input)
+	default:
+		err = fmt.Errorf("unexpected type %T for Long", input)
+	}
+	return err
+}
+
 //
 //零值封送为“0x0”。
 type Uint uint
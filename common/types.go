@@ -100,6 +100,21 @@ func (h Hash) MarshalText() ([]byte, error) {
 	return hexutil.Bytes(h[:]).MarshalText()
 }
 
+//ImplementsGraphQLType在h实现指定的GraphQL类型时返回true。
+func (h Hash) ImplementsGraphQLType(name string) bool { return name == "Bytes32" }
+
+//UnmarshalGraphQL解析GraphQL查询中的参数。
+func (h *Hash) UnmarshalGraphQL(input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case string:
+		err = h.UnmarshalText([]byte(input))
+	default:
+		err = fmt.Errorf("unexpected type %T for Bytes32", input)
+	}
+	return err
+}
+
 //setbytes将哈希值设置为b。
 //如果b大于len（h），b将从左侧裁剪。
 func (h *Hash) SetBytes(b []byte) {
@@ -246,6 +261,21 @@ func (a *Address) UnmarshalJSON(input []byte) error {
 	return hexutil.UnmarshalFixedJSON(addressT, input, a[:])
 }
 
+//ImplementsGraphQLType在a实现指定的GraphQL类型时返回true。
+func (a Address) ImplementsGraphQLType(name string) bool { return name == "Address" }
+
+//UnmarshalGraphQL解析GraphQL查询中的参数。
+func (a *Address) UnmarshalGraphQL(input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case string:
+		err = a.UnmarshalText([]byte(input))
+	default:
+		err = fmt.Errorf("unexpected type %T for Address", input)
+	}
+	return err
+}
+
 //scan实现数据库/sql的scanner。
 func (a *Address) Scan(src interface{}) error {
 	srcB, ok := src.([]byte)
@@ -16,10 +16,13 @@ import (
 	"encoding/binary"
 	"errors"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
 	"math/rand"
@@ -63,6 +66,84 @@ func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error)
 	return validators, nil
 }
 
+var (
+//如果请求的区块没有变更验证人集合，返回errNoValidatorTransition
+	errNoValidatorTransition = errors.New("block does not change the validator set")
+//如果变更验证人集合的区块还没有被足够多的验证人确认，返回errTransitionNotConfirmed
+	errTransitionNotConfirmed = errors.New("validator set transition not confirmed yet")
+)
+
+//EpochProof是验证人集合变更的证明，供跨链桥等只持有可信验证人集合的
+//轻客户端验证对方链的区块头。区块头均为RLP编码，保证哈希可以重新计算
+type EpochProof struct {
+	Header        hexutil.Bytes    `json:"header"`        //变更验证人集合的区块头（新周期第一个区块或周期内补位的区块）
+	Validators    []common.Address `json:"validators"`    //新的验证人集合
+	Proof         []hexutil.Bytes  `json:"proof"`         //验证人集合在DposContextProto.EpochHash下的默克尔证明
+	Confirmations []hexutil.Bytes  `json:"confirmations"` //其后连续的区块头，直到新验证人集合的2/3+1签名确认
+}
+
+//GetEpochProof返回给定区块变更验证人集合的证明。
+//轻客户端需要依次验证每一次变更（包括周期内的补位），才能持续跟踪验证人集合
+func (api *API) GetEpochProof(number rpc.BlockNumber) (*EpochProof, error) {
+	var header *types.Header
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil || header.Number.Sign() == 0 {
+		return nil, errUnknownBlock
+	}
+	validators, err := ExtraValidators(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(validators) == 0 {
+		return nil, errNoValidatorTransition
+	}
+	epochTrie, err := types.NewEpochTrie(header.DposContext.EpochHash, trie.NewDatabase(api.dpos.db))
+	if err != nil {
+		return nil, err
+	}
+	proofDb := ethdb.NewMemDatabase()
+	if err := epochTrie.Prove(types.ValidatorsKey, 0, proofDb); err != nil {
+		return nil, err
+	}
+	proof := &EpochProof{Validators: validators}
+	for _, key := range proofDb.Keys() {
+		node, _ := proofDb.Get(key)
+		proof.Proof = append(proof.Proof, node)
+	}
+	if proof.Header, err = rlp.EncodeToBytes(header); err != nil {
+		return nil, err
+	}
+
+//变更区块由原验证人集合出块，其后的区块由新的集合出块。向后收集区块头，
+//直到新集合中有2/3+1个不同的验证人签名，最多收集一个选举周期
+	sealing := make(map[common.Address]bool)
+	for _, validator := range validators {
+		sealing[validator] = true
+	}
+	threshold := len(validators)*2/3 + 1
+	last := header.Number.Uint64() + uint64(epochInterval)/api.chain.GetHeaderByNumber(0).BlockInterval
+	signed := make(map[common.Address]bool)
+	for number := header.Number.Uint64() + 1; len(signed) < threshold; number++ {
+		cur := api.chain.GetHeaderByNumber(number)
+		if cur == nil || number > last {
+			return nil, errTransitionNotConfirmed
+		}
+		enc, err := rlp.EncodeToBytes(cur)
+		if err != nil {
+			return nil, err
+		}
+		proof.Confirmations = append(proof.Confirmations, enc)
+		if sealing[cur.Validator] {
+			signed[cur.Validator] = true
+		}
+	}
+	return proof, nil
+}
+
 //getconfirmedBlockNumber检索最新的不可逆块
 func (api *API) GetConfirmedBlockNumber() (*big.Int, error) {
 	var err error
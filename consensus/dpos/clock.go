@@ -0,0 +1,137 @@
+package dpos
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Clock是出块时间槽所使用的时钟。默认使用系统时钟，
+//模拟测试中可以换成所有节点共享的模拟时钟
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+//SimulatedClock是手动推进的墙上时钟，时间只在调用Run时前进，
+//定时器按到期时间顺序触发，使多节点模拟可以复现
+type SimulatedClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*simTimer
+changed chan struct{} //有新定时器挂上时关闭并替换
+}
+
+type simTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+//NewSimulatedClock创建从给定时间开始的模拟时钟
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{now: start, changed: make(chan struct{})}
+}
+
+//Now返回当前的模拟时间
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+//After返回在模拟时间经过d之后收到当时时间的通道
+func (c *SimulatedClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, &simTimer{at: c.now.Add(d), ch: ch})
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return ch
+}
+
+//Run把模拟时间推进d，并依次触发期间到期的定时器
+func (c *SimulatedClock) Run(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].at.After(end) {
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = timer.at
+		timer.ch <- c.now
+	}
+	c.now = end
+}
+
+//ActiveTimers返回尚未触发的定时器数量
+func (c *SimulatedClock) ActiveTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+//WaitForTimers阻塞到至少有n个尚未触发的定时器为止，abort关闭时返回false。
+//不需要轮询，定时器挂上时立即唤醒
+func (c *SimulatedClock) WaitForTimers(n int, abort <-chan struct{}) bool {
+	for {
+		c.mu.Lock()
+		if len(c.timers) >= n {
+			c.mu.Unlock()
+			return true
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-abort:
+			return false
+		}
+	}
+}
+
+//SkewedClock在另一个时钟的基础上加上可调整的偏差，
+//用来模拟单个节点的时钟漂移
+type SkewedClock struct {
+	base Clock
+
+	mu   sync.RWMutex
+	skew time.Duration
+}
+
+//NewSkewedClock创建基于base的带偏差时钟
+func NewSkewedClock(base Clock, skew time.Duration) *SkewedClock {
+	return &SkewedClock{base: base, skew: skew}
+}
+
+//SetSkew修改时钟偏差
+func (c *SkewedClock) SetSkew(skew time.Duration) {
+	c.mu.Lock()
+	c.skew = skew
+	c.mu.Unlock()
+}
+
+//Now返回加上偏差之后的时间
+func (c *SkewedClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.base.Now().Add(c.skew)
+}
+
+//After的等待时长与偏差无关，直接交给底层时钟
+func (c *SkewedClock) After(d time.Duration) <-chan time.Time {
+	return c.base.After(d)
+}
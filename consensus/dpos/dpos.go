@@ -67,6 +67,9 @@ byzantiumBlockReward *big.Int = big.NewInt(3e+18) //从拜占庭向上成功开
 
 	timeOfFirstBlock = int64(0)
 
+//EpochInterval是选举周期的长度，供模拟测试等外部代码按周期推进时间
+	EpochInterval = time.Duration(epochInterval) * time.Second
+
 	confirmedBlockHead = []byte("confirmed-block-head")
 )
 
@@ -106,15 +109,24 @@ db      ethdb.Database     //存储和检索快照检查点的数据库
 
 	signer               common.Address
 	signFn               SignerFn
+sealFn               SealerFn      //外部签名者（clef）的出块签名回调，设置后优先于signFn
 signatures           *lru.ARCCache //加快开采速度的近期区块特征
+validatorSets        *lru.ARCCache //近期区块头生效后的验证人集合，用于只凭区块头验证签名
 	confirmedBlockHeader *types.Header
+clock                Clock         //出块时间槽使用的时钟，模拟测试时可替换
 
-	mu   sync.RWMutex
-	stop chan bool
+	mu            sync.RWMutex
+	confirmedLock sync.Mutex //批量验证区块头时可能并发更新已确认区块
+	stop          chan bool
 }
 
 type SignerFn func(accounts.Account, []byte) ([]byte, error)
 
+//SealerFn是由外部签名者提供的出块签名回调。与SignerFn不同，
+//它收到的是完整的区块头，签名者可以在签名之前
+//自行检查区块高度和时间槽是否单调递增，防止重复签名
+type SealerFn func(accounts.Account, *types.Header) ([]byte, error)
+
 //注：Sighash是从集团复制的
 //sighash返回用作权限证明输入的哈希
 //签署。它是除65字节签名之外的整个头的哈希
@@ -126,7 +138,7 @@ type SignerFn func(accounts.Account, []byte) ([]byte, error)
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Validator,
@@ -145,29 +157,63 @@ header.Extra[:len(header.Extra)-65], //是的，如果多余的太短，这会
 		header.Nonce,
 		header.DposContext.Root(),
 header.MaxValidatorSize,			//
-	})
+	}
+//费用市场分叉之后签名同样覆盖基础费用，VerifyBaseFee保证分叉之前为空
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	rlp.Encode(hasher, enc)
 	hasher.Sum(hash[:0])
 
 	return hash
 }
 
+//SealHash返回区块签名所使用的哈希，外部签名者用它来
+//核对收到的区块头
+func SealHash(header *types.Header) common.Hash {
+	return sigHash(header)
+}
+
 func New(config *params.DposConfig, db ethdb.Database) *Dpos {
 	signatures, _ := lru.NewARC(inmemorySignatures)
+	validatorSets, _ := lru.NewARC(inmemoryValidatorSets)
 
 	return &Dpos{
-		config:     config,
-		db:         db,
-		signatures: signatures,
+		config:        config,
+		db:            db,
+		signatures:    signatures,
+		validatorSets: validatorSets,
+		clock:         systemClock{},
 	}
 }
 
+//SetClock替换引擎使用的时钟，只用于模拟测试
+func (d *Dpos) SetClock(clock Clock) {
+	d.mu.Lock()
+	d.clock = clock
+	d.mu.Unlock()
+}
+
+//Clock返回引擎当前使用的时钟
+func (d *Dpos) Clock() Clock {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.clock
+}
+
 func (d *Dpos) Author(header *types.Header) (common.Address, error) {
 	return header.Validator, nil
 }
 
 //验证批量是否符合共识算法规则
 func (d *Dpos) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool, blockInterval uint64) error {
-	return d.verifyHeader(chain, header, nil, blockInterval)
+	if err := d.verifyHeader(chain, header, nil, blockInterval); err != nil {
+		return err
+	}
+	if seal {
+		return d.verifySeal(chain, header, blockInterval, nil)
+	}
+	return nil
 }
 
 func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header,blockInterval uint64 ) error {
@@ -176,7 +222,7 @@ func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 	}
 	number := header.Number.Uint64()
 //不需要验证功能块
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+	if header.Time.Cmp(big.NewInt(d.Clock().Now().Unix())) > 0 {
 		return consensus.ErrFutureBlock
 	}
 //检查额外数据是否包含虚荣和签名
@@ -218,7 +264,21 @@ func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 	if parent.Time.Uint64()+blockInterval> header.Time.Uint64() {
 		return ErrInvalidTimestamp
 	}
-	return nil
+//分叉之后，分叉块和新周期的第一个区块必须携带选举出的验证人列表；
+//分叉之前的区块不携带列表，验证人集合从DposContext中取得
+	validators, err := ExtraValidators(header)
+	if err != nil {
+		return err
+	}
+	if !d.config.IsValidatorList(header.Number) {
+		if len(validators) > 0 {
+			return errUnexpectedExtraValidators
+		}
+	} else if len(validators) == 0 && d.needsExtraValidators(parent, header) {
+		return errMissingExtraValidators
+	}
+//费用市场分叉之后验证基础费用
+	return misc.VerifyBaseFee(chain.Config(), parent, header)
 }
 
 //批量验证区块头是否符合公共计算法规
@@ -231,6 +291,9 @@ func (d *Dpos) VerifyHeaders(chain consensus.ChainReader, headers []*types.Heade
 		for i, header := range headers {
 //header.extra=make（[]字节，ExtraVanity+ExtraSeal）
 			err := d.verifyHeader(chain, header, headers[:i],blockInterval)
+			if err == nil && seals[i] {
+				err = d.verifySeal(chain, header, blockInterval, headers[:i])
+			}
 			select {
 			case <-abort:
 				return
@@ -253,31 +316,22 @@ func (d *Dpos) VerifyUncles(chain consensus.ChainReader, block *types.Block) err
 //验证seal是否执行consension.engine，检查签名是否包含
 //头部满足共识协议要求。
 func (d *Dpos) VerifySeal(chain consensus.ChainReader, currentheader, genesisheader *types.Header) error {
-	return d.verifySeal(chain, currentheader, genesisheader,nil)
+	return d.verifySeal(chain, currentheader, genesisheader.BlockInterval, nil)
 }
 
-func (d *Dpos) verifySeal(chain consensus.ChainReader, currentheader, genesisheader *types.Header, parents []*types.Header) error {
+//verifySeal只依赖区块头链：出块人由父块生效后的验证人集合和时间槽决定，
+//验证人集合取自最近一个携带验证人列表的祖先区块头
+func (d *Dpos) verifySeal(chain consensus.ChainReader, currentheader *types.Header, blockInterval uint64, parents []*types.Header) error {
 //验证不支持Genesis块
 	number := currentheader.Number.Uint64()
 	if number == 0 {
 		return errUnknownBlock
 	}
-	var parent *types.Header
-	if len(parents) > 0 {
-		parent = parents[len(parents)-1]
-	} else {
-		parent = chain.GetHeader(currentheader.ParentHash, number-1)
-	}
-
-	trieDB := trie.NewDatabase(d.db)
-dposContext, err := types.NewDposContextFromProto(trieDB, parent.DposContext) //零位
-
+	validators, err := d.validatorsAfter(chain, currentheader.ParentHash, number-1, parents)
 	if err != nil {
 		return err
 	}
-	epochContext := &EpochContext{DposContext: dposContext}
-	blockInterVal := genesisheader.BlockInterval
-	validator, err := epochContext.lookupValidator(currentheader.Time.Int64(),blockInterVal)
+	validator, err := ScheduledValidator(validators, currentheader.Time.Int64(), blockInterval)
 	if err != nil {
 		return err
 	}
@@ -303,6 +357,9 @@ func (d *Dpos) verifyBlockSigner(validator common.Address, header *types.Header)
 }
 
 func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
+	d.confirmedLock.Lock()
+	defer d.confirmedLock.Unlock()
+
 	if d.confirmedBlockHeader == nil {
 		header, err := d.loadConfirmedBlockHeader(chain)
 		if err != nil {
@@ -353,6 +410,17 @@ func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
 	return nil
 }
 
+//ConfirmedNumber返回最新不可逆区块的高度，还没有确认过区块时返回false
+func (d *Dpos) ConfirmedNumber() (uint64, bool) {
+	d.confirmedLock.Lock()
+	defer d.confirmedLock.Unlock()
+
+	if d.confirmedBlockHeader == nil {
+		return 0, false
+	}
+	return d.confirmedBlockHeader.Number.Uint64(), true
+}
+
 func (s *Dpos) loadConfirmedBlockHeader(chain consensus.ChainReader) (*types.Header, error) {
 	key, err := s.db.Get(confirmedBlockHead)
 	if err != nil {
@@ -384,6 +452,9 @@ func (d *Dpos) Prepare(chain consensus.ChainReader, header *types.Header) error
 	}
 	header.Difficulty = d.CalcDifficulty(chain, header.Time.Uint64(), parent)
 	header.Validator = d.signer
+	if chain.Config().IsFeeMarket(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(chain.Config(), parent)
+	}
 	return nil
 }
 
@@ -411,6 +482,10 @@ func (d *Dpos) Finalize(chain consensus.ChainReader, header *types.Header, state
 		DposContext: dposContext,
 		TimeStamp:   header.Time.Int64(),
 	}
+	prevValidators, err := dposContext.GetValidators()
+	if err != nil {
+		return nil, fmt.Errorf("got error when get validators, err: %s", err)
+	}
 	if timeOfFirstBlock == 0 {
 		if firstBlockHeader := chain.GetHeaderByNumber(1); firstBlockHeader != nil {
 			timeOfFirstBlock = firstBlockHeader.Time.Int64()
@@ -420,10 +495,27 @@ func (d *Dpos) Finalize(chain consensus.ChainReader, header *types.Header, state
 	fmt.Println("**************get genesis header********\n")
 	genesis := chain.GetHeaderByNumber(0)
 
-	err := epochContext.tryElect(genesis, parent)
+	err = epochContext.tryElect(genesis, parent)
 	if err != nil {
 		return nil, fmt.Errorf("got error when elect next epoch, err: %s", err)
 	}
+	if err := d.fillVacancies(epochContext, parent, header); err != nil {
+		return nil, fmt.Errorf("got error when replace vacant validator, err: %s", err)
+	}
+//分叉之后验证人集合发生变化时，把新的集合写入区块头，供只有区块头的节点验证签名
+	if d.config.IsValidatorList(header.Number) {
+		validators, err := dposContext.GetValidators()
+		if err != nil {
+			return nil, fmt.Errorf("got error when get validators, err: %s", err)
+		}
+		if d.needsExtraValidators(parent, header) || !sameValidators(prevValidators, validators) {
+			if err := sealExtraValidators(header, validators); err != nil {
+				return nil, err
+			}
+		} else if err := sealExtraValidators(header, nil); err != nil {
+			return nil, err
+		}
+	}
 
 //更新薄荷计数trie
 	updateMintCnt(parent.Time.Int64(), header.Time.Int64(), header.Validator, dposContext)
@@ -465,6 +557,88 @@ func (d *Dpos) CheckValidator(lastBlock *types.Block, now int64,blockInterval ui
 	return nil
 }
 
+//ScheduledValidators返回在lastBlock之后从now开始的n个出块时间槽中
+//依次出块的验证人（去重）。只使用lastBlock所在周期的验证人集合。
+func (d *Dpos) ScheduledValidators(lastBlock *types.Block, now int64, blockInterval uint64, n int) ([]common.Address, error) {
+	dposContext, err := types.NewDposContextFromProto(trie.NewDatabase(d.db), lastBlock.Header().DposContext)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := dposContext.GetValidators()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		scheduled []common.Address
+		seen      = make(map[common.Address]bool)
+	)
+	slot := NextSlot(now, blockInterval)
+	for i := 0; i < n; i++ {
+		validator, err := ScheduledValidator(validators, slot+int64(i)*int64(blockInterval), blockInterval)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[validator] {
+			seen[validator] = true
+			scheduled = append(scheduled, validator)
+		}
+	}
+	return scheduled, nil
+}
+
+//IsSlotValidator返回本节点的验证人是否是lastBlock之后slot时间槽的出块人。
+//和CheckValidator一样只使用lastBlock所在周期的验证人集合。
+func (d *Dpos) IsSlotValidator(lastBlock *types.Block, slot int64, blockInterval uint64) (bool, error) {
+	d.mu.RLock()
+	signer := d.signer
+	d.mu.RUnlock()
+
+	if signer == (common.Address{}) {
+		return false, nil
+	}
+	dposContext, err := types.NewDposContextFromProto(trie.NewDatabase(d.db), lastBlock.Header().DposContext)
+	if err != nil {
+		return false, err
+	}
+	epochContext := &EpochContext{DposContext: dposContext}
+	validator, err := epochContext.lookupValidator(slot, blockInterval)
+	if err != nil {
+		return false, err
+	}
+	return validator == signer, nil
+}
+
+//validatorAnnounceHash是验证人向对等节点声明身份时签名的哈希
+func validatorAnnounceHash(id []byte) []byte {
+	return crypto.Keccak256([]byte("dpos validator"), id)
+}
+
+//SignValidatorAnnounce用本地验证人的密钥对对等节点ID签名，
+//使用外部签名者（clef）出块时无法签名，返回错误。
+func (d *Dpos) SignValidatorAnnounce(id []byte) (common.Address, []byte, error) {
+	d.mu.RLock()
+	signer, signFn := d.signer, d.signFn
+	d.mu.RUnlock()
+
+	if signFn == nil {
+		return common.Address{}, nil, errors.New("validator signer not available")
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, validatorAnnounceHash(id))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return signer, sig, nil
+}
+
+//RecoverValidatorAnnounce从对节点ID的签名中恢复验证人地址
+func RecoverValidatorAnnounce(id []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(validatorAnnounceHash(id), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
 //Seal使用本地矿工的
 //密封顶部。
 //验证模块内容是否符合DPOSS计算法规（验证新模块是否应由该验证人员提出模块）
@@ -475,23 +649,39 @@ func (d *Dpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan
 	if number == 0 {
 		return nil, errUnknownBlock
 	}
-	now := time.Now().Unix()
+	clock := d.Clock()
+	now := clock.Now().Unix()
 	delay := NextSlot(now,chain.GetHeaderByNumber(0).BlockInterval) - now
 	if delay > 0 {
 		select {
 		case <-stop:
 			return nil, nil
-		case <-time.After(time.Duration(delay) * time.Second):
+		case <-clock.After(time.Duration(delay) * time.Second):
 		}
 	}
-	block.Header().Time.SetInt64(time.Now().Unix())
+	block.Header().Time.SetInt64(clock.Now().Unix())
 
 //时间到了，在街区签名
 //对新块进行签名
-	sighash, err := d.signFn(accounts.Account{Address: d.signer}, sigHash(header).Bytes())
+	d.mu.RLock()
+	signer, signFn, sealFn := d.signer, d.signFn, d.sealFn
+	d.mu.RUnlock()
+
+	var (
+		sighash []byte
+		err     error
+	)
+	if sealFn != nil {
+		sighash, err = sealFn(accounts.Account{Address: signer}, header)
+	} else {
+		sighash, err = signFn(accounts.Account{Address: signer}, sigHash(header).Bytes())
+	}
 	if err != nil {
 		return nil, err
 	}
+	if len(sighash) != extraSeal {
+		return nil, errMissingSignature
+	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
 	return block.WithSeal(header), nil
 }
@@ -513,6 +703,17 @@ func (d *Dpos) Authorize(signer common.Address, signFn SignerFn) {
 	d.mu.Lock()
 	d.signer = signer
 	d.signFn = signFn
+	d.sealFn = nil
+	d.mu.Unlock()
+}
+
+//AuthorizeSealer使用外部签名者（例如clef或硬件钱包）为出块签名，
+//验证人私钥不需要在本节点上解锁
+func (d *Dpos) AuthorizeSealer(signer common.Address, sealFn SealerFn) {
+	d.mu.Lock()
+	d.signer = signer
+	d.sealFn = sealFn
+	d.signFn = nil
 	d.mu.Unlock()
 }
 
@@ -551,6 +752,21 @@ func NextSlot(now int64, blockInterval uint64) int64 {
 	return int64((now+int64(blockInterval)-1)/int64(blockInterval)) * int64(blockInterval)
 }
 
+//fillVacancies在补位分叉之后，周期内有验证人注销或被踢出时由备选候选人补位。空缺和
+//备选候选人都只会随候选人列表变化而出现，周期切换时刚完成选举，所以只在周期内候选人
+//列表变化的区块检查。分叉之前空缺保留到下一个周期选举，与旧节点的验证人集合一致。
+func (d *Dpos) fillVacancies(epochContext *EpochContext, parent, header *types.Header) error {
+	if !d.config.IsVacancy(header.Number) || isEpochTransition(parent, header) || !candidatesChanged(parent, epochContext.DposContext) {
+		return nil
+	}
+	return epochContext.replaceVacantValidators()
+}
+
+//candidatesChanged返回区块中的交易是否改变了父区块之后的候选人列表
+func candidatesChanged(parent *types.Header, dposContext *types.DposContext) bool {
+	return parent.DposContext == nil || parent.DposContext.CandidateHash != dposContext.CandidateTrie().Hash()
+}
+
 //更新Newblock矿工的mintcntrie计数
 //更新周期内验证人员出块数目的
 func updateMintCnt(parentBlockTime, currentBlockTime int64, validator common.Address, dposContext *types.DposContext) {
@@ -12,13 +12,17 @@
 package dpos
 
 import (
+	"math/big"
 	"testing"
 
 	"encoding/binary"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/stretchr/testify/assert"
 )
@@ -129,3 +133,80 @@ func TestUpdateMintCnt(t *testing.T) {
 	assert.Equal(t, int64(1), afterUpdateCnt)
 }
 
+
+//测试验证人对节点ID的声明签名只能在同一节点ID上恢复出验证人地址
+func TestValidatorAnnounce(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+
+	d := &Dpos{}
+	if _, _, err := d.SignValidatorAnnounce([]byte{1}); err == nil {
+		t.Fatal("expected error without signer")
+	}
+	d.Authorize(validator, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	id := crypto.Keccak256([]byte("node"))
+	address, sig, err := d.SignValidatorAnnounce(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, validator, address)
+
+	recovered, err := RecoverValidatorAnnounce(id, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, validator, recovered)
+
+	other, err := RecoverValidatorAnnounce(crypto.Keccak256([]byte("other")), sig)
+	if err == nil {
+		assert.NotEqual(t, validator, other)
+	}
+}
+
+//测试只有本节点的验证人是时间槽的出块人时才返回true
+func TestIsSlotValidator(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	assert.Nil(t, err)
+	validators := []common.Address{{0x01}, {0x02}}
+	assert.Nil(t, dposContext.SetValidators(validators))
+	proto, err := dposContext.Commit()
+	assert.Nil(t, err)
+	block := types.NewBlockWithHeader(&types.Header{DposContext: proto})
+
+	d := New(&params.DposConfig{}, db)
+	scheduled, err := d.IsSlotValidator(block, blockInterval, uint64(blockInterval))
+	assert.Nil(t, err)
+	assert.False(t, scheduled)
+
+	d.Authorize(validators[1], nil)
+	scheduled, err = d.IsSlotValidator(block, blockInterval, uint64(blockInterval))
+	assert.Nil(t, err)
+	assert.True(t, scheduled)
+
+	scheduled, err = d.IsSlotValidator(block, 2*blockInterval, uint64(blockInterval))
+	assert.Nil(t, err)
+	assert.False(t, scheduled)
+
+	_, err = d.IsSlotValidator(block, blockInterval+1, uint64(blockInterval))
+	assert.Equal(t, ErrInvalidMintBlockTime, err)
+}
+
+//测试费用市场分叉之后签名哈希覆盖基础费用
+func TestSigHashBaseFee(t *testing.T) {
+	header := &types.Header{
+		Number:      big.NewInt(1),
+		Extra:       make([]byte, extraSeal),
+		DposContext: &types.DposContextProto{},
+	}
+	legacy := sigHash(header)
+
+	header.BaseFee = big.NewInt(params.InitialBaseFee)
+	withFee := sigHash(header)
+	assert.NotEqual(t, legacy, withFee)
+
+	header.BaseFee = big.NewInt(params.InitialBaseFee + 1)
+	assert.NotEqual(t, withFee, sigHash(header))
+}
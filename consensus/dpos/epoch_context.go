@@ -157,19 +157,107 @@ func (ec *EpochContext) kickoutValidator(epoch int64,genesis *types.Header) erro
 	return nil
 }
 
+//补位验证人：周期内有验证人注销候选人资格（或被踢出候选人列表）时，
+//其出块位置会一直空到下一个周期选举，这里在同一个区块内
+//由未当选候选人中得票最高者按原位置接替，结果写入epoch trie，
+//所以每个节点在同一高度得到一致的验证人列表。接替者继承原验证人本周期的出块数，
+//避免下个周期因出块数不足又被踢出。
+func (ec *EpochContext) replaceVacantValidators() error {
+	validators, err := ec.DposContext.GetValidators()
+	if err != nil {
+		return err
+	}
+	vacancies := []int{}
+	activeValidators := make(map[common.Address]bool)
+	for i, validator := range validators {
+		candidateInTrie, err := ec.DposContext.CandidateTrie().TryGet(validator.Bytes())
+		if err != nil {
+			return err
+		}
+		if candidateInTrie == nil {
+			vacancies = append(vacancies, i)
+		} else {
+			activeValidators[validator] = true
+		}
+	}
+//没有空缺位置
+	if len(vacancies) == 0 {
+		return nil
+	}
+	votes, err := ec.countVotes()
+	if err != nil {
+//候选人列表为空，没有可以补位的候选人
+		log.Warn("No candidate to replace vacant validator", "vacancies", len(vacancies), "err", err)
+		return nil
+	}
+	standbys := sortableAddresses{}
+	for candidate, cnt := range votes {
+		if !activeValidators[candidate] {
+			standbys = append(standbys, &sortableAddress{candidate, cnt})
+		}
+	}
+	sort.Sort(standbys)
+
+	replaced := 0
+	for i, slot := range vacancies {
+		if i >= len(standbys) {
+			log.Warn("Not enough standby candidates", "vacancies", len(vacancies), "standbys", len(standbys))
+			break
+		}
+		log.Info("Replace vacant validator", "slot", slot, "old", validators[slot].String(), "new", standbys[i].address.String(), "votes", standbys[i].weight.String())
+		if err := ec.inheritMintCnt(validators[slot], standbys[i].address); err != nil {
+			return err
+		}
+		validators[slot] = standbys[i].address
+		replaced++
+	}
+	if replaced == 0 {
+		return nil
+	}
+	return ec.DposContext.SetValidators(validators)
+}
+
+//inheritMintCnt把old本周期的出块数累加到接替者replacement上
+func (ec *EpochContext) inheritMintCnt(old, replacement common.Address) error {
+	epochBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(epochBytes, uint64(ec.TimeStamp/epochInterval))
+
+	mintCntTrie := ec.DposContext.MintCntTrie()
+	oldBytes, err := mintCntTrie.TryGet(append(epochBytes, old.Bytes()...))
+	if err != nil || oldBytes == nil {
+		return err
+	}
+	cnt := binary.BigEndian.Uint64(oldBytes)
+	key := append(epochBytes, replacement.Bytes()...)
+	newBytes, err := mintCntTrie.TryGet(key)
+	if err != nil {
+		return err
+	}
+	if newBytes != nil {
+		cnt += binary.BigEndian.Uint64(newBytes)
+	}
+	cntBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(cntBytes, cnt)
+	return mintCntTrie.TryUpdate(key, cntBytes)
+}
+
 //实时检查出块者是否是本节点
 func (ec *EpochContext) lookupValidator(now int64, blockInterval uint64) (validator common.Address, err error) {
-	validator = common.Address{}
+	validators, err := ec.DposContext.GetValidators()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return ScheduledValidator(validators, now, blockInterval)
+}
+
+//ScheduledValidator根据时间槽从给定的验证人列表中选出当前应出块的验证人
+func ScheduledValidator(validators []common.Address, now int64, blockInterval uint64) (common.Address, error) {
 	offset := now % epochInterval
 if offset%int64(blockInterval) != 0 {    //判断当前时间是否在出块周期内
 		return common.Address{}, ErrInvalidMintBlockTime
 	}
 	offset /= int64(blockInterval)
 
-	validators, err := ec.DposContext.GetValidators()
-	if err != nil {
-		return common.Address{}, err
-	}
 	validatorSize := len(validators)
 	if validatorSize == 0 {
 		return common.Address{}, errors.New("failed to lookup validator")
@@ -12,6 +12,7 @@
 package dpos
 
 import (
+	"encoding/binary"
 	"math/big"
 	"strconv"
 	"strings"
@@ -21,6 +22,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 
 	"github.com/stretchr/testify/assert"
@@ -380,3 +382,103 @@ func TestEpochContextTryElect(t *testing.T) {
 	assert.Equal(t, oldHash, dposContext.EpochTrie().Hash())
 }
 
+
+func TestEpochContextReplaceVacantValidators(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	stateDB, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	trieDB := trie.NewDatabase(db)
+	dposContext, err := types.NewDposContext(trieDB)
+	assert.Nil(t, err)
+	epochContext := &EpochContext{
+		TimeStamp:   epochInterval + blockInterval,
+		DposContext: dposContext,
+		statedb:     stateDB,
+	}
+	validators := []common.Address{}
+	for i := 0; i < maxValidatorSize; i++ {
+		validator := common.StringToAddress("addr" + strconv.Itoa(i))
+		validators = append(validators, validator)
+		assert.Nil(t, dposContext.BecomeCandidate(validator))
+		assert.Nil(t, dposContext.Delegate(validator, validator))
+		stateDB.SetBalance(validator, big.NewInt(10))
+	}
+	assert.Nil(t, dposContext.SetValidators(validators))
+
+//没有空缺位置，验证人列表不变
+	oldHash := dposContext.EpochTrie().Hash()
+	assert.Nil(t, epochContext.replaceVacantValidators())
+	assert.Equal(t, oldHash, dposContext.EpochTrie().Hash())
+
+//有空缺但没有备选候选人，验证人列表不变
+	assert.Nil(t, dposContext.KickoutCandidate(validators[1]))
+	assert.Nil(t, epochContext.replaceVacantValidators())
+	assert.Equal(t, oldHash, dposContext.EpochTrie().Hash())
+
+//得票最高的备选候选人接替空缺位置
+	for i, balance := range []int64{3, 7} {
+		standby := common.StringToAddress("standby" + strconv.Itoa(i))
+		assert.Nil(t, dposContext.BecomeCandidate(standby))
+		assert.Nil(t, dposContext.Delegate(standby, standby))
+		stateDB.SetBalance(standby, big.NewInt(balance))
+	}
+//被替换的验证人本周期已出块3次，接替者继承出块数
+	epochBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(epochBytes, uint64(epochContext.TimeStamp/epochInterval))
+	cntBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(cntBytes, 3)
+	assert.Nil(t, dposContext.MintCntTrie().TryUpdate(append(epochBytes, validators[1].Bytes()...), cntBytes))
+
+	assert.Nil(t, epochContext.replaceVacantValidators())
+	result, err := dposContext.GetValidators()
+	assert.Nil(t, err)
+	assert.Equal(t, maxValidatorSize, len(result))
+	assert.Equal(t, validators[0], result[0])
+	assert.Equal(t, common.StringToAddress("standby1"), result[1])
+	assert.Equal(t, validators[2], result[2])
+
+	inherited := dposContext.MintCntTrie().Get(append(epochBytes, common.StringToAddress("standby1").Bytes()...))
+	assert.Equal(t, uint64(3), binary.BigEndian.Uint64(inherited))
+}
+
+//测试补位分叉之前周期内的空缺保留到下一个周期选举，验证人列表与旧规则一致，分叉之后才由备选候选人补位
+func TestFillVacanciesFork(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	stateDB, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	assert.Nil(t, err)
+	epochContext := &EpochContext{
+		TimeStamp:   epochInterval + blockInterval,
+		DposContext: dposContext,
+		statedb:     stateDB,
+	}
+	validators := []common.Address{}
+	for i := 0; i < maxValidatorSize; i++ {
+		validator := common.StringToAddress("addr" + strconv.Itoa(i))
+		validators = append(validators, validator)
+		assert.Nil(t, dposContext.BecomeCandidate(validator))
+		assert.Nil(t, dposContext.Delegate(validator, validator))
+		stateDB.SetBalance(validator, big.NewInt(10))
+	}
+	assert.Nil(t, dposContext.SetValidators(validators))
+	standby := common.StringToAddress("standby")
+	assert.Nil(t, dposContext.BecomeCandidate(standby))
+	assert.Nil(t, dposContext.Delegate(standby, standby))
+	stateDB.SetBalance(standby, big.NewInt(5))
+
+//父区块之后有验证人被踢出，候选人列表发生变化
+	parent := &types.Header{Number: big.NewInt(1), Time: big.NewInt(epochInterval), DposContext: dposContext.ToProto()}
+	assert.Nil(t, dposContext.KickoutCandidate(validators[1]))
+
+	d := New(&params.DposConfig{VacancyBlock: big.NewInt(3)}, nil)
+	header := &types.Header{Number: big.NewInt(2), Time: big.NewInt(epochInterval + blockInterval)}
+	assert.Nil(t, d.fillVacancies(epochContext, parent, header))
+	result, err := dposContext.GetValidators()
+	assert.Nil(t, err)
+	assert.Equal(t, validators, result)
+
+	header.Number = big.NewInt(3)
+	assert.Nil(t, d.fillVacancies(epochContext, parent, header))
+	result, err = dposContext.GetValidators()
+	assert.Nil(t, err)
+	assert.Equal(t, standby, result[1])
+}
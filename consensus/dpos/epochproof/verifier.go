@@ -0,0 +1,184 @@
+//epochproof包验证dpos_getEpochProof返回的验证人集合变更证明。
+//跨链桥只需要持有一个可信的初始验证人集合，依次验证每一次变更，
+//就可以跟踪DPoS链的验证人集合，而不必同步状态或完整的区块头链。
+package epochproof
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+//如果证明中的验证人集合与区块头或默克尔证明不一致，返回ErrValidatorsMismatch
+	ErrValidatorsMismatch = errors.New("validator set does not match proof")
+//如果证明的区块头不是按时间槽排班的验证人签名，返回ErrUnexpectedSigner
+	ErrUnexpectedSigner = errors.New("header not signed by scheduled validator")
+//如果确认区块头不是连续的区块链，返回ErrBrokenChain
+	ErrBrokenChain = errors.New("confirmation headers do not form a chain")
+//如果新验证人集合中签名的数量不足2/3+1，返回ErrNotConfirmed
+	ErrNotConfirmed = errors.New("not enough validators confirmed the transition")
+//如果证明的区块比已验证的区块旧，返回ErrStaleProof
+	ErrStaleProof = errors.New("proof is not newer than the verified head")
+)
+
+//VerifyProof用可信的验证人集合验证一次验证人集合变更，
+//返回变更所在的区块头和新的验证人集合。
+//blockInterval是对方链创世配置中的出块间隔
+func VerifyProof(trusted []common.Address, blockInterval uint64, proof *dpos.EpochProof) (*types.Header, []common.Address, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(proof.Header, header); err != nil {
+		return nil, nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if header.DposContext == nil {
+		return nil, nil, errors.New("header without dpos context")
+	}
+//新的验证人集合必须与区块头extra-data中的列表以及epoch trie中的值一致
+	validators, err := dpos.ExtraValidators(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(validators) == 0 || !equalAddresses(validators, proof.Validators) {
+		return nil, nil, ErrValidatorsMismatch
+	}
+	proofDb := ethdb.NewMemDatabase()
+	for _, node := range proof.Proof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	value, _, err := trie.VerifyProof(header.DposContext.EpochHash, types.ValidatorsKey, proofDb)
+	if err != nil {
+		return nil, nil, err
+	}
+	var proven []common.Address
+	if err := rlp.DecodeBytes(value, &proven); err != nil {
+		return nil, nil, fmt.Errorf("invalid validators in proof: %v", err)
+	}
+	if !equalAddresses(validators, proven) {
+		return nil, nil, ErrValidatorsMismatch
+	}
+
+//变更区块由原验证人集合排班，其后的区块由新的集合排班。确认只统计
+//实际出块的新集合：原集合被大部分替换时，原集合的签名永远凑不够
+	if _, err := verifySigner(header, trusted, blockInterval); err != nil {
+		return nil, nil, err
+	}
+	sealing := make(map[common.Address]bool)
+	for _, validator := range validators {
+		sealing[validator] = true
+	}
+	signed := make(map[common.Address]bool)
+	schedule, parent := validators, header
+	for i, enc := range proof.Confirmations {
+		cur := new(types.Header)
+		if err := rlp.DecodeBytes(enc, cur); err != nil {
+			return nil, nil, fmt.Errorf("invalid confirmation %d: %v", i, err)
+		}
+		if cur.ParentHash != parent.Hash() || cur.Number.Uint64() != parent.Number.Uint64()+1 || cur.Time.Cmp(parent.Time) <= 0 {
+			return nil, nil, ErrBrokenChain
+		}
+		signer, err := verifySigner(cur, schedule, blockInterval)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sealing[signer] {
+			signed[signer] = true
+		}
+//确认区块中如果又发生了变更，之后的区块按变更后的集合排班
+		list, err := dpos.ExtraValidators(cur)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(list) > 0 {
+			schedule = list
+		}
+		parent = cur
+	}
+	if len(signed) < len(validators)*2/3+1 {
+		return nil, nil, ErrNotConfirmed
+	}
+	return header, validators, nil
+}
+
+//verifySigner检查区块头的签名者是该时间槽排班的验证人
+func verifySigner(header *types.Header, validators []common.Address, blockInterval uint64) (common.Address, error) {
+	if len(header.Extra) < 65 {
+		return common.Address{}, errors.New("extra-data 65 byte suffix signature missing")
+	}
+	signature := header.Extra[len(header.Extra)-65:]
+	pubkey, err := crypto.Ecrecover(dpos.SealHash(header).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	scheduled, err := dpos.ScheduledValidator(validators, header.Time.Int64(), blockInterval)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer != scheduled || signer != header.Validator {
+		return common.Address{}, ErrUnexpectedSigner
+	}
+	return signer, nil
+}
+
+func equalAddresses(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Verifier持有当前可信的验证人集合，并随着验证通过的证明向前推进
+type Verifier struct {
+	validators    []common.Address
+	blockInterval uint64
+	number        uint64 //最近一次验证通过的变更区块高度
+
+	lock sync.RWMutex
+}
+
+//NewVerifier用可信的验证人集合（例如创世配置中的验证人）创建验证器
+func NewVerifier(validators []common.Address, blockInterval uint64) *Verifier {
+	return &Verifier{
+		validators:    append([]common.Address(nil), validators...),
+		blockInterval: blockInterval,
+	}
+}
+
+//Validators返回当前可信的验证人集合
+func (v *Verifier) Validators() []common.Address {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	return append([]common.Address(nil), v.validators...)
+}
+
+//Verify验证下一次验证人集合变更，通过后用新的集合替换可信集合
+func (v *Verifier) Verify(proof *dpos.EpochProof) (*types.Header, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	header, validators, err := VerifyProof(v.validators, v.blockInterval, proof)
+	if err != nil {
+		return nil, err
+	}
+	if header.Number.Uint64() <= v.number {
+		return nil, ErrStaleProof
+	}
+	v.validators, v.number = validators, header.Number.Uint64()
+	return header, nil
+}
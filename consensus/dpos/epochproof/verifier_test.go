@@ -0,0 +1,150 @@
+package epochproof
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBlockInterval = 10
+
+func signHeader(t *testing.T, header *types.Header, key *ecdsa.PrivateKey) {
+	header.Validator = crypto.PubkeyToAddress(key.PublicKey)
+	sig, err := crypto.Sign(dpos.SealHash(header).Bytes(), key)
+	assert.Nil(t, err)
+	copy(header.Extra[len(header.Extra)-65:], sig)
+}
+
+func encodeHeader(t *testing.T, header *types.Header) hexutil.Bytes {
+	enc, err := rlp.EncodeToBytes(header)
+	assert.Nil(t, err)
+	return enc
+}
+
+func newKeys(n int, keys map[common.Address]*ecdsa.PrivateKey) []common.Address {
+	var addrs []common.Address
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		keys[addr] = key
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+//newProof构造validators替换trusted的证明：变更区块在时间槽0由trusted[0]出块，
+//其后confirmations个区块按validators排班出块
+func newProof(t *testing.T, keys map[common.Address]*ecdsa.PrivateKey, trusted, validators []common.Address, confirmations int) (*types.Header, *dpos.EpochProof) {
+	epochTrie, err := types.NewEpochTrie(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	assert.Nil(t, err)
+	validatorsRLP, _ := rlp.EncodeToBytes(validators)
+	epochTrie.Update(types.ValidatorsKey, validatorsRLP)
+	proofDb := ethdb.NewMemDatabase()
+	assert.Nil(t, epochTrie.Prove(types.ValidatorsKey, 0, proofDb))
+
+	extra := make([]byte, 32, 32+len(validators)*common.AddressLength+65)
+	for _, validator := range validators {
+		extra = append(extra, validator.Bytes()...)
+	}
+	boundary := &types.Header{
+		Number:      big.NewInt(10),
+		Time:        big.NewInt(60),
+		Difficulty:  big.NewInt(1),
+		DposContext: &types.DposContextProto{EpochHash: epochTrie.Hash()},
+		Extra:       append(extra, make([]byte, 65)...),
+	}
+	signHeader(t, boundary, keys[trusted[0]])
+
+	proof := &dpos.EpochProof{
+		Header:     encodeHeader(t, boundary),
+		Validators: validators,
+	}
+	for _, key := range proofDb.Keys() {
+		node, _ := proofDb.Get(key)
+		proof.Proof = append(proof.Proof, node)
+	}
+	parent := boundary
+	for i := 1; i <= confirmations; i++ {
+		header := &types.Header{
+			ParentHash:  parent.Hash(),
+			Number:      new(big.Int).Add(parent.Number, common.Big1),
+			Time:        new(big.Int).Add(parent.Time, big.NewInt(testBlockInterval)),
+			Difficulty:  big.NewInt(1),
+			DposContext: &types.DposContextProto{EpochHash: epochTrie.Hash()},
+			Extra:       make([]byte, 32+65),
+		}
+		signHeader(t, header, keys[validators[i%len(validators)]])
+		proof.Confirmations = append(proof.Confirmations, encodeHeader(t, header))
+		parent = header
+	}
+	return boundary, proof
+}
+
+func TestVerifyProof(t *testing.T) {
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	trusted := newKeys(3, keys)
+//新周期的验证人集合是原集合重新洗牌后的结果
+	validators := []common.Address{trusted[2], trusted[0], trusted[1]}
+	boundary, proof := newProof(t, keys, trusted, validators, 3)
+
+//只有两个验证人确认时还不能确认
+	partial := *proof
+	partial.Confirmations = proof.Confirmations[:2]
+	_, _, err := VerifyProof(trusted, testBlockInterval, &partial)
+	assert.Equal(t, ErrNotConfirmed, err)
+
+//篡改验证人集合
+	forged := *proof
+	forged.Validators = []common.Address{trusted[0], trusted[1], trusted[2]}
+	_, _, err = VerifyProof(trusted, testBlockInterval, &forged)
+	assert.Equal(t, ErrValidatorsMismatch, err)
+
+//不可信的验证人集合无法验证变更区块的签名
+	_, _, err = VerifyProof(validators, testBlockInterval, proof)
+	assert.Equal(t, ErrUnexpectedSigner, err)
+
+	verifier := NewVerifier(trusted, testBlockInterval)
+	header, err := verifier.Verify(proof)
+	assert.Nil(t, err)
+	assert.Equal(t, boundary.Hash(), header.Hash())
+	assert.Equal(t, validators, verifier.Validators())
+
+	_, err = verifier.Verify(proof)
+	assert.NotNil(t, err)
+}
+
+//新周期替换了大部分验证人：其后的区块几乎都由新验证人出块，
+//确认按新集合统计才能完成
+func TestVerifyProofReplacedSet(t *testing.T) {
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	trusted := newKeys(4, keys)
+	validators := append([]common.Address{trusted[0]}, newKeys(3, keys)...)
+	boundary, proof := newProof(t, keys, trusted, validators, 4)
+
+//新集合四个验证人中只有三个确认，恰好达到2/3+1
+	partial := *proof
+	partial.Confirmations = proof.Confirmations[:2]
+	_, _, err := VerifyProof(trusted, testBlockInterval, &partial)
+	assert.Equal(t, ErrNotConfirmed, err)
+	partial.Confirmations = proof.Confirmations[:3]
+	header, got, err := VerifyProof(trusted, testBlockInterval, &partial)
+	assert.Nil(t, err)
+	assert.Equal(t, boundary.Hash(), header.Hash())
+	assert.Equal(t, validators, got)
+
+//再次替换时以新集合为可信集合
+	verifier := NewVerifier(trusted, testBlockInterval)
+	_, err = verifier.Verify(proof)
+	assert.Nil(t, err)
+	assert.Equal(t, validators, verifier.Validators())
+}
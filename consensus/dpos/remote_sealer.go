@@ -0,0 +1,50 @@
+package dpos
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//外部签名者一次签名请求的超时时间，需要小于出块间隔
+const remoteSealTimeout = 5 * time.Second
+
+//NewRemoteSealer返回一个通过外部签名者（clef的sealer接口）
+//为区块签名的SealerFn。签名结果在返回之前会重新校验，
+//保证签名者确实是当前验证人
+func NewRemoteSealer(client *rpc.Client) SealerFn {
+	return func(account accounts.Account, header *types.Header) ([]byte, error) {
+		blob, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), remoteSealTimeout)
+		defer cancel()
+
+		var signature hexutil.Bytes
+		if err := client.CallContext(ctx, &signature, "sealer_signBlock", common.NewMixedcaseAddress(account.Address), hexutil.Bytes(blob)); err != nil {
+			return nil, err
+		}
+		if len(signature) != extraSeal {
+			return nil, errMissingSignature
+		}
+		pubkey, err := crypto.Ecrecover(sigHash(header).Bytes(), signature)
+		if err != nil {
+			return nil, err
+		}
+		var signer common.Address
+		copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+		if !bytes.Equal(signer.Bytes(), account.Address.Bytes()) {
+			return nil, ErrMismatchSignerAndValidator
+		}
+		return signature, nil
+	}
+}
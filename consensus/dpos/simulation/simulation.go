@@ -0,0 +1,416 @@
+//包simulation在内存中运行多个完整的以太坊节点和DPOS引擎，
+//所有节点共享一个模拟时钟，可以注入网络分区、时钟偏差和
+//验证人宕机，用于复现共识相关的问题。
+package simulation
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const serviceName = "dpos"
+
+//每一步推进模拟时钟之后，等待节点处理完毕的最长真实时间。
+//只用来判定失败，是否处理完毕由定时器和区块头事件决定
+const settleTimeout = 5 * time.Second
+
+//Config是模拟网络的参数
+type Config struct {
+Validators       int       //节点数量，每个节点都是创世验证人
+MaxValidatorSize uint64    //每个周期当选的验证人数量
+BlockInterval    uint64    //出块间隔（秒）
+Start            time.Time //创世区块时间，也是模拟时钟的起点
+}
+
+//Harness管理一组运行DPOS的模拟节点
+type Harness struct {
+	config  Config
+	clock   *dpos.SimulatedClock
+	genesis *core.Genesis
+	network *simulations.Network
+	ids     []discover.NodeID
+	groups  [][]int
+
+	lock  sync.Mutex
+	nodes map[discover.NodeID]*simNode
+}
+
+type simNode struct {
+	validator common.Address
+	ethereum  *eth.Ethereum
+	clock     *dpos.SkewedClock
+	mining    bool
+}
+
+//New创建模拟网络，验证人私钥由节点序号确定性生成，
+//每个验证人在创世区块中分配不同的余额，使选举结果可以复现
+func New(config Config) (*Harness, error) {
+	if config.Validators <= 0 {
+		return nil, errors.New("no validators")
+	}
+	if config.MaxValidatorSize == 0 {
+		config.MaxValidatorSize = uint64(config.Validators)
+	}
+	if config.BlockInterval == 0 {
+		config.BlockInterval = 1
+	}
+	h := &Harness{
+		config: config,
+		clock:  dpos.NewSimulatedClock(config.Start),
+		nodes:  make(map[discover.NodeID]*simNode),
+	}
+	keys := make([]*ecdsa.PrivateKey, config.Validators)
+	validators := make([]common.Address, config.Validators)
+	alloc := core.GenesisAlloc{}
+	for i := range keys {
+		key, err := crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("dpos-simulation-%d", i))))
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		validators[i] = crypto.PubkeyToAddress(key.PublicKey)
+		balance := new(big.Int).Mul(big.NewInt(int64(config.Validators-i)), big.NewInt(params.Ether))
+		alloc[validators[i]] = core.GenesisAccount{Balance: balance}
+	}
+	chainConfig := *params.DposChainConfig
+	chainConfig.Dpos = &params.DposConfig{
+		Validators:         validators,
+		MaxValidatorSize:   config.MaxValidatorSize,
+		BlockInterval:      config.BlockInterval,
+ValidatorListBlock: big.NewInt(0), //从创世块开始在区块头中携带验证人列表
+VacancyBlock:       big.NewInt(0), //从创世块开始在周期内补位空缺的验证人
+	}
+	h.genesis = &core.Genesis{
+		Config:     &chainConfig,
+		Timestamp:  uint64(config.Start.Unix()),
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+
+	adapter := adapters.NewSimAdapter(adapters.Services{serviceName: h.newService})
+	h.network = simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: serviceName})
+	for i, key := range keys {
+		conf := &adapters.NodeConfig{
+			ID:         discover.PubkeyID(&key.PublicKey),
+			Name:       fmt.Sprintf("validator-%d", i),
+			PrivateKey: key,
+			Services:   []string{serviceName},
+		}
+		if _, err := h.network.NewNodeWithConfig(conf); err != nil {
+			h.network.Shutdown()
+			return nil, err
+		}
+		h.ids = append(h.ids, conf.ID)
+	}
+	return h, nil
+}
+
+//newService启动一个完整的eth服务，并把DPOS引擎的时钟和签名者
+//换成模拟时钟和节点私钥
+func (h *Harness) newService(ctx *adapters.ServiceContext) (node.Service, error) {
+	config := eth.DefaultConfig
+	config.Genesis = h.genesis
+	config.NetworkId = h.genesis.Config.ChainID.Uint64()
+	config.SyncMode = downloader.FullSync
+	config.TxPool.NoLocals = true
+
+	ethereum, err := eth.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	engine, ok := ethereum.Engine().(*dpos.Dpos)
+	if !ok {
+		return nil, errors.New("dpos engine required")
+	}
+	key := ctx.Config.PrivateKey
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	clock := dpos.NewSkewedClock(h.clock, 0)
+	if old, ok := h.nodes[ctx.Config.ID]; ok {
+//节点重启后保留之前设置的时钟偏差
+		clock = old.clock
+	}
+	engine.SetClock(clock)
+	engine.Authorize(validator, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	ethereum.SetValidator(validator)
+	ethereum.SetCoinbase(validator)
+
+	h.nodes[ctx.Config.ID] = &simNode{validator: validator, ethereum: ethereum, clock: clock}
+	return ethereum, nil
+}
+
+//Start启动所有节点，两两连接并开始出块
+func (h *Harness) Start() error {
+	if err := h.network.StartAll(); err != nil {
+		return err
+	}
+	if err := h.Heal(); err != nil {
+		return err
+	}
+	for i := range h.ids {
+		h.StartValidator(i)
+	}
+	return nil
+}
+
+//Close停止所有节点
+func (h *Harness) Close() {
+	h.network.Shutdown()
+}
+
+//Clock返回所有节点共享的模拟时钟
+func (h *Harness) Clock() *dpos.SimulatedClock {
+	return h.clock
+}
+
+//Validator返回第i个节点的验证人地址
+func (h *Harness) Validator(i int) common.Address {
+	return h.node(i).validator
+}
+
+//Ethereum返回第i个节点的eth服务
+func (h *Harness) Ethereum(i int) *eth.Ethereum {
+	return h.node(i).ethereum
+}
+
+func (h *Harness) node(i int) *simNode {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.nodes[h.ids[i]]
+}
+
+//StartValidator让第i个节点开始出块
+func (h *Harness) StartValidator(i int) {
+	n := h.node(i)
+	h.lock.Lock()
+	n.mining = true
+	h.lock.Unlock()
+	n.ethereum.Miner().Start(n.validator, h.config.BlockInterval)
+}
+
+//StopValidator模拟第i个验证人宕机：节点继续同步区块，但不再出块
+func (h *Harness) StopValidator(i int) {
+	n := h.node(i)
+	h.lock.Lock()
+	n.mining = false
+	h.lock.Unlock()
+	n.ethereum.Miner().Stop()
+}
+
+//SetSkew设置第i个节点的时钟偏差
+func (h *Harness) SetSkew(i int, skew time.Duration) {
+	h.node(i).clock.SetSkew(skew)
+}
+
+//Partition按节点序号把网络分成几个互不连通的分区，
+//没有出现在任何分区中的节点与所有节点断开
+func (h *Harness) Partition(groups ...[]int) error {
+	group := make(map[int]int)
+	for g, members := range groups {
+		for _, i := range members {
+			group[i] = g + 1
+		}
+	}
+	for i := range h.ids {
+		for j := i + 1; j < len(h.ids); j++ {
+			if group[i] != 0 && group[i] == group[j] {
+				continue
+			}
+			if conn := h.network.GetConn(h.ids[i], h.ids[j]); conn != nil && conn.Up {
+				if err := h.network.Disconnect(h.ids[i], h.ids[j]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	h.lock.Lock()
+	h.groups = groups
+	h.lock.Unlock()
+	return nil
+}
+
+//Heal恢复全部节点之间的连接
+func (h *Harness) Heal() error {
+	for i := range h.ids {
+		for j := i + 1; j < len(h.ids); j++ {
+			if conn := h.network.GetConn(h.ids[i], h.ids[j]); conn != nil && conn.Up {
+				continue
+			}
+			if err := h.network.Connect(h.ids[i], h.ids[j]); err != nil {
+				return err
+			}
+		}
+	}
+	h.lock.Lock()
+	h.groups = nil
+	h.lock.Unlock()
+	return nil
+}
+
+//Advance把模拟时钟推进d，每次推进一个出块检查周期（出块间隔的十分之一），
+//并在每一步之后等待各节点处理完毕，使结果不依赖于真实时间
+func (h *Harness) Advance(d time.Duration) error {
+	step := time.Duration(h.config.BlockInterval) * time.Second / 10
+	for d > 0 {
+		if d < step {
+			step = d
+		}
+		h.clock.Run(step)
+		if err := h.settle(); err != nil {
+			return fmt.Errorf("%v at %v", err, h.clock.Now())
+		}
+		d -= step
+	}
+	return nil
+}
+
+//AdvanceEpochs推进n个选举周期，周期长度与DPOS引擎一致
+func (h *Harness) AdvanceEpochs(n int) error {
+	return h.Advance(time.Duration(n) * dpos.EpochInterval)
+}
+
+//settle等待所有出块节点处理完当前时间槽并重新挂上定时器，
+//然后等待每个分区内的节点同步到相同的区块头
+func (h *Harness) settle() error {
+	abort := make(chan struct{})
+	timer := time.AfterFunc(settleTimeout, func() { close(abort) })
+	defer timer.Stop()
+
+	h.lock.Lock()
+	miners := 0
+	for _, n := range h.nodes {
+		if n.mining {
+			miners++
+		}
+	}
+	h.lock.Unlock()
+	if !h.clock.WaitForTimers(miners, abort) {
+		return errors.New("validators did not finish the slot")
+	}
+
+//先订阅再检查，避免漏掉检查之后到达的区块
+	headCh := make(chan core.ChainHeadEvent, 16)
+	subs := make([]event.Subscription, len(h.ids))
+	for i := range h.ids {
+		subs[i] = h.node(i).ethereum.BlockChain().SubscribeChainHeadEvent(headCh)
+	}
+	defer func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}()
+	for !h.converged(h.heads()) {
+		select {
+		case <-headCh:
+		case <-abort:
+			return errors.New("nodes did not converge")
+		}
+	}
+	return nil
+}
+
+func (h *Harness) heads() []common.Hash {
+	heads := make([]common.Hash, len(h.ids))
+	for i := range h.ids {
+		heads[i] = h.Head(i).Hash()
+	}
+	return heads
+}
+
+//converged检查每个分区内的节点是否同步到相同的区块头
+func (h *Harness) converged(heads []common.Hash) bool {
+	h.lock.Lock()
+	groups := h.groups
+	h.lock.Unlock()
+
+	if groups == nil {
+		groups = [][]int{make([]int, len(h.ids))}
+		for i := range h.ids {
+			groups[0][i] = i
+		}
+	}
+	for _, members := range groups {
+		for _, i := range members {
+			if heads[i] != heads[members[0]] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//Head返回第i个节点当前的区块头
+func (h *Harness) Head(i int) *types.Header {
+	return h.node(i).ethereum.BlockChain().CurrentHeader()
+}
+
+//dposContext返回第i个节点当前区块的DPOS上下文
+func (h *Harness) dposContext(i int) (*types.DposContext, error) {
+	ethereum := h.node(i).ethereum
+	header := ethereum.BlockChain().CurrentHeader()
+	return types.NewDposContextFromProto(trie.NewDatabase(ethereum.ChainDb()), header.DposContext)
+}
+
+//ElectedValidators返回第i个节点当前周期的验证人列表
+func (h *Harness) ElectedValidators(i int) ([]common.Address, error) {
+	dposContext, err := h.dposContext(i)
+	if err != nil {
+		return nil, err
+	}
+	return dposContext.GetValidators()
+}
+
+//Candidates返回第i个节点当前的候选人集合
+func (h *Harness) Candidates(i int) (map[common.Address]bool, error) {
+	dposContext, err := h.dposContext(i)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make(map[common.Address]bool)
+	iter := trie.NewIterator(dposContext.CandidateTrie().NodeIterator(nil))
+	for iter.Next() {
+		candidates[common.BytesToAddress(iter.Value)] = true
+	}
+	return candidates, iter.Err
+}
+
+//ConfirmedNumber返回第i个节点的不可逆区块高度
+func (h *Harness) ConfirmedNumber(i int) (uint64, error) {
+	ethereum := h.node(i).ethereum
+	for _, api := range ethereum.Engine().APIs(ethereum.BlockChain()) {
+		if service, ok := api.Service.(*dpos.API); ok {
+			number, err := service.GetConfirmedBlockNumber()
+			if err != nil {
+				return 0, err
+			}
+			return number.Uint64(), nil
+		}
+	}
+	return 0, errors.New("dpos api not found")
+}
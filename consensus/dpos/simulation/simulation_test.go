@@ -0,0 +1,128 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+)
+
+//创世时间与选举周期对齐
+var testStart = time.Unix(1500000000, 0)
+
+func newTestHarness(t *testing.T, validators int, maxValidatorSize uint64) *Harness {
+	if testing.Short() {
+		t.Skip("skipping dpos simulation in short mode")
+	}
+	h, err := New(Config{
+		Validators:       validators,
+		MaxValidatorSize: maxValidatorSize,
+		BlockInterval:    2,
+		Start:            testStart,
+	})
+	if err != nil {
+		t.Fatalf("failed to create simulation: %v", err)
+	}
+	if err := h.Start(); err != nil {
+		h.Close()
+		t.Fatalf("failed to start simulation: %v", err)
+	}
+	return h
+}
+
+//宕机一整个周期的验证人在下一次选举时被踢出候选人列表，
+//其余验证人继续出块并推进不可逆区块
+func TestSimulationKickoutOfflineValidator(t *testing.T) {
+	h := newTestHarness(t, 5, 3)
+	defer h.Close()
+
+//创世周期结束后按余额选出前三名
+	if err := h.AdvanceEpochs(1); err != nil {
+		t.Fatalf("failed to advance simulation: %v", err)
+	}
+	elected, err := h.ElectedValidators(0)
+	if err != nil {
+		t.Fatalf("failed to get validators: %v", err)
+	}
+	if len(elected) != 3 {
+		t.Fatalf("elected validators mismatch: have %d, want 3", len(elected))
+	}
+	for i := 0; i < 3; i++ {
+		found := false
+		for _, validator := range elected {
+			found = found || validator == h.Validator(i)
+		}
+		if !found {
+			t.Errorf("validator %d not elected", i)
+		}
+	}
+	confirmed, err := h.ConfirmedNumber(0)
+	if err != nil {
+		t.Fatalf("failed to get confirmed block: %v", err)
+	}
+
+//第0个验证人宕机一个周期
+	h.StopValidator(0)
+	if err := h.AdvanceEpochs(1); err != nil {
+		t.Fatalf("failed to advance simulation: %v", err)
+	}
+	if err := h.Advance(10 * time.Second); err != nil {
+		t.Fatalf("failed to advance simulation: %v", err)
+	}
+
+	candidates, err := h.Candidates(1)
+	if err != nil {
+		t.Fatalf("failed to get candidates: %v", err)
+	}
+	if candidates[h.Validator(0)] {
+		t.Errorf("offline validator still a candidate")
+	}
+	elected, _ = h.ElectedValidators(1)
+	for _, validator := range elected {
+		if validator == h.Validator(0) {
+			t.Errorf("offline validator re-elected")
+		}
+	}
+	if number, _ := h.ConfirmedNumber(1); number <= confirmed {
+		t.Errorf("confirmed block not advancing: have %d, before %d", number, confirmed)
+	}
+}
+
+//网络分区期间少数分区无法推进不可逆区块，分区恢复后所有节点
+//收敛到同一条链并继续确认区块
+func TestSimulationPartitionAndSkew(t *testing.T) {
+	h := newTestHarness(t, 4, 4)
+	defer h.Close()
+
+	if err := h.Advance(20 * time.Second); err != nil {
+		t.Fatalf("failed to advance simulation: %v", err)
+	}
+	if err := h.Partition([]int{0}, []int{1, 2, 3}); err != nil {
+		t.Fatalf("failed to partition network: %v", err)
+	}
+	h.SetSkew(3, -time.Second)
+	partitioned := h.Head(0).Number.Uint64()
+	if err := h.Advance(30 * time.Second); err != nil {
+		t.Fatalf("failed to advance simulation: %v", err)
+	}
+//少数分区只有一个验证人，不可能确认分区之后产生的区块
+	minority, _ := h.ConfirmedNumber(0)
+	if minority > partitioned {
+		t.Errorf("minority partition confirmed blocks: have %d, partitioned at %d", minority, partitioned)
+	}
+
+	h.SetSkew(3, 0)
+	if err := h.Heal(); err != nil {
+		t.Fatalf("failed to heal network: %v", err)
+	}
+	if err := h.Advance(30 * time.Second); err != nil {
+		t.Fatalf("failed to advance simulation: %v", err)
+	}
+	head := h.Head(0).Hash()
+	for i := 1; i < 4; i++ {
+		if h.Head(i).Hash() != head {
+			t.Errorf("node %d not converged after heal", i)
+		}
+	}
+	if number, _ := h.ConfirmedNumber(0); number <= minority {
+		t.Errorf("confirmed block not advancing after heal: have %d, before %d", number, minority)
+	}
+}
@@ -0,0 +1,162 @@
+package dpos
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//验证人集合变化（新周期选举或周期内补位）的区块，会像clique的检查点一样
+//把生效后的验证人列表写进extra-data：
+//  vanity(32字节) | N*20字节验证人地址 | seal(65字节)
+//这样只凭区块头链就能确定每个时间槽的出块人，批量并行验证区块签名，
+//不必先从数据库恢复父块的DposContext。
+
+const inmemoryValidatorSets = 4096 //内存中缓存的区块头验证人集合数
+
+var (
+//如果extra-data中的验证人列表长度不是20字节的整数倍，返回errInvalidExtraValidators
+	errInvalidExtraValidators = errors.New("invalid validator list in extra-data")
+//如果新周期的第一个区块没有携带验证人列表，返回errMissingExtraValidators
+	errMissingExtraValidators = errors.New("epoch transition block without validator list")
+//如果分叉之前的区块头携带了验证人列表，返回errUnexpectedExtraValidators
+	errUnexpectedExtraValidators = errors.New("validator list in extra-data before fork")
+//如果区块头中的验证人列表与执行后DposContext中的不一致，返回errMismatchExtraValidators
+	errMismatchExtraValidators = errors.New("mismatch validator list in extra-data")
+)
+
+//ExtraValidators返回区块头extra-data中携带的验证人列表，没有列表时返回nil
+func ExtraValidators(header *types.Header) ([]common.Address, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	list := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if len(list)%common.AddressLength != 0 {
+		return nil, errInvalidExtraValidators
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	validators := make([]common.Address, len(list)/common.AddressLength)
+	for i := range validators {
+		copy(validators[i][:], list[i*common.AddressLength:])
+	}
+	return validators, nil
+}
+
+//isEpochTransition判断区块是否是新选举周期的第一个区块
+func isEpochTransition(parent, header *types.Header) bool {
+	return parent.Time.Int64()/epochInterval != header.Time.Int64()/epochInterval
+}
+
+//needsExtraValidators判断分叉之后的区块是否必须携带验证人列表：新周期的第一个区块，
+//以及分叉块本身（之后的区块从这里开始回溯验证人集合，不必再读取分叉之前的状态）
+func (d *Dpos) needsExtraValidators(parent, header *types.Header) bool {
+	return isEpochTransition(parent, header) || !d.config.IsValidatorList(parent.Number)
+}
+
+//sealExtraValidators在出块时把验证人列表写入extra-data；区块已经签名时
+//（导入其它节点的区块）只检查区块头携带的列表与本地执行的结果是否一致
+func sealExtraValidators(header *types.Header, validators []common.Address) error {
+	if len(header.Extra) < extraVanity {
+		header.Extra = append(header.Extra, make([]byte, extraVanity-len(header.Extra))...)
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		header.Extra = append(header.Extra[:extraVanity], make([]byte, extraSeal)...)
+	}
+	var list []byte
+	for _, validator := range validators {
+		list = append(list, validator.Bytes()...)
+	}
+	seal := header.Extra[len(header.Extra)-extraSeal:]
+	if !bytes.Equal(seal, make([]byte, extraSeal)) {
+		if !bytes.Equal(header.Extra[extraVanity:len(header.Extra)-extraSeal], list) {
+			return errMismatchExtraValidators
+		}
+		return nil
+	}
+	extra := make([]byte, 0, extraVanity+len(list)+extraSeal)
+	extra = append(extra, header.Extra[:extraVanity]...)
+	extra = append(extra, list...)
+	extra = append(extra, seal...)
+	header.Extra = extra
+	return nil
+}
+
+//sameValidators判断两个验证人列表是否完全相同（包括顺序）
+func sameValidators(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//validatorsAfter返回给定区块生效后的验证人集合，即其子区块排班所用的集合。
+//从该区块向前回溯到最近一个携带验证人列表的区块头（或创世块），
+//回溯到分叉之前的区块时改为读取该区块的DposContext。
+//parents为批量验证时尚未写入链中的祖先区块头
+func (d *Dpos) validatorsAfter(chain consensus.ChainReader, hash common.Hash, number uint64, parents []*types.Header) ([]common.Address, error) {
+	var (
+		pending    []common.Hash
+		validators []common.Address
+	)
+	for {
+		if cached, ok := d.validatorSets.Get(hash); ok {
+			validators = cached.([]common.Address)
+			break
+		}
+		var header *types.Header
+		if len(parents) > 0 {
+			header = parents[len(parents)-1]
+			if header.Hash() != hash || header.Number.Uint64() != number {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			parents = parents[:len(parents)-1]
+		} else {
+			header = chain.GetHeader(hash, number)
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+		pending = append(pending, hash)
+		if number == 0 {
+			if chain.Config().Dpos == nil {
+				return nil, errUnknownBlock
+			}
+			validators = chain.Config().Dpos.Validators
+			break
+		}
+		if !d.config.IsValidatorList(header.Number) {
+			dposContext, err := types.NewDposContextFromProto(trie.NewDatabase(d.db), header.DposContext)
+			if err != nil {
+				return nil, err
+			}
+			if validators, err = dposContext.GetValidators(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		list, err := ExtraValidators(header)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) > 0 {
+			validators = list
+			break
+		}
+		hash, number = header.ParentHash, number-1
+	}
+	for _, hash := range pending {
+		d.validatorSets.Add(hash, validators)
+	}
+	return validators, nil
+}
@@ -0,0 +1,129 @@
+package dpos
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealExtraValidators(t *testing.T) {
+	validators := []common.Address{
+		common.HexToAddress("0x44d1ce0b7cb3588bca96151fe1bc05af38f91b6e"),
+		common.HexToAddress("0xa60a3886b552ff9992cfcd208ec1152079e046c2"),
+	}
+	header := &types.Header{Extra: make([]byte, extraVanity+extraSeal)}
+
+//未签名的区块头写入验证人列表
+	assert.Nil(t, sealExtraValidators(header, validators))
+	assert.Equal(t, extraVanity+2*common.AddressLength+extraSeal, len(header.Extra))
+	list, err := ExtraValidators(header)
+	assert.Nil(t, err)
+	assert.Equal(t, validators, list)
+
+//已签名的区块头只做比对
+	copy(header.Extra[len(header.Extra)-extraSeal:], bytes.Repeat([]byte{0x01}, extraSeal))
+	assert.Nil(t, sealExtraValidators(header, validators))
+	assert.Equal(t, errMismatchExtraValidators, sealExtraValidators(header, validators[:1]))
+	assert.Equal(t, errMismatchExtraValidators, sealExtraValidators(header, nil))
+
+//长度不是地址长度整数倍的列表无效
+	header.Extra = make([]byte, extraVanity+common.AddressLength+1+extraSeal)
+	_, err = ExtraValidators(header)
+	assert.Equal(t, errInvalidExtraValidators, err)
+}
+
+func TestValidatorsAfter(t *testing.T) {
+	d := New(&params.DposConfig{ValidatorListBlock: big.NewInt(0)}, nil)
+	validators := []common.Address{
+		common.HexToAddress("0x44d1ce0b7cb3588bca96151fe1bc05af38f91b6e"),
+		common.HexToAddress("0xa60a3886b552ff9992cfcd208ec1152079e046c2"),
+		common.HexToAddress("0x4e080e49f62694554871e669aeb4ebe17c4a9670"),
+	}
+	checkpoint := &types.Header{
+		Number: big.NewInt(1),
+		Time:   big.NewInt(epochInterval),
+		Extra:  make([]byte, extraVanity+extraSeal),
+	}
+	assert.Nil(t, sealExtraValidators(checkpoint, validators))
+
+	parents := []*types.Header{checkpoint}
+	for i := 2; i <= 4; i++ {
+		parent := parents[len(parents)-1]
+		parents = append(parents, &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       new(big.Int).Add(parent.Time, big.NewInt(blockInterval)),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		})
+	}
+	head := parents[len(parents)-1]
+	got, err := d.validatorsAfter(nil, head.Hash(), head.Number.Uint64(), parents)
+	assert.Nil(t, err)
+	assert.Equal(t, validators, got)
+
+//之后的查询直接命中缓存
+	got, err = d.validatorsAfter(nil, parents[1].Hash(), parents[1].Number.Uint64(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, validators, got)
+
+//按时间槽轮流出块
+	validator, err := ScheduledValidator(got, epochInterval+2*blockInterval, uint64(blockInterval))
+	assert.Nil(t, err)
+	assert.Equal(t, validators[2], validator)
+	_, err = ScheduledValidator(got, epochInterval+1, uint64(blockInterval))
+	assert.Equal(t, ErrInvalidMintBlockTime, err)
+}
+
+//configChain只提供链配置，父区块头由调用方直接传入
+type configChain struct {
+	consensus.ChainReader
+	config *params.ChainConfig
+}
+
+func (c *configChain) Config() *params.ChainConfig { return c.config }
+
+func TestVerifyHeaderValidatorListFork(t *testing.T) {
+	config := *params.DposChainConfig
+	config.Dpos = &params.DposConfig{ValidatorListBlock: big.NewInt(3)}
+	chain := &configChain{config: &config}
+	d := New(config.Dpos, nil)
+
+	validators := []common.Address{common.HexToAddress("0x44d1ce0b7cb3588bca96151fe1bc05af38f91b6e")}
+	newHeader := func(parent *types.Header, time int64, list []common.Address) *types.Header {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Time:       big.NewInt(time),
+			Difficulty: big.NewInt(1),
+			UncleHash:  uncleHash,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		assert.Nil(t, sealExtraValidators(header, list))
+		return header
+	}
+	parent := &types.Header{Number: big.NewInt(1), Time: big.NewInt(epochInterval - blockInterval)}
+
+//分叉之前的新周期区块不携带验证人列表，按旧规则验证通过
+	legacy := newHeader(parent, epochInterval, nil)
+	assert.Nil(t, d.verifyHeader(chain, legacy, []*types.Header{parent}, uint64(blockInterval)))
+	header := newHeader(parent, epochInterval, validators)
+	assert.Equal(t, errUnexpectedExtraValidators, d.verifyHeader(chain, header, []*types.Header{parent}, uint64(blockInterval)))
+
+//分叉块即使不是新周期的第一个区块也必须携带列表
+	header = newHeader(legacy, epochInterval+blockInterval, nil)
+	assert.Equal(t, errMissingExtraValidators, d.verifyHeader(chain, header, []*types.Header{legacy}, uint64(blockInterval)))
+	fork := newHeader(legacy, epochInterval+blockInterval, validators)
+	assert.Nil(t, d.verifyHeader(chain, fork, []*types.Header{legacy}, uint64(blockInterval)))
+
+//分叉之后只有新周期的第一个区块必须携带列表
+	header = newHeader(fork, epochInterval+2*blockInterval, nil)
+	assert.Nil(t, d.verifyHeader(chain, header, []*types.Header{fork}, uint64(blockInterval)))
+	header = newHeader(fork, 2*epochInterval, nil)
+	assert.Equal(t, errMissingExtraValidators, d.verifyHeader(chain, header, []*types.Header{fork}, uint64(blockInterval)))
+}
@@ -0,0 +1,69 @@
+package misc
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+//VerifyBaseFee验证区块头的基础费用：费用市场分叉之前必须为空，
+//之后必须等于根据父块计算的值。
+func VerifyBaseFee(config *params.ChainConfig, parent, header *types.Header) error {
+	if !config.IsFeeMarket(header.Number) {
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %v, want <nil>", header.BaseFee)
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return fmt.Errorf("header is missing baseFee")
+	}
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if expected := CalcBaseFee(config, parent); header.BaseFee.Cmp(expected) != 0 {
+		return fmt.Errorf("invalid baseFee: have %v, want %v, parentBaseFee %v, parentGasUsed %d",
+			header.BaseFee, expected, parent.BaseFee, parent.GasUsed)
+	}
+	return nil
+}
+
+//CalcBaseFee计算parent的子块的基础费用。分叉块使用初始基础费用，之后
+//父块用掉的燃气高于燃气目标（燃气上限的一半）时上调，低于时下调，
+//每个区块最多变化1/8。
+func CalcBaseFee(config *params.ChainConfig, parent *types.Header) *big.Int {
+	if !config.IsFeeMarket(parent.Number) || parent.BaseFee == nil {
+		return new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	target := parent.GasLimit / params.ElasticityMultiplier
+	if target == 0 || parent.GasUsed == target {
+		return new(big.Int).Set(parent.BaseFee)
+	}
+	var (
+		targetBig      = new(big.Int).SetUint64(target)
+		denominatorBig = new(big.Int).SetUint64(params.BaseFeeChangeDenominator)
+	)
+	if parent.GasUsed > target {
+//燃气用量高于目标，基础费用至少上调1
+		delta := new(big.Int).SetUint64(parent.GasUsed - target)
+		delta.Mul(delta, parent.BaseFee)
+		delta.Div(delta, targetBig)
+		delta.Div(delta, denominatorBig)
+		delta = math.BigMax(delta, common1)
+		return delta.Add(delta, parent.BaseFee)
+	}
+//燃气用量低于目标，基础费用下调，最低为0
+	delta := new(big.Int).SetUint64(target - parent.GasUsed)
+	delta.Mul(delta, parent.BaseFee)
+	delta.Div(delta, targetBig)
+	delta.Div(delta, denominatorBig)
+	return math.BigMax(delta.Sub(parent.BaseFee, delta), common0)
+}
+
+var (
+	common0 = big.NewInt(0)
+	common1 = big.NewInt(1)
+)
@@ -188,6 +188,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+//头区块确定之后再打开它的平面状态快照，之后导入区块时从快照读取状态
+	bc.stateCache = state.NewDatabaseWithSnapshots(db, bc.CurrentBlock().Root())
+
 //取得这个国家的所有权
 	blockinterval := bc.GetBlockByNumber(0).Header().BlockInterval
 	fmt.Println(blockinterval)
@@ -362,6 +365,16 @@ func（bc*区块链）setprocessor（处理器处理器）
  bc.processor=处理器
 }
 
+//SetParallelTxs设置默认状态处理器并行执行交易的线程数，小于2时顺序执行
+func (bc *BlockChain) SetParallelTxs(workers int) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+
+	if processor, ok := bc.processor.(*StateProcessor); ok {
+		processor.SetParallel(workers)
+	}
+}
+
 //setvalidator设置用于验证传入块的验证程序。
 func（bc*区块链）setvalidator（验证器验证器）
  bc.procmu.lock（）。
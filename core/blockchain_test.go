@@ -14,9 +14,13 @@ package core
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1480,3 +1484,74 @@ func BenchmarkBlockChain_1x1000Executions(b *testing.B) {
 	benchmarkLargeNumberOfValueToNonexisting(b, numTxs, numBlocks, recipientFn, dataFn)
 }
 
+//测试回滚到已冻结的高度以下时截断冻结表，被回滚的高度不再返回旧的规范区块，
+//之后导入的分叉区块成为这些高度的规范区块
+func TestSetHeadBelowAncients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sethead_ancients_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, filepath.Join(dir, "ancient"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	genesis := new(Genesis).MustCommit(db)
+	blocks := makeBlockChain(genesis, 10, ethash.NewFaker(), db, canonicalSeed)
+	fork := makeBlockChain(blocks[3], 6, ethash.NewFaker(), db, forkSeed)
+
+	chain, err := NewBlockChain(db, nil, params.AllEthashProtocolChanges, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+//冻结高度0到7的区块
+	limit := uint64(8)
+	db.Freeze(func() uint64 { return atomic.LoadUint64(&limit) })
+	for i := 0; ; i++ {
+		if frozen, _ := db.Ancients(); frozen == 8 {
+			break
+		}
+		if i == 200 {
+			t.Fatalf("blocks not frozen")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	atomic.StoreUint64(&limit, 0)
+
+	if err := chain.SetHead(4); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 5 {
+		t.Fatalf("frozen count mismatch: have %d, want %d", frozen, 5)
+	}
+	for _, block := range blocks[4:] {
+		if hash := rawdb.ReadCanonicalHash(db, block.NumberU64()); hash != (common.Hash{}) {
+			t.Fatalf("block %d: rewound canonical hash still readable: %x", block.NumberU64(), hash)
+		}
+		if rawdb.HasHeader(db, block.Hash(), block.NumberU64()) {
+			t.Fatalf("block %d: rewound header still readable", block.NumberU64())
+		}
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 4); hash != blocks[3].Hash() {
+		t.Fatalf("block 4: canonical hash mismatch: have %x, want %x", hash, blocks[3].Hash())
+	}
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	for _, block := range fork {
+		if hash := rawdb.ReadCanonicalHash(db, block.NumberU64()); hash != block.Hash() {
+			t.Fatalf("block %d: canonical hash mismatch: have %x, want %x", block.NumberU64(), hash, block.Hash())
+		}
+	}
+}
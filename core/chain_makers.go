@@ -35,6 +35,7 @@ type BlockGen struct {
 	chainReader consensus.ChainReader
 	header      *types.Header
 	statedb     *state.StateDB
+dposContext *types.DposContext //DPoS交易修改的上下文，从父块的DPoS状态打开
 
 	gasPool  *GasPool
 	txs      []*types.Transaction
@@ -88,7 +89,7 @@ func (b *BlockGen) AddTxWithChain(bc *BlockChain, tx *types.Transaction) {
 		b.SetCoinbase(common.Address{})
 	}
 	b.statedb.Prepare(tx.Hash(), common.Hash{}, len(b.txs))
-	receipt, _, err := ApplyTransaction(b.config, nil, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vm.Config{})
+	receipt, _, err := ApplyTransaction(b.config, b.dposContext, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vm.Config{})
 	if err != nil {
 		panic(err)
 	}
@@ -170,7 +171,11 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		blockchain, _ := NewBlockChain(db, nil, config, engine, vm.Config{})
 		defer blockchain.Stop()
 
-		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: blockchain, statedb: statedb, config: config, engine: engine}
+		dposContext, err := types.NewDposContextFromProto(statedb.Database().TrieDB(), parent.Header().DposContext)
+		if err != nil {
+			panic(fmt.Sprintf("dpos context error: %v", err))
+		}
+		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: blockchain, statedb: statedb, dposContext: dposContext, config: config, engine: engine}
 		b.header = makeHeader(b.chainReader, parent, statedb, b.engine)
 
 //根据任何硬分叉规格改变状态并阻塞
@@ -191,7 +196,12 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		}
 
 		if b.engine != nil {
-			block, _ := b.engine.Finalize(b.chainReader, b.header, statedb, b.txs, b.uncles, b.receipts,parent.DposContext)
+			b.header.DposContext = b.dposContext.ToProto()
+			block, _ := b.engine.Finalize(b.chainReader, b.header, statedb, b.txs, b.uncles, b.receipts, b.dposContext)
+			if _, err := b.dposContext.Commit(); err != nil {
+				panic(fmt.Sprintf("dpos context write error: %v", err))
+			}
+			block.DposContext = b.dposContext
 //将状态更改写入数据库
 			root, err := statedb.Commit(config.IsEIP158(b.header.Number))
 			if err != nil {
@@ -225,7 +235,7 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 time = new(big.Int).Add(parent.Time(), big.NewInt(10)) //阻塞时间固定为10秒
 	}
 
-	return &types.Header{
+	header := &types.Header{
 		Root:       state.IntermediateRoot(chain.Config().IsEIP158(parent.Number())),
 		ParentHash: parent.Hash(),
 		Coinbase:   parent.Coinbase(),
@@ -239,6 +249,10 @@ time = new(big.Int).Add(parent.Time(), big.NewInt(10)) //阻塞时间固定为10
 		Number:   new(big.Int).Add(parent.Number(), common.Big1),
 		Time:     time,
 	}
+	if chain.Config().IsFeeMarket(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(chain.Config(), parent.Header())
+	}
+	return header
 }
 
 //MakeHeaderChain创建一个以父级为根的具有确定性的头链。
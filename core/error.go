@@ -28,5 +28,11 @@ var (
 //如果事务的nonce高于
 //下一个基于本地链的期望值。
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+//如果交易的最高费用低于区块的基础费用，则返回ErrFeeCapTooLow。
+	ErrFeeCapTooLow = errors.New("max fee per gas less than block base fee")
+
+//如果交易的小费上限高于最高费用，则返回ErrTipAboveFeeCap。
+	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
 )
 
@@ -40,6 +40,10 @@ beneficiary, _ = chain.Engine().Author(header) //忽略错误，我们已经过
 	} else {
 		beneficiary = *author
 	}
+	var baseFee *big.Int
+	if header.BaseFee != nil {
+		baseFee = new(big.Int).Set(header.BaseFee)
+	}
 	return vm.Context{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
@@ -50,7 +54,8 @@ beneficiary, _ = chain.Engine().Author(header) //忽略错误，我们已经过
 		Time:        new(big.Int).Set(header.Time),
 		Difficulty:  new(big.Int).Set(header.Difficulty),
 		GasLimit:    header.GasLimit,
-		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		GasPrice:    EffectiveGasPrice(msg, baseFee),
+		BaseFee:     baseFee,
 	}
 }
 
@@ -464,6 +464,12 @@ func (hc *HeaderChain) SetHead(head uint64, delFn DeleteCallback) {
 		rawdb.DeleteCanonicalHash(batch, i)
 	}
 	batch.Write()
+//回滚到已冻结的高度以下时截断冻结表，否则仍会从冻结表读到旧的规范区块
+	if ancients, ok := hc.chainDb.(rawdb.AncientWriter); ok {
+		if err := ancients.TruncateAncients(head + 1); err != nil {
+			log.Crit("Failed to truncate ancient store", "err", err)
+		}
+	}
 
 //从缓存中清除所有过时的内容
 	hc.headerCache.Purge()
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//errSerialFallback表示推测执行的结果无法合并，需要退回顺序执行
+var errSerialFallback = errors.New("serial execution required")
+
+//txExecution是一笔交易在状态副本上的推测执行结果
+type txExecution struct {
+	msg         types.Message
+	state       *state.StateDB
+	gas         uint64
+	failed      bool
+dposRead    bool //是否通过预编译合约读取过DPoS状态
+dposWritten bool //是否通过系统合约修改过DPoS状态
+	err         error
+}
+
+//executeSpeculative在base的副本上执行交易并记录读取的帐户和存储槽，
+//不修改base，也不定稿状态，以便之后把修改合并到真正的状态。
+func (p *StateProcessor) executeSpeculative(base *state.StateDB, dposContext *types.DposContext, header *types.Header, blockHash common.Hash, index int, tx *types.Transaction, cfg vm.Config) *txExecution {
+	exec := &txExecution{}
+	if exec.err = checkEnvelope(p.config, header.Number, tx); exec.err != nil {
+		return exec
+	}
+	msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number))
+	if err != nil {
+		exec.err = err
+		return exec
+	}
+	exec.msg = msg
+	if msg.To() == nil && msg.Type() != types.Binary {
+		exec.err = types.ErrInvalidType
+		return exec
+	}
+	context := NewEVMContext(msg, header, p.bc, nil)
+//DPoS状态的trie在读取时会缓存解析的节点，每笔交易使用自己的副本
+	context.DposContext = dposContext.Copy()
+
+	statedb := base.Copy()
+	statedb.Prepare(tx.Hash(), blockHash, index)
+	statedb.TrackAccess(context.Coinbase)
+
+	vmenv := vm.NewEVM(context, statedb, p.config, cfg)
+//每笔交易单独使用区块的燃气上限，真正的燃气池在合并时按顺序扣减
+	gp := new(GasPool).AddGas(header.GasLimit)
+	_, exec.gas, exec.failed, exec.err = ApplyMessage(vmenv, msg, gp)
+	exec.state = statedb
+	exec.dposRead = vmenv.DposRead()
+	exec.dposWritten = vmenv.DposWritten()
+	return exec
+}
+
+//processParallel乐观地并行执行区块中的交易：每笔交易先在区块开始时状态的
+//副本上执行，然后按顺序合并到statedb。交易读取的帐户或存储槽被之前的交易
+//修改过时，在当前状态的副本上重新执行。得到的收据和状态根与顺序执行相同。
+func (p *StateProcessor) processParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	var (
+		receipts types.Receipts
+		usedGas  = new(uint64)
+		header   = block.Header()
+		allLogs  []*types.Log
+		gp       = new(GasPool).AddGas(block.GasLimit())
+		txs      = block.Transactions()
+		execs    = make([]*txExecution, len(txs))
+	)
+	workers := p.parallel
+	if workers > runtime.NumCPU() {
+		workers = runtime.NumCPU()
+	}
+//第一阶段：在区块开始时的状态上并行推测执行
+	var (
+		wg   sync.WaitGroup
+		next = make(chan int, len(txs))
+	)
+	for i := range txs {
+		next <- i
+	}
+	close(next)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				execs[i] = p.executeSpeculative(statedb, block.DposCtx(), header, block.Hash(), i, txs[i], cfg)
+			}
+		}()
+	}
+	wg.Wait()
+
+//第二阶段：按顺序合并，冲突的交易在当前状态上重新执行
+	var (
+		written     = state.NewAccessSet()
+		dposWritten bool
+	)
+	for i, tx := range txs {
+		receipt, err := p.commitExecution(execs[i], written, dposWritten, block, statedb, i, gp, usedGas, cfg)
+		if err == errSerialFallback {
+//修改无法合并（拜占庭之前的特殊情况），之后的交易全部顺序执行
+			for j := i; j < len(txs); j++ {
+				statedb.Prepare(txs[j].Hash(), block.Hash(), j)
+				receipt, _, err := ApplyTransaction(p.config, block.DposCtx(), p.bc, nil, gp, statedb, header, txs[j], usedGas, cfg)
+				if err != nil {
+					return nil, nil, 0, err
+				}
+				receipts = append(receipts, receipt)
+				allLogs = append(allLogs, receipt.Logs...)
+			}
+			break
+		}
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+		if tx.Type() != types.Binary {
+			dposWritten = true
+		}
+	}
+	if _, err := p.engine.Finalize(p.bc, header, statedb, txs, block.Uncles(), receipts, block.DposCtx()); err != nil {
+		return nil, nil, 0, err
+	}
+	return receipts, allLogs, *usedGas, nil
+}
+
+//commitExecution把第i笔交易的推测执行结果按顺序合并到statedb并生成收据。
+//结果读取的内容已被之前的交易修改时（dposWritten表示之前有交易修改了DPoS状态），
+//先在当前状态上重新执行。
+func (p *StateProcessor) commitExecution(exec *txExecution, written *state.AccessSet, dposWritten bool, block *types.Block, statedb *state.StateDB, i int, gp *GasPool, usedGas *uint64, cfg vm.Config) (*types.Receipt, error) {
+	var (
+		header      = block.Header()
+		tx          = block.Transactions()[i]
+		deleteEmpty = p.config.IsEIP158(header.Number)
+	)
+	if exec.err != nil || (exec.dposRead && dposWritten) || written.Conflicts(exec.state.AccessedSet()) {
+		exec = p.executeSpeculative(statedb, block.DposCtx(), header, block.Hash(), i, tx, cfg)
+	}
+	if exec.err != nil {
+		return nil, exec.err
+	}
+//通过系统合约修改DPoS状态的交易同样无法合并
+	if !exec.state.Mergeable() || exec.dposWritten {
+		return nil, errSerialFallback
+	}
+//与ApplyMessage扣减燃气池的效果相同：先预留全部燃气，再退回剩余部分
+	if err := gp.SubGas(exec.msg.Gas()); err != nil {
+		return nil, err
+	}
+	gp.AddGas(exec.msg.Gas() - exec.gas)
+
+	statedb.Prepare(tx.Hash(), block.Hash(), i)
+	writes := exec.state.MergeInto(statedb, deleteEmpty)
+	written.Merge(writes)
+
+	if exec.msg.Type() != types.Binary {
+		if err := ApplyDposMessage(block.DposCtx(), exec.msg); err != nil {
+			return nil, err
+		}
+	}
+	var root []byte
+	if p.config.IsByzantium(header.Number) {
+		statedb.Finalise(true)
+	} else {
+		root = statedb.IntermediateRoot(deleteEmpty).Bytes()
+	}
+	*usedGas += exec.gas
+
+	receipt := types.NewReceipt(root, exec.failed, *usedGas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = exec.gas
+	if exec.msg.To() == nil {
+		receipt.ContractAddress = crypto.CreateAddress(exec.msg.From(), tx.Nonce())
+	}
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt, nil
+}
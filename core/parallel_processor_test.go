@@ -0,0 +1,147 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+//processResult是处理一个区块得到的全部结果
+type processResult struct {
+	receipts types.Receipts
+	logs     []*types.Log
+	usedGas  uint64
+	root     common.Hash
+	dposRoot common.Hash
+}
+
+//测试并行执行与顺序执行处理同一条链得到相同的收据、日志、燃气和状态根。
+//链中包含同一发送者的连续交易、读写同一存储槽的合约调用以及DPoS交易。
+func TestParallelProcessing(t *testing.T) {
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		key3, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		addr3   = crypto.PubkeyToAddress(key3.PublicKey)
+		counter = common.Address{0xcc}
+		funds   = big.NewInt(1000000000000000000)
+
+		db    = ethdb.NewMemDatabase()
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				addr1: {Balance: funds},
+				addr2: {Balance: funds},
+				addr3: {Balance: funds},
+//把调用数据加到存储槽0上并记录新值
+				counter: {Code: []byte{
+					byte(vm.PUSH1), 0, byte(vm.CALLDATALOAD),
+					byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.ADD),
+					byte(vm.DUP1), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+					byte(vm.PUSH1), 0, byte(vm.MSTORE),
+					byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG0),
+					byte(vm.STOP),
+				}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	engine := ethash.NewFaker()
+	chain, _ := GenerateChain(gspec.Config, genesis, engine, db, 3, func(i int, block *BlockGen) {
+		add := func(key *ecdsa.PrivateKey, txType types.TxType, to common.Address, value int64, gas uint64, data []byte) {
+			tx := types.NewTransaction(txType, block.TxNonce(crypto.PubkeyToAddress(key.PublicKey)), to, big.NewInt(value), gas, big.NewInt(1), data)
+			tx, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			block.AddTx(tx)
+		}
+		call := common.LeftPadBytes([]byte{byte(i + 1)}, 32)
+
+//同一发送者的连续交易，其中一笔修改共享的存储槽
+		add(key1, types.Binary, addr2, 1000, params.TxGas, nil)
+		add(key1, types.Binary, counter, 0, 100000, call)
+		add(key1, types.Binary, addr3, 1000, params.TxGas, nil)
+//其他发送者读写同一个存储槽
+		add(key2, types.Binary, counter, 0, 100000, call)
+		add(key3, types.Binary, counter, 0, 100000, call)
+
+		switch i {
+		case 0:
+			add(key3, types.RegCandidate, addr3, 0, 100000, nil)
+		case 1:
+			add(key2, types.Delegate, addr3, 0, 100000, nil)
+			add(key1, types.Delegate, addr3, 0, 100000, nil)
+		case 2:
+			add(key2, types.UnDelegate, addr3, 0, 100000, nil)
+			add(key2, types.Binary, counter, 0, 100000, call)
+		}
+	})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	process := func(parallel int, parent, block *types.Block) *processResult {
+		statedb, err := state.New(parent.Root(), state.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("failed to open parent state: %v", err)
+		}
+		block.DposContext, err = types.NewDposContextFromProto(statedb.Database().TrieDB(), parent.Header().DposContext)
+		if err != nil {
+			t.Fatalf("failed to open parent dpos context: %v", err)
+		}
+		processor := NewStateProcessor(gspec.Config, blockchain, engine)
+		processor.SetParallel(parallel)
+
+		receipts, logs, usedGas, err := processor.Process(block, statedb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: processing with %d workers failed: %v", block.NumberU64(), parallel, err)
+		}
+		return &processResult{
+			receipts: receipts,
+			logs:     logs,
+			usedGas:  usedGas,
+			root:     statedb.IntermediateRoot(gspec.Config.IsEIP158(block.Number())),
+			dposRoot: block.DposCtx().Root(),
+		}
+	}
+	parent := genesis
+	for _, block := range chain {
+		sequential, parallel := process(0, parent, block), process(4, parent, block)
+
+		if sequential.root != block.Root() {
+			t.Fatalf("block %d: sequential state root mismatch: have %x, want %x", block.NumberU64(), sequential.root, block.Root())
+		}
+		if want := block.Header().DposContext.Root(); sequential.dposRoot != want {
+			t.Fatalf("block %d: sequential dpos root mismatch: have %x, want %x", block.NumberU64(), sequential.dposRoot, want)
+		}
+		if !reflect.DeepEqual(parallel.receipts, sequential.receipts) {
+			t.Errorf("block %d: receipts mismatch: parallel %v, sequential %v", block.NumberU64(), parallel.receipts, sequential.receipts)
+		}
+		if !reflect.DeepEqual(parallel.logs, sequential.logs) {
+			t.Errorf("block %d: logs mismatch: parallel %v, sequential %v", block.NumberU64(), parallel.logs, sequential.logs)
+		}
+		if parallel.usedGas != sequential.usedGas {
+			t.Errorf("block %d: used gas mismatch: parallel %d, sequential %d", block.NumberU64(), parallel.usedGas, sequential.usedGas)
+		}
+		if parallel.root != sequential.root {
+			t.Errorf("block %d: state root mismatch: parallel %x, sequential %x", block.NumberU64(), parallel.root, sequential.root)
+		}
+		if parallel.dposRoot != sequential.dposRoot {
+			t.Errorf("block %d: dpos root mismatch: parallel %x, sequential %x", block.NumberU64(), parallel.dposRoot, sequential.dposRoot)
+		}
+		parent = block
+	}
+}
@@ -26,6 +26,11 @@ import (
 //readCanonicalHash检索分配给规范块号的哈希。
 func ReadCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 	data, _ := db.Get(headerHashKey(number))
+	if len(data) == 0 {
+		if ancients, ok := db.(AncientReader); ok {
+			data, _ = ancients.Ancient(freezerHashTable, number)
+		}
+	}
 	if len(data) == 0 {
 		return common.Hash{}
 	}
@@ -124,12 +129,18 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 
 //readheaderrlp以其原始RLP数据库编码检索块头。
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	if data := readAncient(db, freezerHeaderTable, hash, number); len(data) > 0 {
+		return data
+	}
 	data, _ := db.Get(headerKey(number, hash))
 	return data
 }
 
 //散列头验证与散列对应的块头是否存在。
 func HasHeader(db DatabaseReader, hash common.Hash, number uint64) bool {
+	if isCanonicalAncient(db, hash, number) {
+		return true
+	}
 	if has, err := db.Has(headerKey(number, hash)); !has || err != nil {
 		return false
 	}
@@ -186,6 +197,9 @@ func DeleteHeader(db DatabaseDeleter, hash common.Hash, number uint64) {
 
 //readbodyrlp以rlp编码方式检索块体（事务和uncles）。
 func ReadBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	if data := readAncient(db, freezerBodiesTable, hash, number); len(data) > 0 {
+		return data
+	}
 	data, _ := db.Get(blockBodyKey(number, hash))
 	return data
 }
@@ -199,6 +213,9 @@ func WriteBodyRLP(db DatabaseWriter, hash common.Hash, number uint64, rlp rlp.Ra
 
 //hasbody验证哈希对应的块体的存在。
 func HasBody(db DatabaseReader, hash common.Hash, number uint64) bool {
+	if isCanonicalAncient(db, hash, number) {
+		return true
+	}
 	if has, err := db.Has(blockBodyKey(number, hash)); !has || err != nil {
 		return false
 	}
@@ -237,7 +254,10 @@ func DeleteBody(db DatabaseDeleter, hash common.Hash, number uint64) {
 
 //readtd检索与哈希相对应的块的总难度。
 func ReadTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
-	data, _ := db.Get(headerTDKey(number, hash))
+	data := readAncient(db, freezerDifficultyTable, hash, number)
+	if len(data) == 0 {
+		data, _ = db.Get(headerTDKey(number, hash))
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -269,8 +289,11 @@ func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64) {
 
 //readReceipts检索属于块的所有事务收据。
 func ReadReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
-//检索扁平收据切片
-	data, _ := db.Get(blockReceiptsKey(number, hash))
+//检索扁平收据切片，已冻结的区块从冻结表读取
+	data := readAncient(db, freezerReceiptTable, hash, number)
+	if len(data) == 0 {
+		data, _ = db.Get(blockReceiptsKey(number, hash))
+	}
 	if len(data) == 0 {
 		return nil
 	}
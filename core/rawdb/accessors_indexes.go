@@ -13,8 +13,11 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -102,3 +105,87 @@ func WriteBloomBits(db DatabaseWriter, bit uint, section uint64, head common.Has
 	}
 }
 
+
+//addressTxRef记录区块写入的一条地址交易索引，重组时据此删除
+type addressTxRef struct {
+	Address common.Address
+	Index   uint32
+}
+
+//WriteAddressTxEntries存储一个区块中所有地址的交易索引条目，以及用于
+//DeleteAddressTxEntries删除它们的区块记录。
+func WriteAddressTxEntries(db DatabaseWriter, number uint64, addresses []common.Address, entries []*AddressTxEntry) {
+	refs := make([]addressTxRef, 0, len(entries))
+	for i, entry := range entries {
+		data, err := rlp.EncodeToBytes(entry)
+		if err != nil {
+			log.Crit("Failed to encode address transaction entry", "err", err)
+		}
+		if err := db.Put(addressTxKey(addresses[i], number, uint32(entry.Index)), data); err != nil {
+			log.Crit("Failed to store address transaction entry", "err", err)
+		}
+		refs = append(refs, addressTxRef{Address: addresses[i], Index: uint32(entry.Index)})
+	}
+	data, err := rlp.EncodeToBytes(refs)
+	if err != nil {
+		log.Crit("Failed to encode address index block", "err", err)
+	}
+	if err := db.Put(addressBlockKey(number), data); err != nil {
+		log.Crit("Failed to store address index block", "err", err)
+	}
+}
+
+//DeleteAddressTxEntries删除某个高度的区块写入的全部地址交易索引条目
+func DeleteAddressTxEntries(db ethdb.Database, number uint64) {
+	data, _ := db.Get(addressBlockKey(number))
+	if len(data) == 0 {
+		return
+	}
+	var refs []addressTxRef
+	if err := rlp.DecodeBytes(data, &refs); err != nil {
+		log.Error("Invalid address index block RLP", "number", number, "err", err)
+		return
+	}
+	for _, ref := range refs {
+		db.Delete(addressTxKey(ref.Address, number, ref.Index))
+	}
+	db.Delete(addressBlockKey(number))
+}
+
+//ReadAddressTxEntries按区块顺序检索地址在[from, to]区块范围内的交易索引条目，
+//跳过前skip条，最多返回limit条。底层数据库不支持遍历时返回空。
+func ReadAddressTxEntries(db ethdb.Database, address common.Address, from, to uint64, skip, limit int) []*AddressTxEntry {
+	store, ok := db.(ethdb.Store)
+	if !ok {
+		return nil
+	}
+	prefix := addressTxsKey(address)
+	it := store.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	var entries []*AddressTxEntry
+	for len(entries) < limit && it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+12 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number < from {
+			continue
+		}
+		if number > to {
+			break
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		entry := new(AddressTxEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil {
+			log.Error("Invalid address transaction entry RLP", "address", address, "number", number, "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -13,7 +13,9 @@
 package rawdb
 
 import (
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -63,3 +65,48 @@ func TestLookupStorage(t *testing.T) {
 	}
 }
 
+
+//测试地址交易索引条目的存储、按区块范围分页读取以及按区块删除
+func TestAddressTxEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addrindex_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var (
+		addr  = common.BytesToAddress([]byte{0x11})
+		other = common.BytesToAddress([]byte{0x22})
+	)
+	for number := uint64(1); number <= 4; number++ {
+		entries := []*AddressTxEntry{
+			{BlockHash: common.Hash{byte(number)}, BlockIndex: number, Index: 0, TxHash: common.Hash{byte(number), 0}, Roles: 1},
+			{BlockHash: common.Hash{byte(number)}, BlockIndex: number, Index: 1, TxHash: common.Hash{byte(number), 1}, Roles: 2},
+			{BlockHash: common.Hash{byte(number)}, BlockIndex: number, Index: 1, TxHash: common.Hash{byte(number), 1}, Roles: 1},
+		}
+		WriteAddressTxEntries(db, number, []common.Address{addr, addr, other}, entries)
+	}
+	if entries := ReadAddressTxEntries(db, addr, 0, 100, 0, 100); len(entries) != 8 {
+		t.Fatalf("entry count mismatch: have %d, want %d", len(entries), 8)
+	}
+	entries := ReadAddressTxEntries(db, addr, 2, 3, 1, 2)
+	if len(entries) != 2 {
+		t.Fatalf("page size mismatch: have %d, want %d", len(entries), 2)
+	}
+	if entries[0].BlockIndex != 2 || entries[0].Index != 1 || entries[1].BlockIndex != 3 || entries[1].Index != 0 {
+		t.Fatalf("page mismatch: have %d/%d, %d/%d", entries[0].BlockIndex, entries[0].Index, entries[1].BlockIndex, entries[1].Index)
+	}
+	DeleteAddressTxEntries(db, 2)
+	if entries := ReadAddressTxEntries(db, addr, 2, 2, 0, 100); len(entries) != 0 {
+		t.Fatalf("deleted entries returned: %d", len(entries))
+	}
+	if entries := ReadAddressTxEntries(db, other, 0, 100, 0, 100); len(entries) != 3 {
+		t.Fatalf("other address entry count mismatch: have %d, want %d", len(entries), 3)
+	}
+}
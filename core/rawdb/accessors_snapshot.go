@@ -0,0 +1,98 @@
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+//ReadSnapshotRoot检索磁盘上状态快照对应的状态根，没有快照时返回空哈希。
+func ReadSnapshotRoot(db DatabaseReader) common.Hash {
+	data, _ := db.Get(snapshotRootKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+//WriteSnapshotRoot存储磁盘上状态快照对应的状态根。
+func WriteSnapshotRoot(db DatabaseWriter, root common.Hash) {
+	if err := db.Put(snapshotRootKey, root[:]); err != nil {
+		log.Crit("Failed to store snapshot root", "err", err)
+	}
+}
+
+//DeleteSnapshotRoot删除快照根，表示磁盘上的快照不再可用。
+func DeleteSnapshotRoot(db DatabaseDeleter) {
+	if err := db.Delete(snapshotRootKey); err != nil {
+		log.Crit("Failed to remove snapshot root", "err", err)
+	}
+}
+
+//ReadSnapshotGenerator检索快照生成的进度。第二个返回值为false时没有正在进行的生成；
+//否则marker之前（含）的账户和存储已经生成，marker为空表示尚未开始。
+func ReadSnapshotGenerator(db DatabaseReader) ([]byte, bool) {
+	if has, _ := db.Has(snapshotGeneratorKey); !has {
+		return nil, false
+	}
+	data, _ := db.Get(snapshotGeneratorKey)
+	return data, true
+}
+
+//WriteSnapshotGenerator存储快照生成的进度。
+func WriteSnapshotGenerator(db DatabaseWriter, marker []byte) {
+	if err := db.Put(snapshotGeneratorKey, marker); err != nil {
+		log.Crit("Failed to store snapshot generator", "err", err)
+	}
+}
+
+//DeleteSnapshotGenerator删除快照生成的进度，表示生成已经完成。
+func DeleteSnapshotGenerator(db DatabaseDeleter) {
+	if err := db.Delete(snapshotGeneratorKey); err != nil {
+		log.Crit("Failed to remove snapshot generator", "err", err)
+	}
+}
+
+//ReadAccountSnapshot检索账户的快照数据，即账户trie中该账户叶子的值。
+func ReadAccountSnapshot(db DatabaseReader, hash common.Hash) []byte {
+	data, _ := db.Get(accountSnapshotKey(hash))
+	return data
+}
+
+//WriteAccountSnapshot存储账户的快照数据。
+func WriteAccountSnapshot(db DatabaseWriter, hash common.Hash, entry []byte) {
+	if err := db.Put(accountSnapshotKey(hash), entry); err != nil {
+		log.Crit("Failed to store account snapshot", "err", err)
+	}
+}
+
+//DeleteAccountSnapshot删除账户的快照数据。
+func DeleteAccountSnapshot(db DatabaseDeleter, hash common.Hash) {
+	if err := db.Delete(accountSnapshotKey(hash)); err != nil {
+		log.Crit("Failed to delete account snapshot", "err", err)
+	}
+}
+
+//ReadStorageSnapshot检索存储槽的快照数据，即存储trie中该槽叶子的值。
+func ReadStorageSnapshot(db DatabaseReader, accountHash, storageHash common.Hash) []byte {
+	data, _ := db.Get(storageSnapshotKey(accountHash, storageHash))
+	return data
+}
+
+//WriteStorageSnapshot存储存储槽的快照数据。
+func WriteStorageSnapshot(db DatabaseWriter, accountHash, storageHash common.Hash, entry []byte) {
+	if err := db.Put(storageSnapshotKey(accountHash, storageHash), entry); err != nil {
+		log.Crit("Failed to store storage snapshot", "err", err)
+	}
+}
+
+//DeleteStorageSnapshot删除存储槽的快照数据。
+func DeleteStorageSnapshot(db DatabaseDeleter, accountHash, storageHash common.Hash) {
+	if err := db.Delete(storageSnapshotKey(accountHash, storageHash)); err != nil {
+		log.Crit("Failed to delete storage snapshot", "err", err)
+	}
+}
+
+//StorageSnapshotsPrefix返回账户全部存储快照共同的键前缀，用于遍历或整体删除。
+func StorageSnapshotsPrefix(accountHash common.Hash) []byte {
+	return storageSnapshotsKey(accountHash)
+}
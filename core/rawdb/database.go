@@ -0,0 +1,38 @@
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+//FreezerDatabase是带冻结表的链数据库。最近的区块保存在键值数据库中，
+//不可逆的旧区块移入只追加的冻结表，本包的读取函数会透明地从两处读取。
+type FreezerDatabase struct {
+	ethdb.Store
+	*freezer
+}
+
+//NewDatabaseWithFreezer在键值数据库之上打开dir中的冻结表。
+//返回的数据库只读取冻结数据，调用Freeze之后才开始移动区块。
+func NewDatabaseWithFreezer(db ethdb.Store, dir string) (*FreezerDatabase, error) {
+	frdb, err := newFreezer(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FreezerDatabase{Store: db, freezer: frdb}, nil
+}
+
+//Freeze启动后台冻结，把高度低于limit()的规范区块移入冻结表。
+//limit应当返回不可逆区块高度减去保留的余量，只能调用一次。
+func (db *FreezerDatabase) Freeze(limit func() uint64) {
+	db.freezer.wg.Add(1)
+	go db.freezer.freeze(db.Store, limit)
+}
+
+//Close停止后台冻结，关闭冻结表和键值数据库
+func (db *FreezerDatabase) Close() {
+	if err := db.freezer.close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	db.Store.Close()
+}
@@ -0,0 +1,372 @@
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+//freezerRecheckInterval是没有可冻结的区块时再次检查的间隔
+	freezerRecheckInterval = time.Minute
+
+//freezerBatchLimit是一轮最多冻结的区块数，避免长时间占用磁盘IO
+	freezerBatchLimit = 30000
+)
+
+//冻结表的名称
+const (
+	freezerHashTable       = "hashes"   //规范区块哈希
+	freezerHeaderTable     = "headers"  //RLP编码的区块头
+	freezerBodiesTable     = "bodies"   //RLP编码的区块体
+	freezerReceiptTable    = "receipts" //RLP编码的存储格式收据
+	freezerDifficultyTable = "diffs"    //RLP编码的总难度
+)
+
+//freezerNoCompression标记不压缩的表，哈希无法压缩
+var freezerNoCompression = map[string]bool{
+	freezerHashTable:       true,
+	freezerHeaderTable:     false,
+	freezerBodiesTable:     false,
+	freezerReceiptTable:    false,
+	freezerDifficultyTable: false,
+}
+
+var errUnknownTable = errors.New("unknown table")
+
+//freezer把不可逆的规范链区块从键值数据库移到只追加的平面文件中。
+//所有表的条目都按区块高度从0开始连续编号，frozen是已经冻结的区块数。
+type freezer struct {
+	frozen uint64 //已冻结的区块数，原子访问
+
+	tables map[string]*freezerTable
+	lock   sync.Mutex //保证冻结一批区块和截断冻结表不会交错
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+//newFreezer打开或创建dir下的冻结表，各表条目数不一致时截断到最短的表
+func newFreezer(dir string) (*freezer, error) {
+	f := &freezer{
+		tables: make(map[string]*freezerTable),
+		quit:   make(chan struct{}),
+	}
+	for name, noCompression := range freezerNoCompression {
+		table, err := newFreezerTable(dir, name, noCompression)
+		if err != nil {
+			for _, table := range f.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		f.tables[name] = table
+	}
+	if err := f.repair(); err != nil {
+		f.close()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "path", dir, "frozen", f.frozen)
+	return f, nil
+}
+
+//repair把所有表截断到相同的条目数。追加区块时中途退出会让部分表多出条目
+func (f *freezer) repair() error {
+	min := uint64(math.MaxUint64)
+	for _, table := range f.tables {
+		if items := table.Items(); items < min {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+//HasAncient判断某类冻结数据中是否有给定高度的条目
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.has(number), nil
+	}
+	return false, nil
+}
+
+//Ancient读取某类冻结数据中给定高度的条目
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+//Ancients返回已冻结的区块数
+func (f *freezer) Ancients() (uint64, error) {
+	return atomic.LoadUint64(&f.frozen), nil
+}
+
+//TruncateAncients把冻结表截断到前items个区块。链回滚到已冻结的高度以下时必须调用，
+//否则冻结表仍会返回旧的规范区块；被截断的高度在新的规范区块导入后会再次冻结
+func (f *freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for name, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return fmt.Errorf("failed to truncate %s: %v", name, err)
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	log.Warn("Truncated ancient store", "items", items)
+	return nil
+}
+
+//appendAncient冻结一个区块。任何一张表写入失败时所有表回滚到之前的条目数
+func (f *freezer) appendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	if frozen := atomic.LoadUint64(&f.frozen); number != frozen {
+		return fmt.Errorf("%v: have %d, want %d", errOutOrderInsertion, number, frozen)
+	}
+	defer func() {
+		if err != nil {
+			for _, table := range f.tables {
+				if rerr := table.truncate(number); rerr != nil {
+					log.Error("Failed to roll back ancient table", "err", rerr)
+				}
+			}
+		}
+	}()
+	items := map[string][]byte{
+		freezerHashTable:       hash,
+		freezerHeaderTable:     header,
+		freezerBodiesTable:     body,
+		freezerReceiptTable:    receipts,
+		freezerDifficultyTable: td,
+	}
+	for name, blob := range items {
+		if err := f.tables[name].Append(number, blob); err != nil {
+			return fmt.Errorf("failed to append %s: %v", name, err)
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1)
+	return nil
+}
+
+//sync把所有表刷到磁盘
+func (f *freezer) sync() error {
+	for name, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+//close停止后台冻结并关闭所有表
+func (f *freezer) close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+//freeze在后台循环，把高度低于limit()的规范区块从db移入冻结表，
+//写入并刷盘之后再从db中删除这些区块以及同高度的分叉区块
+func (f *freezer) freeze(db ethdb.Database, limit func() uint64) {
+	defer f.wg.Done()
+
+	for {
+		threshold := limit()
+		if atomic.LoadUint64(&f.frozen) >= threshold {
+			select {
+			case <-time.After(freezerRecheckInterval):
+				continue
+			case <-f.quit:
+				return
+			}
+		}
+		start := time.Now()
+		frozen, ancients, err := f.freezeBatch(db, threshold)
+		if err != nil {
+			log.Error("Failed to freeze blocks", "number", frozen+uint64(len(ancients)), "err", err)
+		}
+		if len(ancients) == 0 {
+			select {
+			case <-time.After(freezerRecheckInterval):
+				continue
+			case <-f.quit:
+				return
+			}
+		}
+		log.Info("Moved blocks into ancient store", "blocks", len(ancients), "frozen", frozen+uint64(len(ancients)),
+			"elapsed", common.PrettyDuration(time.Since(start)))
+
+		select {
+		case <-f.quit:
+			return
+		default:
+		}
+	}
+}
+
+//freezeBatch冻结从已冻结高度到threshold之间的一批规范区块，刷盘后从db中删除，
+//返回这一批的起始高度和区块哈希。整个过程持有锁，不会与TruncateAncients交错
+func (f *freezer) freezeBatch(db ethdb.Database, threshold uint64) (uint64, []common.Hash, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	frozen := atomic.LoadUint64(&f.frozen)
+	if frozen >= threshold {
+		return frozen, nil, nil
+	}
+	if threshold-frozen > freezerBatchLimit {
+		threshold = frozen + freezerBatchLimit
+	}
+	ancients, err := f.freezeRange(db, frozen, threshold)
+	if len(ancients) == 0 {
+		return frozen, nil, err
+	}
+	if err := f.sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	f.cleanup(db, frozen, ancients)
+	return frozen, ancients, err
+}
+
+//freezeRange冻结[from, to)范围内的规范区块，返回已冻结区块的哈希
+func (f *freezer) freezeRange(db ethdb.Database, from, to uint64) ([]common.Hash, error) {
+	var hashes []common.Hash
+	for number := from; number < to; number++ {
+		select {
+		case <-f.quit:
+			return hashes, nil
+		default:
+		}
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return hashes, fmt.Errorf("canonical hash missing")
+		}
+		header := ReadHeaderRLP(db, hash, number)
+		if len(header) == 0 {
+			return hashes, fmt.Errorf("block header missing")
+		}
+		body := ReadBodyRLP(db, hash, number)
+		if len(body) == 0 {
+			return hashes, fmt.Errorf("block body missing")
+		}
+		receipts, _ := db.Get(blockReceiptsKey(number, hash))
+		if len(receipts) == 0 {
+			return hashes, fmt.Errorf("block receipts missing")
+		}
+		td, _ := db.Get(headerTDKey(number, hash))
+		if len(td) == 0 {
+			return hashes, fmt.Errorf("total difficulty missing")
+		}
+		if err := f.appendAncient(number, hash.Bytes(), header, body, receipts, td); err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+//cleanup从db中删除已冻结的区块。规范区块保留哈希到高度的映射，
+//交易查找仍然需要它；同高度的分叉区块全部删除。创世区块留在db中。
+//如果删除前进程退出，残留的数据不影响读取，只是多占空间。
+func (f *freezer) cleanup(db ethdb.Database, from uint64, hashes []common.Hash) {
+	batch := db.NewBatch()
+	for i, hash := range hashes {
+		number := from + uint64(i)
+		if number == 0 {
+			continue
+		}
+		for _, side := range forkHashes(db, number) {
+			if side != hash {
+				DeleteBlock(batch, side, number)
+			}
+		}
+		DeleteCanonicalHash(batch, number)
+		DeleteReceipts(batch, hash, number)
+		DeleteBody(batch, hash, number)
+		DeleteTd(batch, hash, number)
+		if err := batch.Delete(headerKey(number, hash)); err != nil {
+			log.Crit("Failed to delete frozen header", "err", err)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete frozen blocks", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete frozen blocks", "err", err)
+	}
+}
+
+//forkHashes返回db中给定高度的所有区块哈希，底层数据库不支持遍历时返回空
+func forkHashes(db ethdb.Database, number uint64) []common.Hash {
+	store, ok := db.(ethdb.Store)
+	if !ok {
+		return nil
+	}
+	prefix := append(append([]byte{}, headerPrefix...), encodeBlockNumber(number)...)
+	it := store.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	var hashes []common.Hash
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength {
+			hashes = append(hashes, common.BytesToHash(key[len(prefix):]))
+		}
+	}
+	return hashes
+}
+
+//readAncient从冻结表读取区块数据，只有该高度的规范区块哈希与hash一致时才返回
+func readAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	ancients, ok := db.(AncientReader)
+	if !ok {
+		return nil
+	}
+	if !isCanonicalAncient(db, hash, number) {
+		return nil
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+//isCanonicalAncient判断hash是否是已冻结的规范区块
+func isCanonicalAncient(db DatabaseReader, hash common.Hash, number uint64) bool {
+	ancients, ok := db.(AncientReader)
+	if !ok {
+		return false
+	}
+	canonical, _ := ancients.Ancient(freezerHashTable, number)
+	return bytes.Equal(canonical, hash.Bytes())
+}
@@ -0,0 +1,263 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/snappy"
+)
+
+const indexEntrySize = 8 //每个索引项是数据结束位置的大端uint64
+
+var (
+//errClosed在表已关闭后读写时返回
+	errClosed = errors.New("closed")
+
+//errOutOfBounds在读取尚未写入的条目时返回
+	errOutOfBounds = errors.New("out of bounds")
+
+//errOutOrderInsertion在追加的条目编号不连续时返回
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+//freezerTable是只追加的平面文件表。条目按编号连续存放在数据文件中，
+//索引文件第i项记录第i个条目在数据文件中的结束位置。
+type freezerTable struct {
+	items uint64 //已写入的条目数，原子访问
+
+	noCompression bool     //为true时不用snappy压缩，用于已经是哈希等不可压缩的数据
+	data          *os.File //条目数据文件
+	index         *os.File //条目结束位置的索引文件
+	offset        uint64   //数据文件当前的长度，即下一个条目的开始位置
+
+	lock sync.RWMutex //保护文件的读写和关闭
+	log  log.Logger
+}
+
+//newFreezerTable打开或创建dir中名为name的表，并修复异常退出导致的不一致
+func newFreezerTable(dir string, name string, noCompression bool) (*freezerTable, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ext := ".rdat"
+	if noCompression {
+		ext = ".rdat.raw"
+	}
+	data, err := os.OpenFile(filepath.Join(dir, name+ext), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, name+".ridx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	t := &freezerTable{
+		noCompression: noCompression,
+		data:          data,
+		index:         index,
+		log:           log.New("table", name),
+	}
+	if err := t.repair(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+//repair让索引和数据文件保持一致：丢弃不完整的索引项，
+//丢弃指向数据文件之外的索引项，截掉索引之外多余的数据
+func (t *freezerTable) repair() error {
+	indexStat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	dataStat, err := t.data.Stat()
+	if err != nil {
+		return err
+	}
+	indexSize := indexStat.Size() - indexStat.Size()%indexEntrySize
+	dataSize := uint64(dataStat.Size())
+
+	items := uint64(indexSize / indexEntrySize)
+	for ; items > 0; items-- {
+		end, err := t.readIndex(items - 1)
+		if err != nil {
+			return err
+		}
+		if end <= dataSize {
+			break
+		}
+	}
+	offset := uint64(0)
+	if items > 0 {
+		if offset, err = t.readIndex(items - 1); err != nil {
+			return err
+		}
+	}
+	if int64(items*indexEntrySize) != indexStat.Size() || offset != dataSize {
+		t.log.Warn("Repairing freezer table", "items", items, "data", offset)
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	t.offset = offset
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+//readIndex读取第item个条目的结束位置
+func (t *freezerTable) readIndex(item uint64) (uint64, error) {
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(item*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+//Items返回表中的条目数
+func (t *freezerTable) Items() uint64 {
+	return atomic.LoadUint64(&t.items)
+}
+
+//has判断第item个条目是否已写入
+func (t *freezerTable) has(item uint64) bool {
+	return atomic.LoadUint64(&t.items) > item
+}
+
+//Append在表尾追加第item个条目，item必须等于当前的条目数
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if items := atomic.LoadUint64(&t.items); item != items {
+		return fmt.Errorf("%v: have %d, want %d", errOutOrderInsertion, item, items)
+	}
+	if !t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	if _, err := t.data.WriteAt(blob, int64(t.offset)); err != nil {
+		return err
+	}
+	end := t.offset + uint64(len(blob))
+
+	buf := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(buf, end)
+	if _, err := t.index.WriteAt(buf, int64(item*indexEntrySize)); err != nil {
+		return err
+	}
+	t.offset = end
+	atomic.AddUint64(&t.items, 1)
+	return nil
+}
+
+//Retrieve读取第item个条目
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return nil, errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= item {
+		return nil, errOutOfBounds
+	}
+	start := uint64(0)
+	if item > 0 {
+		var err error
+		if start, err = t.readIndex(item - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.readIndex(item)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.noCompression {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
+
+//truncate丢弃第items个及之后的条目
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= items {
+		return nil
+	}
+	offset := uint64(0)
+	if items > 0 {
+		var err error
+		if offset, err = t.readIndex(items - 1); err != nil {
+			return err
+		}
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	t.offset = offset
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+//Sync把数据和索引刷到磁盘。先刷数据，保证索引不会指向未落盘的数据
+func (t *freezerTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+//Close关闭表的文件
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.data != nil {
+		if err := t.data.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.data = nil
+	}
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.index = nil
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
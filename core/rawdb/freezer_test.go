@@ -0,0 +1,209 @@
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+//测试冻结表的追加、读取，以及异常退出后的修复。
+func TestFreezerTableRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer_table_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newFreezerTable(dir, "test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if err := table.Append(i, bytes.Repeat([]byte{byte(i)}, int(i)*10)); err != nil {
+			t.Fatalf("append %d failed: %v", i, err)
+		}
+	}
+	if err := table.Append(11, []byte{1}); err == nil {
+		t.Fatal("out of order append succeeded")
+	}
+	table.Close()
+
+//模拟写入最后一个条目时退出：数据文件只写了一半
+	data := filepath.Join(dir, "test.rdat.raw")
+	stat, err := os.Stat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(data, stat.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+	if table, err = newFreezerTable(dir, "test", true); err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	if items := table.Items(); items != 9 {
+		t.Fatalf("item count mismatch after repair: have %d, want %d", items, 9)
+	}
+	for i := uint64(0); i < 9; i++ {
+		blob, err := table.Retrieve(i)
+		if err != nil {
+			t.Fatalf("retrieve %d failed: %v", i, err)
+		}
+		if !bytes.Equal(blob, bytes.Repeat([]byte{byte(i)}, int(i)*10)) {
+			t.Fatalf("item %d mismatch", i)
+		}
+	}
+	if _, err := table.Retrieve(9); err != errOutOfBounds {
+		t.Fatalf("retrieve beyond end: have %v, want %v", err, errOutOfBounds)
+	}
+}
+
+//测试冻结后的区块从键值数据库删除，并能透明地读出。
+func TestFreezerTransparentRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewDatabaseWithFreezer(kvdb, filepath.Join(dir, "ancient"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var blocks []*types.Block
+	parent := common.Hash{}
+	for i := 0; i < 10; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Extra: []byte(fmt.Sprintf("block %d", i))}
+		block := types.NewBlockWithHeader(header)
+		receipts := types.Receipts{&types.Receipt{CumulativeGasUsed: uint64(i), Logs: []*types.Log{}}}
+
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i)))
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+//高度5上的分叉区块冻结后应当被删除
+	side := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5), ParentHash: blocks[4].Hash(), Extra: []byte("side")})
+	WriteBlock(db, side)
+
+	hashes, err := db.freezeRange(kvdb, 0, 8)
+	if err != nil {
+		t.Fatalf("freeze failed: %v", err)
+	}
+	if err := db.sync(); err != nil {
+		t.Fatal(err)
+	}
+	db.cleanup(kvdb, 0, hashes)
+
+	if frozen, _ := db.Ancients(); frozen != 8 {
+		t.Fatalf("frozen count mismatch: have %d, want %d", frozen, 8)
+	}
+	if HasHeader(kvdb, blocks[5].Hash(), 5) {
+		t.Fatal("frozen header still in key-value database")
+	}
+	if HasHeader(db, side.Hash(), 5) {
+		t.Fatal("side chain header not deleted")
+	}
+	for i, block := range blocks {
+		number := uint64(i)
+		if hash := ReadCanonicalHash(db, number); hash != block.Hash() {
+			t.Fatalf("block %d: canonical hash mismatch: have %x, want %x", i, hash, block.Hash())
+		}
+		if entry := ReadBlock(db, block.Hash(), number); entry == nil || entry.Hash() != block.Hash() {
+			t.Fatalf("block %d: not readable", i)
+		}
+		if td := ReadTd(db, block.Hash(), number); td == nil || td.Int64() != int64(i) {
+			t.Fatalf("block %d: total difficulty mismatch: %v", i, td)
+		}
+		if receipts := ReadReceipts(db, block.Hash(), number); len(receipts) != 1 || receipts[0].CumulativeGasUsed != number {
+			t.Fatalf("block %d: receipts mismatch", i)
+		}
+	}
+}
+
+//测试截断冻结表之后，被截断的高度不再返回旧的规范区块，新的规范区块可以再次冻结。
+func TestFreezerTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewDatabaseWithFreezer(kvdb, filepath.Join(dir, "ancient"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	writeChain := func(from, to int, parent common.Hash, extra string) []*types.Block {
+		var blocks []*types.Block
+		for i := from; i < to; i++ {
+			header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Extra: []byte(fmt.Sprintf("%s %d", extra, i))}
+			block := types.NewBlockWithHeader(header)
+			WriteBlock(db, block)
+			WriteReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{})
+			WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i)))
+			WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+			blocks = append(blocks, block)
+			parent = block.Hash()
+		}
+		return blocks
+	}
+	blocks := writeChain(0, 8, common.Hash{}, "block")
+	if _, ancients, err := db.freezeBatch(kvdb, 8); err != nil || len(ancients) != 8 {
+		t.Fatalf("freeze failed: %d blocks, %v", len(ancients), err)
+	}
+//回滚到高度3：之后的冻结区块不再是规范区块
+	if err := db.TruncateAncients(4); err != nil {
+		t.Fatalf("truncate failed: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 4 {
+		t.Fatalf("frozen count mismatch: have %d, want %d", frozen, 4)
+	}
+	for i := 4; i < 8; i++ {
+		if hash := ReadCanonicalHash(db, uint64(i)); hash != (common.Hash{}) {
+			t.Fatalf("block %d: truncated canonical hash still readable: %x", i, hash)
+		}
+		if HasHeader(db, blocks[i].Hash(), uint64(i)) {
+			t.Fatalf("block %d: truncated header still readable", i)
+		}
+	}
+	if hash := ReadCanonicalHash(db, 3); hash != blocks[3].Hash() {
+		t.Fatalf("block 3: canonical hash mismatch: have %x, want %x", hash, blocks[3].Hash())
+	}
+//新的规范区块从截断的高度继续冻结
+	fork := writeChain(4, 8, blocks[3].Hash(), "fork")
+	if _, ancients, err := db.freezeBatch(kvdb, 8); err != nil || len(ancients) != 4 {
+		t.Fatalf("refreeze failed: %d blocks, %v", len(ancients), err)
+	}
+	for i, block := range fork {
+		number := uint64(i + 4)
+		if hash := ReadCanonicalHash(db, number); hash != block.Hash() {
+			t.Fatalf("block %d: canonical hash mismatch: have %x, want %x", number, hash, block.Hash())
+		}
+		if HasHeader(kvdb, block.Hash(), number) {
+			t.Fatalf("block %d: refrozen header still in key-value database", number)
+		}
+	}
+}
@@ -28,3 +28,21 @@ type DatabaseDeleter interface {
 	Delete(key []byte) error
 }
 
+
+//AncientReader包装冻结区块数据的读取方法，见NewDatabaseWithFreezer。
+type AncientReader interface {
+//HasAncient判断某类冻结数据中是否有给定高度的条目
+	HasAncient(kind string, number uint64) (bool, error)
+
+//Ancient读取某类冻结数据中给定高度的条目
+	Ancient(kind string, number uint64) ([]byte, error)
+
+//Ancients返回已冻结的区块数
+	Ancients() (uint64, error)
+}
+
+//AncientWriter包装冻结表的截断方法，链回滚到已冻结的高度以下时使用
+type AncientWriter interface {
+//TruncateAncients把冻结表截断到前items个区块
+	TruncateAncients(items uint64) error
+}
@@ -37,6 +37,12 @@ var (
 //FastTrieProgressKey跟踪在快速同步期间导入的Trie条目数。
 	fastTrieProgressKey = []byte("TrieSync")
 
+//snapshotRootKey跟踪磁盘上状态快照对应的状态根。
+	snapshotRootKey = []byte("SnapshotRoot")
+
+//snapshotGeneratorKey跟踪状态快照后台生成的进度。
+	snapshotGeneratorKey = []byte("SnapshotGenerator")
+
 //数据项前缀（使用单字节避免混合数据类型，避免使用“i”，用于索引）。
 headerPrefix       = []byte("h") //headerPrefix+num（uint64 big endian）+hash->header
 headerTDSuffix     = []byte("t") //headerPrefix+num（uint64 big endian）+hash+headerTsuffix->td
@@ -49,11 +55,18 @@ blockReceiptsPrefix = []byte("r") //blockReceiptsPrefix+num（uint64 big endian
 txLookupPrefix  = []byte("l") //txlookupprefix+hash->交易/收据查找元数据
 bloomBitsPrefix = []byte("B") //bloombitsprefix+bit（uint16 big endian）+section（uint64 big endian）+hash->bloom位
 
+SnapshotAccountPrefix = []byte("a") //SnapshotAccountPrefix+账户哈希->账户trie叶子的值
+SnapshotStoragePrefix = []byte("o") //SnapshotStoragePrefix+账户哈希+存储键哈希->存储trie叶子的值
+
+addressTxPrefix    = []byte("x") //addressTxPrefix+地址+num（uint64 big endian）+交易索引（uint32 big endian）->地址交易索引条目
+addressBlockPrefix = []byte("X") //addressBlockPrefix+num（uint64 big endian）->区块写入的地址交易索引键
+
 preimagePrefix = []byte("secure-key-")      //preimageprefix+hash->preimage
 configPrefix   = []byte("ethereum-config-") //数据库的配置前缀
 
 //链索引前缀（使用'i`+单字节以避免混合数据类型）。
 BloomBitsIndexPrefix = []byte("iB") //BloomBitsIndexPrefix是跟踪其进展的链表索引器的数据表。
+AddressIndexPrefix   = []byte("iA") //AddressIndexPrefix是地址交易索引器跟踪进度的数据表
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -67,6 +80,15 @@ type TxLookupEntry struct {
 	Index      uint64
 }
 
+//AddressTxEntry是地址交易索引中的一条记录，Roles是地址在交易中的角色位
+type AddressTxEntry struct {
+	BlockHash  common.Hash
+	BlockIndex uint64
+	Index      uint64
+	TxHash     common.Hash
+	Roles      uint8
+}
+
 //encodeBlockNumber将块编号编码为big endian uint64
 func encodeBlockNumber(number uint64) []byte {
 	enc := make([]byte, 8)
@@ -129,3 +151,34 @@ func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
 }
 
+//accountSnapshotKey=SnapshotAccountPrefix+哈希
+func accountSnapshotKey(hash common.Hash) []byte {
+	return append(append([]byte{}, SnapshotAccountPrefix...), hash.Bytes()...)
+}
+
+//storageSnapshotKey=SnapshotStoragePrefix+账户哈希+存储键哈希
+func storageSnapshotKey(accountHash, storageHash common.Hash) []byte {
+	return append(storageSnapshotsKey(accountHash), storageHash.Bytes()...)
+}
+
+//storageSnapshotsKey=SnapshotStoragePrefix+账户哈希，是账户全部存储快照的前缀
+func storageSnapshotsKey(accountHash common.Hash) []byte {
+	return append(append([]byte{}, SnapshotStoragePrefix...), accountHash.Bytes()...)
+}
+
+//addressTxsKey=addressTxPrefix+地址，是地址全部交易索引的前缀
+func addressTxsKey(address common.Address) []byte {
+	return append(append([]byte{}, addressTxPrefix...), address.Bytes()...)
+}
+
+//addressTxKey=addressTxPrefix+地址+num（uint64 big endian）+交易索引（uint32 big endian）
+func addressTxKey(address common.Address, number uint64, index uint32) []byte {
+	key := append(append(addressTxsKey(address), encodeBlockNumber(number)...), make([]byte, 4)...)
+	binary.BigEndian.PutUint32(key[len(key)-4:], index)
+	return key
+}
+
+//addressBlockKey=addressBlockPrefix+num（uint64 big endian）
+func addressBlockKey(number uint64) []byte {
+	return append(append([]byte{}, addressBlockPrefix...), encodeBlockNumber(number)...)
+}
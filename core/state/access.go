@@ -0,0 +1,198 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//AccessSet是一笔交易读取或写入的帐户和存储槽集合，
+//用于并行执行交易时检测冲突。
+type AccessSet struct {
+accounts map[common.Address]struct{}                 //余额、nonce、代码或存在性
+slots    map[common.Address]map[common.Hash]struct{} //存储槽
+wiped    map[common.Address]struct{}                 //整个存储被清空（重建、自杀或删除）的帐户
+}
+
+//NewAccessSet创建空的访问集合
+func NewAccessSet() *AccessSet {
+	return &AccessSet{
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+		wiped:    make(map[common.Address]struct{}),
+	}
+}
+
+func (set *AccessSet) addAccount(addr common.Address) {
+	set.accounts[addr] = struct{}{}
+}
+
+func (set *AccessSet) addSlot(addr common.Address, key common.Hash) {
+	slots := set.slots[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]struct{})
+		set.slots[addr] = slots
+	}
+	slots[key] = struct{}{}
+}
+
+//Merge把other中的帐户和存储槽并入集合
+func (set *AccessSet) Merge(other *AccessSet) {
+	for addr := range other.accounts {
+		set.accounts[addr] = struct{}{}
+	}
+	for addr, slots := range other.slots {
+		for key := range slots {
+			set.addSlot(addr, key)
+		}
+	}
+	for addr := range other.wiped {
+		set.wiped[addr] = struct{}{}
+	}
+}
+
+//Conflicts返回写入集合set是否改变了读取集合reads中的任何帐户或存储槽
+func (set *AccessSet) Conflicts(reads *AccessSet) bool {
+	for addr := range reads.accounts {
+		if _, ok := set.accounts[addr]; ok {
+			return true
+		}
+	}
+	for addr, slots := range reads.slots {
+		if _, ok := set.wiped[addr]; ok {
+			return true
+		}
+		written := set.slots[addr]
+		if written == nil {
+			continue
+		}
+		for key := range slots {
+			if _, ok := written[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//accessTracker记录交易执行期间读取的帐户和存储槽。出块者只通过AddBalance
+//收取手续费时不算读取，所有交易都给它付费也不会互相冲突。
+type accessTracker struct {
+	reads           *AccessSet
+	coinbase        common.Address
+coinbaseBalance *big.Int //开始跟踪时出块者的余额
+}
+
+//TrackAccess开始记录此状态上读取的帐户和存储槽，状态必须刚刚定稿
+func (self *StateDB) TrackAccess(coinbase common.Address) {
+	self.access = &accessTracker{
+		reads:           NewAccessSet(),
+		coinbase:        coinbase,
+		coinbaseBalance: new(big.Int).Set(self.GetBalance(coinbase)),
+	}
+}
+
+//AccessedSet返回开始跟踪后读取的帐户和存储槽，没有跟踪时返回nil
+func (self *StateDB) AccessedSet() *AccessSet {
+	if self.access == nil {
+		return nil
+	}
+	return self.access.reads
+}
+
+func (self *StateDB) trackAccount(addr common.Address) {
+	if self.access != nil {
+		self.access.reads.addAccount(addr)
+	}
+}
+
+func (self *StateDB) trackSlot(addr common.Address, key common.Hash) {
+	if self.access != nil {
+		self.access.reads.addSlot(addr, key)
+	}
+}
+
+//Mergeable返回当前交易的修改能否用MergeInto合并。拜占庭之前燃气耗尽的
+//交易可能只在日志中留下对预编译合约的触碰而没有状态对象，这种修改无法合并。
+func (self *StateDB) Mergeable() bool {
+	if self.access == nil {
+		return false
+	}
+	for addr := range self.journal.dirties {
+		if self.stateObjects[addr] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+//MergeInto把当前交易（最近一次Prepare之后、定稿之前）对状态的修改、
+//日志和预映像写入dst，效果与在dst上直接执行该交易相同。调用者必须保证
+//交易读取的内容在dst中没有改变，并且已经用同一笔交易调用过dst.Prepare。
+//返回交易写入的帐户和存储槽。调用前必须用Mergeable检查。
+func (self *StateDB) MergeInto(dst *StateDB, deleteEmptyObjects bool) *AccessSet {
+//在事务中被重建的帐户，旧的存储需要清空
+	recreated := make(map[common.Address]bool)
+	for _, entry := range self.journal.entries {
+		if ch, ok := entry.(resetObjectChange); ok {
+			recreated[ch.prev.address] = true
+		}
+	}
+	writes := NewAccessSet()
+	for addr := range self.journal.dirties {
+		obj := self.stateObjects[addr]
+		_, read := self.access.reads.accounts[addr]
+
+		if addr == self.access.coinbase && !read && !recreated[addr] {
+//出块者只收到了转账，按差额累加
+			delta := new(big.Int).Sub(obj.Balance(), self.access.coinbaseBalance)
+			dst.AddBalance(addr, delta)
+			if delta.Sign() != 0 || (deleteEmptyObjects && obj.empty()) {
+				writes.addAccount(addr)
+			}
+		} else {
+			if recreated[addr] {
+				dst.CreateAccount(addr)
+			}
+			changed := recreated[addr] || obj.suicided ||
+				dst.GetBalance(addr).Cmp(obj.Balance()) != 0 ||
+				dst.GetNonce(addr) != obj.Nonce() ||
+				!bytes.Equal(dst.GetCodeHash(addr).Bytes(), obj.CodeHash())
+
+			if !bytes.Equal(dst.GetCodeHash(addr).Bytes(), obj.CodeHash()) {
+				dst.SetCode(addr, obj.Code(self.db))
+			}
+			dst.SetBalance(addr, new(big.Int).Set(obj.Balance()))
+			dst.SetNonce(addr, obj.Nonce())
+
+			if changed || (deleteEmptyObjects && obj.empty()) {
+				writes.addAccount(addr)
+			}
+		}
+		for key, value := range obj.dirtyStorage {
+			dst.SetState(addr, key, value)
+			writes.addSlot(addr, key)
+		}
+		if obj.suicided {
+			dst.Suicide(addr)
+		}
+		if recreated[addr] || obj.suicided || (deleteEmptyObjects && obj.empty()) {
+			writes.wiped[addr] = struct{}{}
+		}
+	}
+	for _, log := range self.logs[self.thash] {
+		cpy := &types.Log{
+			Address:     log.Address,
+			Topics:      log.Topics,
+			Data:        log.Data,
+			BlockNumber: log.BlockNumber,
+		}
+		dst.AddLog(cpy)
+	}
+	for hash, preimage := range self.preimages {
+		dst.AddPreimage(hash, preimage)
+	}
+	return writes
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//accessList是当前交易已经访问过（“热”）的帐户和存储槽。第一次访问某个帐户或
+//存储槽时按冷访问收费，之后按热访问收费。修改记录在日志中，回滚时一起撤消。
+type accessList struct {
+	addresses map[common.Address]map[common.Hash]struct{}
+}
+
+func newAccessList() *accessList {
+	return &accessList{
+		addresses: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+func (al *accessList) containsAddress(addr common.Address) bool {
+	_, ok := al.addresses[addr]
+	return ok
+}
+
+//contains返回帐户和存储槽是否已在列表中
+func (al *accessList) contains(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
+	slots, ok := al.addresses[addr]
+	if !ok {
+		return false, false
+	}
+	_, slotOk = slots[slot]
+	return true, slotOk
+}
+
+//addAddress加入帐户，返回列表是否因此改变
+func (al *accessList) addAddress(addr common.Address) bool {
+	if _, ok := al.addresses[addr]; ok {
+		return false
+	}
+	al.addresses[addr] = make(map[common.Hash]struct{})
+	return true
+}
+
+//addSlot加入存储槽（不存在时连同帐户一起加入），返回帐户和存储槽是否因此改变
+func (al *accessList) addSlot(addr common.Address, slot common.Hash) (addrChange bool, slotChange bool) {
+	slots, ok := al.addresses[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		al.addresses[addr] = slots
+		addrChange = true
+	}
+	if _, ok := slots[slot]; ok {
+		return addrChange, false
+	}
+	slots[slot] = struct{}{}
+	return addrChange, true
+}
+
+func (al *accessList) deleteAddress(addr common.Address) {
+	delete(al.addresses, addr)
+}
+
+func (al *accessList) deleteSlot(addr common.Address, slot common.Hash) {
+	delete(al.addresses[addr], slot)
+}
+
+func (al *accessList) copy() *accessList {
+	cpy := newAccessList()
+	for addr, slots := range al.addresses {
+		slotsCpy := make(map[common.Hash]struct{}, len(slots))
+		for slot := range slots {
+			slotsCpy[slot] = struct{}{}
+		}
+		cpy.addresses[addr] = slotsCpy
+	}
+	return cpy
+}
+
+//PrepareAccessList在访问列表分叉之后、执行交易之前调用：清空上一笔交易的访问列表，
+//把发送者、接收者、预编译合约以及交易自带的访问列表加入其中。
+func (self *StateDB) PrepareAccessList(sender common.Address, dst *common.Address, precompiles []common.Address, list types.AccessList) {
+	self.accessList = newAccessList()
+	self.accessList.addAddress(sender)
+	if dst != nil {
+		self.accessList.addAddress(*dst)
+	}
+	for _, addr := range precompiles {
+		self.accessList.addAddress(addr)
+	}
+	for _, tuple := range list {
+		self.accessList.addAddress(tuple.Address)
+		for _, key := range tuple.StorageKeys {
+			self.accessList.addSlot(tuple.Address, key)
+		}
+	}
+}
+
+//AddressInAccessList返回帐户是否已被当前交易访问过
+func (self *StateDB) AddressInAccessList(addr common.Address) bool {
+	return self.accessList.containsAddress(addr)
+}
+
+//SlotInAccessList返回帐户以及其存储槽是否已被当前交易访问过
+func (self *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
+	return self.accessList.contains(addr, slot)
+}
+
+//AddAddressToAccessList把帐户加入访问列表
+func (self *StateDB) AddAddressToAccessList(addr common.Address) {
+	if self.accessList.addAddress(addr) {
+		self.journal.append(accessListAddAccountChange{&addr})
+	}
+}
+
+//AddSlotToAccessList把存储槽加入访问列表，帐户不在列表中时一起加入
+func (self *StateDB) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	addrMod, slotMod := self.accessList.addSlot(addr, slot)
+	if addrMod {
+//先记录加入帐户，回滚时先删除存储槽再删除帐户
+		self.journal.append(accessListAddAccountChange{&addr})
+	}
+	if slotMod {
+		self.journal.append(accessListAddSlotChange{
+			address: &addr,
+			slot:    &slot,
+		})
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/trie"
 	lru "github.com/hashicorp/golang-lru"
@@ -32,6 +33,10 @@ const (
 
 //要保留的codehash->大小关联数。
 	codeSizeCacheSize = 100000
+
+//内存中保留的快照差异层数。更早的差异压平到磁盘层，磁盘层的状态根
+//必须仍能从trie读取（用于后台生成），因此要小于区块链在内存中保留的trie数。
+	snapshotLayers = 64
 )
 
 //数据库将访问权限包装为“尝试”和“合同代码”。
@@ -79,11 +84,23 @@ func NewDatabase(db ethdb.Database) Database {
 	}
 }
 
+//NewDatabaseWithSnapshots和NewDatabase一样创建状态的后备存储，同时打开root的
+//平面状态快照，从它打开的状态优先从快照读取账户和存储，提交时更新快照。
+//快照需要遍历数据库，db不支持遍历时不使用快照。
+func NewDatabaseWithSnapshots(db ethdb.Database, root common.Hash) Database {
+	sdb := NewDatabase(db).(*cachingDB)
+	if store, ok := db.(ethdb.Store); ok {
+		sdb.snaps = snapshot.New(store, sdb.db, root)
+	}
+	return sdb
+}
+
 type cachingDB struct {
 	db            *trie.Database
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
+	snaps         *snapshot.Tree //平面状态快照，未启用时为nil
 }
 
 //opentrie打开主帐户trie。
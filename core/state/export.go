@@ -0,0 +1,321 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//emptyRoot是空trie的根哈希
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+var errNoDposContext = errors.New("missing dpos context")
+
+//storageChunkSize是导出流中每块存储最多包含的条目数，
+//导出和导入时内存中最多只有一块存储，与账户的存储大小无关
+var storageChunkSize = 1024
+
+//导出流的格式：先是DPoS上下文的五棵trie（epoch、delegate、candidate、vote、mintCnt），
+//每棵trie一个exportEntry列表；之后每个账户一个exportAccount，紧跟着该账户的存储，
+//分成若干个不超过storageChunkSize的exportSlot列表，以空列表结束，直到流结束。
+//账户和存储以哈希为键，原像已知时一并导出，导入后Dump仍能显示地址。
+
+//exportEntry是DPoS trie中的一个键值对
+type exportEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+//exportSlot是账户存储中的一个条目
+type exportSlot struct {
+	Hash  common.Hash //存储键的哈希
+	Key   []byte      //存储键，原像未知时为空
+	Value []byte      //RLP编码的值，与存储trie的叶子相同
+}
+
+//exportAccount是导出流中的一个账户，Root和CodeHash用于导入时校验
+type exportAccount struct {
+	Hash     common.Hash //地址的哈希
+	Address  []byte      //地址，原像未知时为空
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+	Code     []byte
+}
+
+//ExportState把root对应的状态以及dpos对应的DPoS上下文以RLP流写入w
+func ExportState(db ethdb.Database, root common.Hash, dpos *types.DposContextProto, w io.Writer) error {
+	if dpos == nil {
+		return errNoDposContext
+	}
+	triedb := trie.NewDatabase(db)
+
+	opens := []struct {
+		root common.Hash
+		open func(common.Hash, *trie.Database) (*trie.Trie, error)
+	}{
+		{dpos.EpochHash, types.NewEpochTrie},
+		{dpos.DelegateHash, types.NewDelegateTrie},
+		{dpos.CandidateHash, types.NewCandidateTrie},
+		{dpos.VoteHash, types.NewVoteTrie},
+		{dpos.MintCntHash, types.NewMintCntTrie},
+	}
+	for _, o := range opens {
+		t, err := o.open(o.root, triedb)
+		if err != nil {
+			return err
+		}
+		entries := []exportEntry{}
+		it := trie.NewIterator(t.NodeIterator(nil))
+		for it.Next() {
+			entries = append(entries, exportEntry{Key: common.CopyBytes(it.Key), Value: common.CopyBytes(it.Value)})
+		}
+		if it.Err != nil {
+			return it.Err
+		}
+		if err := rlp.Encode(w, entries); err != nil {
+			return err
+		}
+	}
+	accTrie, err := trie.New(root, triedb)
+	if err != nil {
+		return err
+	}
+	var (
+		count  = 0
+		start  = time.Now()
+		logged = time.Now()
+	)
+	it := trie.NewIterator(accTrie.NodeIterator(nil))
+	for it.Next() {
+		var data Account
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return fmt.Errorf("invalid account %x: %v", it.Key, err)
+		}
+		acc := exportAccount{
+			Hash:     common.BytesToHash(it.Key),
+			Address:  rawdb.ReadPreimage(db, common.BytesToHash(it.Key)),
+			Nonce:    data.Nonce,
+			Balance:  data.Balance,
+			Root:     data.Root,
+			CodeHash: data.CodeHash,
+		}
+		if codeHash := common.BytesToHash(data.CodeHash); codeHash != emptyCode {
+			if acc.Code, err = triedb.Node(codeHash); err != nil {
+				return fmt.Errorf("code of account %x: %v", acc.Hash, err)
+			}
+		}
+		if err := rlp.Encode(w, &acc); err != nil {
+			return err
+		}
+		if err := exportStorage(db, triedb, data.Root, w); err != nil {
+			return fmt.Errorf("storage of account %x: %v", acc.Hash, err)
+		}
+		count++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state", "accounts", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+	log.Info("Exported state", "root", root, "accounts", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+//exportStorage把root对应的账户存储分块写入w，最后写入一个空列表表示结束
+func exportStorage(db ethdb.Database, triedb *trie.Database, root common.Hash, w io.Writer) error {
+	chunk := make([]exportSlot, 0, storageChunkSize)
+	if root != emptyRoot && root != (common.Hash{}) {
+		storeTrie, err := trie.New(root, triedb)
+		if err != nil {
+			return err
+		}
+		it := trie.NewIterator(storeTrie.NodeIterator(nil))
+		for it.Next() {
+			hash := common.BytesToHash(it.Key)
+			chunk = append(chunk, exportSlot{
+				Hash:  hash,
+				Key:   rawdb.ReadPreimage(db, hash),
+				Value: common.CopyBytes(it.Value),
+			})
+			if len(chunk) == storageChunkSize {
+				if err := rlp.Encode(w, chunk); err != nil {
+					return err
+				}
+				chunk = chunk[:0]
+			}
+		}
+		if it.Err != nil {
+			return it.Err
+		}
+	}
+	if len(chunk) > 0 {
+		if err := rlp.Encode(w, chunk); err != nil {
+			return err
+		}
+	}
+	return rlp.Encode(w, []exportSlot{})
+}
+
+//ImportState从RLP流中读取ExportState导出的状态并写入db，返回重建的状态根和DPoS上下文，
+//调用者应当与导出时的区块头比较。每个账户的存储根和代码哈希在导入时校验。
+func ImportState(db ethdb.Database, stream *rlp.Stream) (common.Hash, *types.DposContextProto, error) {
+	triedb := trie.NewDatabase(db)
+
+	dposContext, err := types.NewDposContext(triedb)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	for _, t := range []*trie.Trie{
+		dposContext.EpochTrie(),
+		dposContext.DelegateTrie(),
+		dposContext.CandidateTrie(),
+		dposContext.VoteTrie(),
+		dposContext.MintCntTrie(),
+	} {
+		var entries []exportEntry
+		if err := stream.Decode(&entries); err != nil {
+			return common.Hash{}, nil, fmt.Errorf("dpos context: %v", err)
+		}
+		for _, entry := range entries {
+			if err := t.TryUpdate(entry.Key, entry.Value); err != nil {
+				return common.Hash{}, nil, err
+			}
+		}
+	}
+	dpos, err := dposContext.Commit()
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	accTrie, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	var (
+		preimages = make(map[common.Hash][]byte)
+		count     = 0
+		start     = time.Now()
+		logged    = time.Now()
+	)
+	for {
+		var acc exportAccount
+		if err := stream.Decode(&acc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return common.Hash{}, nil, fmt.Errorf("account %d: %v", count, err)
+		}
+		if err := importAccount(db, triedb, accTrie, &acc, stream, preimages); err != nil {
+			return common.Hash{}, nil, fmt.Errorf("account %x: %v", acc.Hash, err)
+		}
+		flushPreimages(db, preimages)
+		count++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state", "accounts", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	rawdb.WritePreimages(db, 0, preimages)
+
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return common.Hash{}, nil, err
+	}
+	log.Info("Imported state", "root", root, "accounts", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return root, dpos, nil
+}
+
+//flushPreimages在积累的原像较多时写入磁盘并清空
+func flushPreimages(db ethdb.Database, preimages map[common.Hash][]byte) {
+	if len(preimages) > 1024 {
+		rawdb.WritePreimages(db, 0, preimages)
+		for hash := range preimages {
+			delete(preimages, hash)
+		}
+	}
+}
+
+//importAccount从流中逐块读取账户的存储，重建存储trie和代码并写入磁盘，再把账户加入账户trie
+func importAccount(db ethdb.Database, triedb *trie.Database, accTrie *trie.Trie, acc *exportAccount, stream *rlp.Stream, preimages map[common.Hash][]byte) error {
+	if len(acc.Address) > 0 {
+		preimages[acc.Hash] = acc.Address
+	}
+	storeTrie, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return err
+	}
+	slots := 0
+	for {
+		var chunk []exportSlot
+		if err := stream.Decode(&chunk); err != nil {
+			return fmt.Errorf("storage: %v", err)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		for _, slot := range chunk {
+			if err := storeTrie.TryUpdate(slot.Hash[:], slot.Value); err != nil {
+				return err
+			}
+			if len(slot.Key) > 0 {
+				preimages[slot.Hash] = slot.Key
+			}
+		}
+		slots += len(chunk)
+		flushPreimages(db, preimages)
+	}
+	if slots > 0 {
+		root, err := storeTrie.Commit(nil)
+		if err != nil {
+			return err
+		}
+		if root != acc.Root {
+			return fmt.Errorf("storage root mismatch: have %x, want %x", root, acc.Root)
+		}
+		if err := triedb.Commit(root, false); err != nil {
+			return err
+		}
+	} else if acc.Root != emptyRoot && acc.Root != (common.Hash{}) {
+		return fmt.Errorf("storage missing for root %x", acc.Root)
+	}
+	if len(acc.Code) > 0 {
+		hash := crypto.Keccak256Hash(acc.Code)
+		if !bytes.Equal(hash[:], acc.CodeHash) {
+			return fmt.Errorf("code hash mismatch: have %x, want %x", hash, acc.CodeHash)
+		}
+		triedb.InsertBlob(hash, acc.Code)
+		if err := triedb.Commit(hash, false); err != nil {
+			return err
+		}
+	} else if common.BytesToHash(acc.CodeHash) != emptyCode {
+		return fmt.Errorf("code missing for hash %x", acc.CodeHash)
+	}
+	data, err := rlp.EncodeToBytes(&Account{
+		Nonce:    acc.Nonce,
+		Balance:  acc.Balance,
+		Root:     acc.Root,
+		CodeHash: acc.CodeHash,
+	})
+	if err != nil {
+		return err
+	}
+	return accTrie.TryUpdate(acc.Hash[:], data)
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+//测试导出的状态和DPoS上下文导入到空数据库后得到相同的根
+func TestExportImportState(t *testing.T) {
+//存储较多的账户分成多块导出
+	defer func(size int) { storageChunkSize = size }(storageChunkSize)
+	storageChunkSize = 4
+
+	srcDb := ethdb.NewMemDatabase()
+	db := NewDatabase(srcDb)
+	state, _ := New(common.Hash{}, db)
+
+	for i := byte(0); i < 32; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.AddBalance(addr, big.NewInt(int64(i)+1))
+		state.SetNonce(addr, uint64(i))
+		if i%3 == 0 {
+			state.SetCode(addr, []byte{i, i, i})
+		}
+		if i%4 == 0 {
+			state.SetState(addr, common.BytesToHash([]byte{i}), common.BytesToHash([]byte{i, i}))
+		}
+	}
+	large := common.BytesToAddress([]byte{0xff})
+	for i := byte(1); i <= 10; i++ {
+		state.SetState(large, common.BytesToHash([]byte{i}), common.BytesToHash([]byte{i}))
+	}
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	dposContext, err := types.NewDposContext(db.TrieDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dposContext.BecomeCandidate(common.BytesToAddress([]byte{1})); err != nil {
+		t.Fatal(err)
+	}
+	dpos, err := dposContext.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := ExportState(srcDb, root, dpos, buf); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	dstDb := ethdb.NewMemDatabase()
+	imported, importedDpos, err := ImportState(dstDb, rlp.NewStream(buf, 0))
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if imported != root {
+		t.Fatalf("state root mismatch: have %x, want %x", imported, root)
+	}
+	if *importedDpos != *dpos {
+		t.Fatalf("dpos context mismatch: have %v, want %v", importedDpos, dpos)
+	}
+	if err := checkStateConsistency(dstDb, root); err != nil {
+		t.Fatalf("inconsistent imported state: %v", err)
+	}
+}
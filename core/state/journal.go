@@ -86,7 +86,8 @@ type (
 		account *common.Address
 	}
 	resetObjectChange struct {
-		prev *stateObject
+		prev         *stateObject
+prevdestruct bool //快照中旧账户此前是否已经标记为删除
 	}
 	suicideChange struct {
 		account     *common.Address
@@ -127,6 +128,15 @@ prev        bool //账户是否已经自杀
 		prev      bool
 		prevDirty bool
 	}
+
+//访问列表的更改。
+	accessListAddAccountChange struct {
+		address *common.Address
+	}
+	accessListAddSlotChange struct {
+		address *common.Address
+		slot    *common.Hash
+	}
 )
 
 func (ch createObjectChange) revert(s *StateDB) {
@@ -140,6 +150,9 @@ func (ch createObjectChange) dirtied() *common.Address {
 
 func (ch resetObjectChange) revert(s *StateDB) {
 	s.setStateObject(ch.prev)
+	if !ch.prevdestruct && s.snap != nil {
+		delete(s.snapDestructs, ch.prev.addrHash)
+	}
 }
 
 func (ch resetObjectChange) dirtied() *common.Address {
@@ -229,3 +242,18 @@ func (ch addPreimageChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch accessListAddAccountChange) revert(s *StateDB) {
+	s.accessList.deleteAddress(*ch.address)
+}
+
+func (ch accessListAddAccountChange) dirtied() *common.Address {
+	return nil
+}
+
+func (ch accessListAddSlotChange) revert(s *StateDB) {
+	s.accessList.deleteSlot(*ch.address, *ch.slot)
+}
+
+func (ch accessListAddSlotChange) dirtied() *common.Address {
+	return nil
+}
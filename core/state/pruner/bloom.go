@@ -0,0 +1,59 @@
+package pruner
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//stateBloomHashes是每个键使用的哈希函数个数
+const stateBloomHashes = 4
+
+//stateBloom是记录需要保留的状态键的布隆过滤器。trie节点和合约代码的键
+//本身就是keccak256哈希，分布均匀，因此直接取键的不同片段作为哈希函数。
+//误判只会让少量垃圾留在数据库中，不会删掉需要的数据。
+type stateBloom struct {
+	bits  []uint64
+	nbits uint64
+}
+
+//newStateBloom创建占用size MB内存的布隆过滤器
+func newStateBloom(size uint64) *stateBloom {
+	if size == 0 {
+		size = 1
+	}
+	words := size * 1024 * 1024 / 8
+	return &stateBloom{
+		bits:  make([]uint64, words),
+		nbits: words * 64,
+	}
+}
+
+//add记录一个需要保留的键
+func (b *stateBloom) add(key []byte) {
+	for i := 0; i < stateBloomHashes; i++ {
+		bit := b.index(key, i)
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+//contains判断键是否可能需要保留，返回false时键一定没有被记录
+func (b *stateBloom) contains(key []byte) bool {
+	for i := 0; i < stateBloomHashes; i++ {
+		bit := b.index(key, i)
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//index返回第i个哈希函数对应的位
+func (b *stateBloom) index(key []byte, i int) uint64 {
+	return binary.BigEndian.Uint64(key[i*8:]) % b.nbits
+}
+
+//isStateKey判断键是否可能是trie节点或合约代码，只有这类键会被清理
+func isStateKey(key []byte) bool {
+	return len(key) == common.HashLength
+}
@@ -0,0 +1,205 @@
+//Package pruner实现离线的状态裁剪：只保留指定区块可达的状态，删除其余的trie节点。
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//progressInterval是输出进度日志的间隔
+const progressInterval = 8 * time.Second
+
+var errNoDposContext = errors.New("header has no dpos context")
+
+//Pruner删除数据库中指定区块不可达的状态数据，包括账户trie、存储trie、
+//合约代码以及DPoS的五棵trie。裁剪必须在节点停止时进行。
+type Pruner struct {
+	db    ethdb.Store
+	bloom *stateBloom
+}
+
+//NewPruner创建裁剪器，bloomSize是布隆过滤器占用的内存（MB）。
+//过滤器越大误判越少，留下的垃圾也越少。
+func NewPruner(db ethdb.Store, bloomSize uint64) *Pruner {
+	return &Pruner{
+		db:    db,
+		bloom: newStateBloom(bloomSize),
+	}
+}
+
+//Prune只保留header对应的状态。先标记所有可达的节点，确认目标状态完整后
+//把链头回退到目标区块并作废磁盘上的状态快照，然后才开始删除，
+//中途退出不会损坏目标状态，重新运行即可继续清理。
+func (p *Pruner) Prune(header *types.Header) error {
+	start := time.Now()
+	if err := p.markState(header.Root); err != nil {
+		return fmt.Errorf("state %x: %v", header.Root, err)
+	}
+	if err := p.markDposContext(header); err != nil {
+		return fmt.Errorf("dpos context of block %d: %v", header.Number, err)
+	}
+	log.Info("Marked reachable state", "number", header.Number, "root", header.Root, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if err := p.setHead(header); err != nil {
+		return err
+	}
+	if err := p.sweep(); err != nil {
+		return err
+	}
+	log.Info("Compacting database")
+	cstart := time.Now()
+	if err := p.db.Compact(nil, nil); err != nil {
+		return err
+	}
+	log.Info("Pruned state", "elapsed", common.PrettyDuration(time.Since(start)), "compaction", common.PrettyDuration(time.Since(cstart)))
+	return nil
+}
+
+//setHead把头区块、头区块头和快速同步头设为目标区块，并删除目标之上的规范哈希。
+//目标之上区块的状态会被删除，不回退的话节点下次启动时会停在没有状态的头区块上。
+//磁盘上的状态快照不是目标状态时删除快照根，下次启动时从目标状态重新生成快照。
+func (p *Pruner) setHead(header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+		height = number
+	)
+	for _, head := range []common.Hash{rawdb.ReadHeadHeaderHash(p.db), rawdb.ReadHeadBlockHash(p.db), rawdb.ReadHeadFastBlockHash(p.db)} {
+		if n := rawdb.ReadHeaderNumber(p.db, head); n != nil && *n > height {
+			height = *n
+		}
+	}
+	batch := p.db.NewBatch()
+	for n := height; n > number; n-- {
+		rawdb.DeleteCanonicalHash(batch, n)
+	}
+	rawdb.WriteHeadHeaderHash(batch, hash)
+	rawdb.WriteHeadBlockHash(batch, hash)
+	rawdb.WriteHeadFastBlockHash(batch, hash)
+
+	if root := rawdb.ReadSnapshotRoot(p.db); root != (common.Hash{}) && root != header.Root {
+		rawdb.DeleteSnapshotRoot(batch)
+		rawdb.DeleteSnapshotGenerator(batch)
+		log.Info("Dropped stale state snapshot", "root", root)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if height > number {
+		log.Warn("Rewound chain head to pruning target", "number", number, "hash", hash, "dropped", height-number)
+	}
+	return nil
+}
+
+//markState把root可达的账户trie节点、存储trie节点和合约代码加入过滤器
+func (p *Pruner) markState(root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(p.db))
+	if err != nil {
+		return err
+	}
+	var (
+		it     = state.NewNodeIterator(statedb)
+		count  = 0
+		logged = time.Now()
+	)
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			p.bloom.add(it.Hash.Bytes())
+			count++
+		}
+		if time.Since(logged) > progressInterval {
+			log.Info("Marking state", "nodes", count)
+			logged = time.Now()
+		}
+	}
+	return it.Error
+}
+
+//markDposContext把区块DPoS上下文的五棵trie的节点加入过滤器
+func (p *Pruner) markDposContext(header *types.Header) error {
+	if header.DposContext == nil {
+		return errNoDposContext
+	}
+	var (
+		triedb = trie.NewDatabase(p.db)
+		ctx    = header.DposContext
+	)
+	opens := []struct {
+		root common.Hash
+		open func(common.Hash, *trie.Database) (*trie.Trie, error)
+	}{
+		{ctx.EpochHash, types.NewEpochTrie},
+		{ctx.DelegateHash, types.NewDelegateTrie},
+		{ctx.CandidateHash, types.NewCandidateTrie},
+		{ctx.VoteHash, types.NewVoteTrie},
+		{ctx.MintCntHash, types.NewMintCntTrie},
+	}
+	for _, o := range opens {
+		t, err := o.open(o.root, triedb)
+		if err != nil {
+			return err
+		}
+		it := t.NodeIterator(nil)
+		for it.Next(true) {
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				p.bloom.add(hash.Bytes())
+			}
+		}
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//sweep删除所有不在过滤器中的状态键
+func (p *Pruner) sweep() error {
+	var (
+		it      = p.db.NewIteratorWithPrefix(nil)
+		batch   = p.db.NewBatch()
+		start   = time.Now()
+		logged  = time.Now()
+		deleted = 0
+		size    common.StorageSize
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if !isStateKey(key) || p.bloom.contains(key) {
+			continue
+		}
+		size += common.StorageSize(len(key) + len(it.Value()))
+		if err := batch.Delete(common.CopyBytes(key)); err != nil {
+			return err
+		}
+		deleted++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > progressInterval {
+			log.Info("Pruning state data", "nodes", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned stale state", "nodes", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,180 @@
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//commitState在parent之上修改几个账户并写入数据库，返回新的状态根
+func commitState(t *testing.T, sdb state.Database, parent common.Hash, round byte) common.Hash {
+	statedb, err := state.New(parent, sdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(0); i < 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.AddBalance(addr, big.NewInt(int64(round)+1))
+		statedb.SetState(addr, common.Hash{i}, common.Hash{round + 1})
+	}
+	statedb.SetCode(common.BytesToAddress([]byte{round, 0xff}), []byte{round, 0x60, 0x00})
+
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+//测试裁剪后目标区块的状态和DPoS上下文完整，旧状态被删除
+func TestPruneState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pruner_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sdb := state.NewDatabase(db)
+	stale := commitState(t, sdb, common.Hash{}, 0)
+	root := commitState(t, sdb, stale, 1)
+
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dposContext.BecomeCandidate(common.BytesToAddress([]byte{1})); err != nil {
+		t.Fatal(err)
+	}
+	if err := dposContext.Delegate(common.BytesToAddress([]byte{2}), common.BytesToAddress([]byte{1})); err != nil {
+		t.Fatal(err)
+	}
+	proto, err := dposContext.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+//非状态数据不能被删除
+	db.Put([]byte("unrelated-key"), []byte{1})
+
+	header := &types.Header{Number: big.NewInt(1), Root: root, DposContext: proto}
+	if err := NewPruner(db, 1).Prune(header); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if has, _ := db.Has(stale.Bytes()); has {
+		t.Fatal("stale state root not pruned")
+	}
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("pruned state not accessible: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("pruned state incomplete: %v", it.Error)
+	}
+	triedb := trie.NewDatabase(db)
+	for _, root := range []common.Hash{proto.CandidateHash, proto.DelegateHash, proto.VoteHash} {
+		tr, err := trie.New(root, triedb)
+		if err != nil {
+			t.Fatalf("dpos trie %x not accessible: %v", root, err)
+		}
+		nodes := tr.NodeIterator(nil)
+		for nodes.Next(true) {
+		}
+		if err := nodes.Error(); err != nil {
+			t.Fatalf("dpos trie %x incomplete: %v", root, err)
+		}
+	}
+	if has, _ := db.Has([]byte("unrelated-key")); !has {
+		t.Fatal("non-state data pruned")
+	}
+}
+
+//测试裁剪目标低于链头时，链头回退到目标区块，目标之上的规范哈希被删除，
+//描述其它状态根的快照被作废
+func TestPruneRewindsHead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pruner_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sdb := state.NewDatabase(db)
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto, err := dposContext.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		headers []*types.Header
+		root    common.Hash
+		parent  common.Hash
+	)
+	for i := 0; i < 4; i++ {
+		root = commitState(t, sdb, root, byte(i))
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Root: root, DposContext: proto}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	head := headers[3].Hash()
+	rawdb.WriteHeadHeaderHash(db, head)
+	rawdb.WriteHeadBlockHash(db, head)
+	rawdb.WriteHeadFastBlockHash(db, head)
+	rawdb.WriteSnapshotRoot(db, headers[3].Root)
+
+	target := headers[1]
+	if err := NewPruner(db, 1).Prune(target); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if hash := rawdb.ReadHeadBlockHash(db); hash != target.Hash() {
+		t.Errorf("head block mismatch: have %x, want %x", hash, target.Hash())
+	}
+	if hash := rawdb.ReadHeadHeaderHash(db); hash != target.Hash() {
+		t.Errorf("head header mismatch: have %x, want %x", hash, target.Hash())
+	}
+	if hash := rawdb.ReadHeadFastBlockHash(db); hash != target.Hash() {
+		t.Errorf("head fast block mismatch: have %x, want %x", hash, target.Hash())
+	}
+	for i := 2; i < 4; i++ {
+		if hash := rawdb.ReadCanonicalHash(db, uint64(i)); hash != (common.Hash{}) {
+			t.Errorf("canonical hash %d above target not deleted: %x", i, hash)
+		}
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 1); hash != target.Hash() {
+		t.Errorf("target canonical hash mismatch: have %x, want %x", hash, target.Hash())
+	}
+	if root := rawdb.ReadSnapshotRoot(db); root != (common.Hash{}) {
+		t.Errorf("stale snapshot root kept: %x", root)
+	}
+	if _, err := state.New(target.Root, state.NewDatabase(db)); err != nil {
+		t.Errorf("target state not accessible: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//diffLayer是一个区块在父层之上做的状态修改，只存在于内存中。
+//查找时先查本层，本层没有修改过的条目再交给父层。
+type diffLayer struct {
+	parent snapshot    //下一层，压平时会被替换为新的磁盘层
+	root   common.Hash //本层对应的状态根
+	stale  bool        //本层已经被压平或丢弃
+
+	destructs map[common.Hash]struct{}               //被删除的账户，其旧存储全部失效
+	accounts  map[common.Hash][]byte                 //修改后的账户，nil表示删除
+	storage   map[common.Hash]map[common.Hash][]byte //修改后的存储，nil表示删除
+
+	lock sync.RWMutex
+}
+
+//newDiffLayer创建parent之上的差异层
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return &diffLayer{
+		parent:    parent,
+		root:      root,
+		destructs: destructs,
+		accounts:  accounts,
+		storage:   storage,
+	}
+}
+
+//Root返回本层对应的状态根
+func (dl *diffLayer) Root() common.Hash {
+	return dl.root
+}
+
+//Parent返回下一层
+func (dl *diffLayer) Parent() snapshot {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+//Stale判断本层是否已经失效
+func (dl *diffLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+//markStale把本层标记为失效
+func (dl *diffLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+//Account返回账户哈希对应的RLP编码账户
+func (dl *diffLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if data, ok := dl.accounts[hash]; ok {
+		dl.lock.RUnlock()
+		if len(data) == 0 {
+			return nil, nil
+		}
+		return data, nil
+	}
+	if _, ok := dl.destructs[hash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Account(hash)
+}
+
+//Storage返回账户中存储键哈希对应的RLP编码值
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if storage, ok := dl.storage[accountHash]; ok {
+		if data, ok := storage[storageHash]; ok {
+			dl.lock.RUnlock()
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		}
+	}
+	if _, ok := dl.destructs[accountHash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Storage(accountHash, storageHash)
+}
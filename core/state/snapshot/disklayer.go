@@ -0,0 +1,196 @@
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//diskLayer是持久化在数据库中的快照，是所有差异层的最底层。
+//后台生成期间只有不超过genMarker的键可以读取。
+type diskLayer struct {
+	db     ethdb.Store
+	triedb *trie.Database //生成快照时读取状态trie
+	root   common.Hash    //快照对应的状态根
+	stale  bool           //已经被更新的磁盘层取代
+
+	genMarker  []byte             //生成进度，nil表示已经完成，空表示尚未开始
+	genAbort   chan chan struct{} //通知生成过程暂停
+	genPending chan struct{}      //生成过程退出时关闭
+
+	lock sync.RWMutex
+}
+
+//Root返回磁盘层对应的状态根
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+//Parent返回nil，磁盘层是最底层
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+//Stale判断磁盘层是否已经失效
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+//Account返回账户哈希对应的RLP编码账户
+func (dl *diskLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !covered(dl.genMarker, hash[:]) {
+		return nil, ErrNotCoveredYet
+	}
+	data := rawdb.ReadAccountSnapshot(dl.db, hash)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+//Storage返回账户中存储键哈希对应的RLP编码值
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !covered(dl.genMarker, append(accountHash.Bytes(), storageHash[:]...)) {
+		return nil, ErrNotCoveredYet
+	}
+	data := rawdb.ReadStorageSnapshot(dl.db, accountHash, storageHash)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+//flatten把差异层（从上到下排列）依次写入数据库，返回新的磁盘层并让当前层失效。
+//生成尚未覆盖的键直接跳过，新磁盘层会从同一位置继续用新的状态根生成它们。
+func (dl *diskLayer) flatten(diffs []*diffLayer) *diskLayer {
+//先暂停生成，避免生成过程和这里同时写同一批键
+	abort := make(chan struct{})
+	select {
+	case dl.genAbort <- abort:
+		<-abort
+	case <-dl.genPending:
+	}
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	var (
+		marker = dl.genMarker
+		batch  = dl.db.NewBatch()
+	)
+	flush := func(force bool) {
+		if force || batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write state snapshot", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	for i := len(diffs) - 1; i >= 0; i-- {
+		diff := diffs[i]
+		for hash := range diff.destructs {
+			if !covered(marker, hash[:]) {
+				continue
+			}
+//删除存储要遍历数据库，更早的差异层写入的存储必须先落盘
+			flush(true)
+			rawdb.DeleteAccountSnapshot(batch, hash)
+			wipeStorage(dl.db, batch, hash)
+			flush(false)
+		}
+		for hash, data := range diff.accounts {
+			if !covered(marker, hash[:]) {
+				continue
+			}
+			if len(data) == 0 {
+				rawdb.DeleteAccountSnapshot(batch, hash)
+			} else {
+				rawdb.WriteAccountSnapshot(batch, hash, data)
+			}
+			flush(false)
+		}
+		for accountHash, storage := range diff.storage {
+			for storageHash, data := range storage {
+				if !covered(marker, append(accountHash.Bytes(), storageHash[:]...)) {
+					continue
+				}
+				if len(data) == 0 {
+					rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+				} else {
+					rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+				}
+			}
+			flush(false)
+		}
+	}
+	root := diffs[0].root
+	rawdb.WriteSnapshotRoot(batch, root)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state snapshot", "err", err)
+	}
+	dl.stale = true
+
+	disk := &diskLayer{
+		db:         dl.db,
+		triedb:     dl.triedb,
+		root:       root,
+		genMarker:  marker,
+		genAbort:   make(chan chan struct{}),
+		genPending: make(chan struct{}),
+	}
+	if marker != nil {
+		go disk.generate()
+	} else {
+		close(disk.genPending)
+	}
+	return disk
+}
+
+//wipeStorage删除账户的全部存储快照
+func wipeStorage(db ethdb.Store, batch ethdb.Batch, accountHash common.Hash) {
+	prefix := rawdb.StorageSnapshotsPrefix(accountHash)
+	it := db.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength {
+			batch.Delete(common.CopyBytes(key))
+		}
+	}
+}
+
+//covered判断生成进度为marker时key是否已经生成。marker为账户哈希时
+//该账户和它的全部存储都已生成；为账户哈希加存储键哈希时，该账户本身
+//以及不超过该存储键的存储已经生成。
+func covered(marker, key []byte) bool {
+	if marker == nil {
+		return true
+	}
+	if len(marker) == 0 {
+		return false
+	}
+	n := len(key)
+	if len(marker) < n {
+		n = len(marker)
+	}
+	return bytes.Compare(key[:n], marker[:n]) <= 0
+}
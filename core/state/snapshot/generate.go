@@ -0,0 +1,189 @@
+package snapshot
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//emptyRoot是空trie的根哈希
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+//account是账户trie叶子的格式，生成快照时用来找到账户的存储trie
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+//generate在后台从磁盘层的状态trie生成快照，从genMarker处继续。
+//genMarker为空时先清除数据库中残留的旧快照。收到genAbort时保存进度并退出，
+//读取trie失败时保留进度，等待下一次压平用更新的状态根重新开始。
+func (dl *diskLayer) generate() {
+	defer close(dl.genPending)
+
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	var (
+		batch  = dl.db.NewBatch()
+		start  = time.Now()
+		logged = time.Now()
+		count  = 0
+	)
+//flush把批次和进度一起写入数据库
+	flush := func(marker []byte) {
+		rawdb.WriteSnapshotGenerator(batch, marker)
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write state snapshot", "err", err)
+		}
+		batch.Reset()
+
+		dl.lock.Lock()
+		dl.genMarker = marker
+		dl.lock.Unlock()
+	}
+//aborted检查是否需要暂停，需要时保存进度
+	aborted := func(marker []byte) bool {
+		select {
+		case abort := <-dl.genAbort:
+			flush(marker)
+			close(abort)
+			return true
+		default:
+			return false
+		}
+	}
+//fail在出错时保留已经保存的进度，等待暂停通知
+	fail := func(err error) {
+		log.Warn("State snapshot generation stalled", "root", dl.root, "err", err)
+		abort := <-dl.genAbort
+		close(abort)
+	}
+	if len(marker) == 0 {
+		if err := wipeSnapshot(dl.db); err != nil {
+			fail(err)
+			return
+		}
+		if aborted(marker) {
+			return
+		}
+	}
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		fail(err)
+		return
+	}
+	var accMarker []byte
+	if len(marker) > 0 {
+		accMarker = marker[:common.HashLength]
+	}
+	it := trie.NewIterator(accTrie.NodeIterator(accMarker))
+	for it.Next() {
+		accountHash := common.BytesToHash(it.Key)
+		rawdb.WriteAccountSnapshot(batch, accountHash, it.Value)
+		count++
+
+		var acc account
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			fail(fmt.Errorf("invalid account %x: %v", accountHash, err))
+			return
+		}
+		if acc.Root != emptyRoot {
+			storeTrie, err := trie.New(acc.Root, dl.triedb)
+			if err != nil {
+				fail(err)
+				return
+			}
+			var storeMarker []byte
+			if len(marker) == 2*common.HashLength && accountHash == common.BytesToHash(accMarker) {
+				storeMarker = marker[common.HashLength:]
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(storeMarker))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				if batch.ValueSize() >= ethdb.IdealBatchSize {
+					progress := append(common.CopyBytes(accountHash[:]), storeIt.Key...)
+					flush(progress)
+					if aborted(progress) {
+						return
+					}
+				}
+			}
+			if storeIt.Err != nil {
+				fail(storeIt.Err)
+				return
+			}
+		}
+		progress := common.CopyBytes(accountHash[:])
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			flush(progress)
+		}
+		if aborted(progress) {
+			return
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Generating state snapshot", "root", dl.root, "accounts", count, "at", accountHash, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Err != nil {
+		fail(it.Err)
+		return
+	}
+	rawdb.DeleteSnapshotGenerator(batch)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state snapshot", "err", err)
+	}
+	dl.lock.Lock()
+	dl.genMarker = nil
+	dl.lock.Unlock()
+
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", count, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+//wipeSnapshot删除数据库中全部账户和存储快照。快照键与trie节点共享前缀空间，
+//只删除长度符合的键。
+func wipeSnapshot(db ethdb.Store) error {
+	for _, wipe := range []struct {
+		prefix []byte
+		keylen int
+	}{
+		{rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix) + common.HashLength},
+		{rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength},
+	} {
+		it := db.NewIteratorWithPrefix(wipe.prefix)
+		batch := db.NewBatch()
+		for it.Next() {
+			if key := it.Key(); len(key) == wipe.keylen {
+				batch.Delete(common.CopyBytes(key))
+			}
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
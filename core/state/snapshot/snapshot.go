@@ -0,0 +1,182 @@
+//Package snapshot实现账户和存储的平面快照，作为状态trie之上的读取加速结构。
+//
+//快照由一个磁盘层和若干内存中的差异层组成。磁盘层把某个状态根下的
+//全部账户和存储以哈希键直接存放在数据库中，每个区块的状态修改作为
+//一个差异层叠在父区块的层之上。读取只需按层查找，不必逐级遍历trie。
+//差异层超过一定数量时，最底层的差异被压平写入磁盘层。
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+//ErrSnapshotStale在读取已经被压平或丢弃的层时返回，调用者应当改为读取trie
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+//ErrNotCoveredYet在磁盘层还没有生成到所读的键时返回，调用者应当改为读取trie
+	ErrNotCoveredYet = errors.New("not covered yet")
+)
+
+//Snapshot是某个状态根对应的账户和存储的平面视图。
+//返回的数据与trie叶子的值相同，不存在的条目返回nil。
+type Snapshot interface {
+//Root返回快照对应的状态根
+	Root() common.Hash
+
+//Account返回账户哈希对应的RLP编码账户
+	Account(hash common.Hash) ([]byte, error)
+
+//Storage返回账户中存储键哈希对应的RLP编码值
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+//snapshot是树内部使用的层，比Snapshot多了层之间的关系
+type snapshot interface {
+	Snapshot
+
+//Parent返回下一层，磁盘层返回nil
+	Parent() snapshot
+
+//Stale判断该层是否已经被压平或丢弃
+	Stale() bool
+}
+
+//Tree管理一个磁盘层和叠在其上的所有差异层，按状态根索引。
+//同一个父层上可以有多个子层，对应链上的分叉。
+type Tree struct {
+	diskdb ethdb.Store
+	triedb *trie.Database
+	layers map[common.Hash]snapshot
+	lock   sync.RWMutex
+}
+
+//New打开数据库中的快照。如果磁盘上的快照不是root的，或者还没生成完成，
+//就在后台从root的状态trie（重新）生成；生成期间未覆盖的键读取时返回ErrNotCoveredYet。
+func New(diskdb ethdb.Store, triedb *trie.Database, root common.Hash) *Tree {
+	base := &diskLayer{
+		db:         diskdb,
+		triedb:     triedb,
+		root:       root,
+		genAbort:   make(chan chan struct{}),
+		genPending: make(chan struct{}),
+	}
+	marker, generating := rawdb.ReadSnapshotGenerator(diskdb)
+	switch {
+	case rawdb.ReadSnapshotRoot(diskdb) != root:
+		log.Info("Regenerating state snapshot", "root", root)
+		marker = []byte{}
+		rawdb.WriteSnapshotRoot(diskdb, root)
+		rawdb.WriteSnapshotGenerator(diskdb, marker)
+	case generating:
+		log.Info("Resuming state snapshot generation", "root", root, "at", fmt.Sprintf("%x", marker))
+		if marker == nil {
+			marker = []byte{}
+		}
+	default:
+		log.Info("Loaded state snapshot", "root", root)
+		marker = nil
+	}
+	base.genMarker = marker
+	if marker != nil {
+		go base.generate()
+	} else {
+		close(base.genPending)
+	}
+	return &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		layers: map[common.Hash]snapshot{root: base},
+	}
+}
+
+//Snapshot返回root对应的快照，没有时返回nil
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if snap, ok := t.layers[root]; ok {
+		return snap
+	}
+	return nil
+}
+
+//Update在parent的快照之上加一个差异层。destructs是被删除的账户，
+//accounts和storage是修改后的值，nil表示删除。
+func (t *Tree) Update(root, parent common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	if root == parent {
+		return errors.New("snapshot cycle")
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[root]; ok {
+		return nil
+	}
+	base, ok := t.layers[parent]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parent)
+	}
+	t.layers[root] = newDiffLayer(base, root, destructs, accounts, storage)
+	return nil
+}
+
+//Cap让root之下最多保留layers个差异层，更早的差异层压平写入磁盘层。
+//不是从新磁盘层派生的层（已经不可能成为规范链的分叉）全部丢弃。
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	var chain []*diffLayer
+	for snap != nil {
+		diff, ok := snap.(*diffLayer)
+		if !ok {
+			break
+		}
+		chain = append(chain, diff)
+		snap = diff.Parent()
+	}
+	if len(chain) <= layers {
+		return nil
+	}
+	base := snap.(*diskLayer)
+	disk := base.flatten(chain[layers:])
+	if layers > 0 {
+		chain[layers-1].lock.Lock()
+		chain[layers-1].parent = disk
+		chain[layers-1].lock.Unlock()
+	}
+	for _, diff := range chain[layers:] {
+		diff.markStale()
+	}
+//只保留落在新磁盘层之上的层
+	layerset := map[common.Hash]snapshot{disk.root: disk}
+	for hash, snap := range t.layers {
+		if hash == disk.root {
+			continue
+		}
+		bottom := snap
+		for bottom.Parent() != nil {
+			bottom = bottom.Parent()
+		}
+		if bottom == snapshot(disk) {
+			layerset[hash] = snap
+		} else if diff, ok := snap.(*diffLayer); ok {
+			diff.markStale()
+		}
+	}
+	t.layers = layerset
+	return nil
+}
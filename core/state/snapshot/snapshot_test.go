@@ -0,0 +1,190 @@
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func newTestDatabase(t *testing.T) (*ethdb.LDBDatabase, func()) {
+	dir, err := ioutil.TempDir("", "snapshot_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+//makeState在triedb中生成若干账户，偶数账户带存储，返回状态根以及账户和存储的叶子值
+func makeState(t *testing.T, triedb *trie.Database) (common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	accounts := make(map[common.Hash][]byte)
+	storage := make(map[common.Hash]map[common.Hash][]byte)
+
+	for i := byte(0); i < 20; i++ {
+		hash := crypto.Keccak256Hash([]byte{i})
+		acc := account{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: emptyRoot, CodeHash: crypto.Keccak256(nil)}
+		if i%2 == 0 {
+			storeTrie, _ := trie.New(common.Hash{}, triedb)
+			storage[hash] = make(map[common.Hash][]byte)
+			for j := byte(1); j < 10; j++ {
+				key := crypto.Keccak256Hash([]byte{i, j})
+				val, _ := rlp.EncodeToBytes([]byte{j})
+				storeTrie.Update(key[:], val)
+				storage[hash][key] = val
+			}
+			root, err := storeTrie.Commit(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			acc.Root = root
+		}
+		enc, _ := rlp.EncodeToBytes(acc)
+		accTrie.Update(hash[:], enc)
+		accounts[hash] = enc
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return root, accounts, storage
+}
+
+//测试从状态trie生成的快照与trie的叶子一致
+func TestGenerate(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+
+	triedb := trie.NewDatabase(db)
+	root, accounts, storage := makeState(t, triedb)
+
+	snaps := New(db, triedb, root)
+	disk := snaps.layers[root].(*diskLayer)
+	<-disk.genPending
+
+	if _, generating := rawdb.ReadSnapshotGenerator(db); generating {
+		t.Fatal("generator progress not cleared")
+	}
+	snap := snaps.Snapshot(root)
+	for hash, enc := range accounts {
+		data, err := snap.Account(hash)
+		if err != nil || !bytes.Equal(data, enc) {
+			t.Fatalf("account %x mismatch: have %x, %v, want %x", hash, data, err, enc)
+		}
+		for key, val := range storage[hash] {
+			data, err := snap.Storage(hash, key)
+			if err != nil || !bytes.Equal(data, val) {
+				t.Fatalf("storage %x/%x mismatch: have %x, %v, want %x", hash, key, data, err, val)
+			}
+		}
+	}
+	if data, err := snap.Account(common.Hash{0xff}); data != nil || err != nil {
+		t.Fatalf("missing account: have %x, %v", data, err)
+	}
+//重新打开同一个状态根时直接使用磁盘上的快照
+	snaps = New(db, triedb, root)
+	if marker := snaps.layers[root].(*diskLayer).genMarker; marker != nil {
+		t.Fatalf("complete snapshot regenerated from %x", marker)
+	}
+}
+
+//测试差异层的查找、删除账户以及压平到磁盘层
+func TestDiffLayers(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+
+	triedb := trie.NewDatabase(db)
+	base, accounts, storage := makeState(t, triedb)
+	snaps := New(db, triedb, base)
+	<-snaps.layers[base].(*diskLayer).genPending
+
+	var (
+		destructed common.Hash //删除了的带存储的账户
+		modified   common.Hash //修改了一个存储的账户
+		slot       common.Hash
+	)
+	for hash := range storage {
+		if destructed == (common.Hash{}) {
+			destructed = hash
+			continue
+		}
+		modified = hash
+		for key := range storage[hash] {
+			slot = key
+			break
+		}
+		break
+	}
+	created := common.Hash{0x01}
+
+	root1, root2, fork := common.Hash{0xa1}, common.Hash{0xa2}, common.Hash{0xb1}
+	if err := snaps.Update(root1, base, map[common.Hash]struct{}{destructed: {}}, map[common.Hash][]byte{created: {0x01}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Update(root2, root1, nil, nil, map[common.Hash]map[common.Hash][]byte{modified: {slot: {0x02}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Update(fork, base, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := snaps.Update(common.Hash{0xc1}, common.Hash{0xff}, nil, nil, nil); err == nil {
+		t.Fatal("update with unknown parent succeeded")
+	}
+	check := func(snap Snapshot) {
+		if data, err := snap.Account(destructed); data != nil || err != nil {
+			t.Fatalf("destructed account: have %x, %v", data, err)
+		}
+		for key := range storage[destructed] {
+			if data, err := snap.Storage(destructed, key); data != nil || err != nil {
+				t.Fatalf("destructed storage: have %x, %v", data, err)
+			}
+		}
+		if data, _ := snap.Account(created); !bytes.Equal(data, []byte{0x01}) {
+			t.Fatalf("created account: have %x", data)
+		}
+		if data, _ := snap.Storage(modified, slot); !bytes.Equal(data, []byte{0x02}) {
+			t.Fatalf("modified storage: have %x", data)
+		}
+		if data, _ := snap.Account(modified); !bytes.Equal(data, accounts[modified]) {
+			t.Fatalf("untouched account: have %x, want %x", data, accounts[modified])
+		}
+	}
+	check(snaps.Snapshot(root2))
+	if data, _ := snaps.Snapshot(fork).Account(destructed); !bytes.Equal(data, accounts[destructed]) {
+		t.Fatalf("fork sees sibling changes: have %x", data)
+	}
+//压平root2之下的全部差异层，分叉被丢弃
+	stale := snaps.Snapshot(root1)
+	if err := snaps.Cap(root2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale.Account(created); err != ErrSnapshotStale {
+		t.Fatalf("flattened layer: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if snaps.Snapshot(fork) != nil {
+		t.Fatal("fork not dropped")
+	}
+	if root := rawdb.ReadSnapshotRoot(db); root != root2 {
+		t.Fatalf("disk root mismatch: have %x, want %x", root, root2)
+	}
+	check(snaps.Snapshot(root2))
+}
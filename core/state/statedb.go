@@ -20,6 +20,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
@@ -49,6 +50,15 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+//平面状态快照。snap是打开时状态根的快照，提交时把本次的修改作为新的差异层
+//叠在它上面。snapDestructs、snapAccounts和snapStorage记录修改，键是地址和存储键的哈希。
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapRoot      common.Hash
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
 //此映射包含“活动”对象，在处理状态转换时将对其进行修改。
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -70,12 +80,18 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+//当前交易已访问的帐户和存储槽
+	accessList *accessList
+
 //国家修改杂志。这是
 //快照并还原为快照。
 	journal        *journal
 	validRevisions []revision
 	nextRevisionId int
 
+//并行执行交易时记录读取的帐户和存储槽，为nil时不记录
+	access *accessTracker
+
 	lock sync.Mutex
 }
 
@@ -85,7 +101,7 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                db,
 		trie:              tr,
 		stateObjects:      make(map[common.Address]*stateObject),
@@ -93,7 +109,26 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
 		journal:           newJournal(),
-	}, nil
+		accessList:        newAccessList(),
+	}
+	sdb.openSnapshot(root)
+	return sdb, nil
+}
+
+//openSnapshot在后备存储启用了快照并且root有快照时改为从快照读取，
+//并清空记录的修改
+func (self *StateDB) openSnapshot(root common.Hash) {
+	self.snapRoot = root
+	self.snap, self.snapDestructs, self.snapAccounts, self.snapStorage = nil, nil, nil, nil
+
+	if cdb, ok := self.db.(*cachingDB); ok && cdb.snaps != nil {
+		self.snaps = cdb.snaps
+		if self.snap = cdb.snaps.Snapshot(root); self.snap != nil {
+			self.snapDestructs = make(map[common.Hash]struct{})
+			self.snapAccounts = make(map[common.Hash][]byte)
+			self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+		}
+	}
 }
 
 //setError记住调用它时使用的第一个非零错误。
@@ -123,6 +158,8 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
 	self.preimages = make(map[common.Hash][]byte)
+	self.accessList = newAccessList()
+	self.openSnapshot(root)
 	self.clearJournalAndRefund()
 	return nil
 }
@@ -173,18 +210,21 @@ func (self *StateDB) AddRefund(gas uint64) {
 //exist报告给定帐户地址是否存在于状态中。
 //值得注意的是，对于自杀账户，这也会返回真值。
 func (self *StateDB) Exist(addr common.Address) bool {
+	self.trackAccount(addr)
 	return self.getStateObject(addr) != nil
 }
 
 //空返回状态对象是否不存在
 //或根据EIP161规范为空（余额=nonce=代码=0）
 func (self *StateDB) Empty(addr common.Address) bool {
+	self.trackAccount(addr)
 	so := self.getStateObject(addr)
 	return so == nil || so.empty()
 }
 
 //从给定地址检索余额，如果找不到对象，则检索0
 func (self *StateDB) GetBalance(addr common.Address) *big.Int {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Balance()
@@ -193,6 +233,7 @@ func (self *StateDB) GetBalance(addr common.Address) *big.Int {
 }
 
 func (self *StateDB) GetNonce(addr common.Address) uint64 {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Nonce()
@@ -202,6 +243,7 @@ func (self *StateDB) GetNonce(addr common.Address) uint64 {
 }
 
 func (self *StateDB) GetCode(addr common.Address) []byte {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Code(self.db)
@@ -210,6 +252,7 @@ func (self *StateDB) GetCode(addr common.Address) []byte {
 }
 
 func (self *StateDB) GetCodeSize(addr common.Address) int {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject == nil {
 		return 0
@@ -225,6 +268,7 @@ func (self *StateDB) GetCodeSize(addr common.Address) int {
 }
 
 func (self *StateDB) GetCodeHash(addr common.Address) common.Hash {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject == nil {
 		return common.Hash{}
@@ -233,6 +277,7 @@ func (self *StateDB) GetCodeHash(addr common.Address) common.Hash {
 }
 
 func (self *StateDB) GetState(addr common.Address, bhash common.Hash) common.Hash {
+	self.trackSlot(addr, bhash)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(self.db, bhash)
@@ -257,6 +302,7 @@ func (self *StateDB) StorageTrie(addr common.Address) Trie {
 }
 
 func (self *StateDB) HasSuicided(addr common.Address) bool {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.suicided
@@ -271,6 +317,9 @@ func (self *StateDB) HasSuicided(addr common.Address) bool {
 
 //addbalance将金额添加到与addr关联的帐户。
 func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	if self.access == nil || addr != self.access.coinbase {
+		self.trackAccount(addr)
+	}
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
@@ -279,6 +328,7 @@ func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
 
 //子余额从与addr关联的帐户中减去金额。
 func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
+	self.trackAccount(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SubBalance(amount)
@@ -286,6 +336,7 @@ func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 }
 
 func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
+	self.trackAccount(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetBalance(amount)
@@ -293,6 +344,7 @@ func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
 }
 
 func (self *StateDB) SetNonce(addr common.Address, nonce uint64) {
+	self.trackAccount(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetNonce(nonce)
@@ -300,6 +352,7 @@ func (self *StateDB) SetNonce(addr common.Address, nonce uint64) {
 }
 
 func (self *StateDB) SetCode(addr common.Address, code []byte) {
+	self.trackAccount(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetCode(crypto.Keccak256Hash(code), code)
@@ -307,6 +360,7 @@ func (self *StateDB) SetCode(addr common.Address, code []byte) {
 }
 
 func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
+	self.trackSlot(addr, key)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetState(self.db, key, value)
@@ -319,6 +373,7 @@ func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
 //在提交状态之前，帐户的状态对象仍然可用，
 //GetStateObject将在自杀后返回非零帐户。
 func (self *StateDB) Suicide(addr common.Address) bool {
+	self.trackAccount(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject == nil {
 		return false
@@ -346,6 +401,10 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	if self.snap != nil {
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 //DeleteStateObject从状态trie中删除给定的对象。
@@ -353,6 +412,12 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	if self.snap != nil {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapAccounts, stateObject.addrHash)
+		delete(self.snapStorage, stateObject.addrHash)
+	}
 }
 
 //检索由地址给定的状态对象。如果未找到，则返回nil。
@@ -365,8 +430,17 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-//从数据库加载对象。
-	enc, err := self.trie.TryGet(addr[:])
+//从数据库加载对象，有快照时优先读快照，快照不可用时退回trie。
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.Account(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -399,12 +473,21 @@ func (self *StateDB) GetOrNewStateObject(addr common.Address) *stateObject {
 //给定的地址，将被覆盖并作为第二个返回值返回。
 func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObject) {
 	prev = self.getStateObject(addr)
+
+//覆盖已有账户时旧的存储在快照中也要失效
+	var prevdestruct bool
+	if self.snap != nil && prev != nil {
+		_, prevdestruct = self.snapDestructs[prev.addrHash]
+		if !prevdestruct {
+			self.snapDestructs[prev.addrHash] = struct{}{}
+		}
+	}
 	newobj = newObject(self, addr, Account{})
 newobj.setNonce(0) //将对象设置为脏
 	if prev == nil {
 		self.journal.append(createObjectChange{account: &addr})
 	} else {
-		self.journal.append(resetObjectChange{prev: prev})
+		self.journal.append(resetObjectChange{prev: prev, prevdestruct: prevdestruct})
 	}
 	self.setStateObject(newobj)
 	return newobj, prev
@@ -421,6 +504,7 @@ newobj.setNonce(0) //将对象设置为脏
 //
 //保持平衡可确保乙醚不会消失。
 func (self *StateDB) CreateAccount(addr common.Address) {
+	self.trackAccount(addr)
 	new, prev := self.createObject(addr)
 	if prev != nil {
 		new.setBalance(prev.data.Balance)
@@ -465,6 +549,28 @@ func (self *StateDB) Copy() *StateDB {
 		logSize:           self.logSize,
 		preimages:         make(map[common.Hash][]byte),
 		journal:           newJournal(),
+		accessList:        self.accessList.copy(),
+		snaps:             self.snaps,
+		snap:              self.snap,
+		snapRoot:          self.snapRoot,
+	}
+	if self.snap != nil {
+		state.snapDestructs = make(map[common.Hash]struct{}, len(self.snapDestructs))
+		for hash := range self.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(self.snapAccounts))
+		for hash, data := range self.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(self.snapStorage))
+		for hash, storage := range self.snapStorage {
+			cpy := make(map[common.Hash][]byte, len(storage))
+			for key, data := range storage {
+				cpy[key] = data
+			}
+			state.snapStorage[hash] = cpy
+		}
 	}
 //复制脏状态、日志和预映像
 	for addr := range self.journal.dirties {
@@ -621,6 +727,21 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		return nil
 	})
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
-	return root, err
+	if err != nil {
+		return root, err
+	}
+//把本次的修改作为新的差异层加入快照，并把过多的差异层压平到磁盘
+	if s.snap != nil && s.snapRoot != root {
+		if err := s.snaps.Update(root, s.snapRoot, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+			log.Warn("Failed to update state snapshot", "from", s.snapRoot, "to", root, "err", err)
+		}
+		if err := s.snaps.Cap(root, snapshotLayers); err != nil {
+			log.Warn("Failed to cap state snapshot", "root", root, "layers", snapshotLayers, "err", err)
+		}
+	}
+	if s.snaps != nil {
+		s.openSnapshot(root)
+	}
+	return root, nil
 }
 
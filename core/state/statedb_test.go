@@ -432,3 +432,108 @@ func TestCopyOfCopy(t *testing.T) {
 	}
 }
 
+
+//测试在副本上执行的修改合并后与直接执行的结果相同，并能检测读写冲突
+func TestMergeInto(t *testing.T) {
+	var (
+		coinbase = common.BytesToAddress([]byte("coinbase"))
+		from     = common.BytesToAddress([]byte("from"))
+		to       = common.BytesToAddress([]byte("to"))
+		contract = common.BytesToAddress([]byte("contract"))
+		key      = common.BytesToHash([]byte("key"))
+		value    = common.BytesToHash([]byte("value"))
+	)
+	newState := func() *StateDB {
+		state, _ := New(common.Hash{}, NewDatabase(ethdb.NewMemDatabase()))
+		state.SetBalance(from, big.NewInt(100))
+		state.SetBalance(coinbase, big.NewInt(5))
+		state.Finalise(true)
+		return state
+	}
+//两笔交易：转账和写存储，都给出块者付费
+	transfer := func(state *StateDB) {
+		state.SubBalance(from, big.NewInt(12))
+		state.AddBalance(to, big.NewInt(10))
+		state.AddBalance(coinbase, big.NewInt(2))
+	}
+	store := func(state *StateDB) {
+		state.GetState(contract, key)
+		state.SetState(contract, key, value)
+		state.AddBalance(coinbase, big.NewInt(3))
+	}
+	serial := newState()
+	serial.Prepare(common.Hash{1}, common.Hash{}, 0)
+	transfer(serial)
+	serial.Finalise(true)
+	serial.Prepare(common.Hash{2}, common.Hash{}, 1)
+	store(serial)
+	serial.Finalise(true)
+
+	merged := newState()
+	base := merged.Copy()
+	written := NewAccessSet()
+	for i, exec := range []func(*StateDB){transfer, store} {
+		spec := base.Copy()
+		spec.Prepare(common.Hash{byte(i + 1)}, common.Hash{}, i)
+		spec.TrackAccess(coinbase)
+		exec(spec)
+
+		if written.Conflicts(spec.AccessedSet()) {
+			t.Fatalf("tx %d: unexpected conflict", i)
+		}
+		if !spec.Mergeable() {
+			t.Fatalf("tx %d: not mergeable", i)
+		}
+		merged.Prepare(common.Hash{byte(i + 1)}, common.Hash{}, i)
+		written.Merge(spec.MergeInto(merged, true))
+		merged.Finalise(true)
+	}
+	if have, want := merged.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+		t.Fatalf("root mismatch: have %x, want %x", have, want)
+	}
+//读取了已被修改的帐户时应检测到冲突
+	spec := base.Copy()
+	spec.TrackAccess(coinbase)
+	spec.GetBalance(to)
+	if !written.Conflicts(spec.AccessedSet()) {
+		t.Fatalf("conflict on %x not detected", to)
+	}
+}
+
+func TestAccessListRevert(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(ethdb.NewMemDatabase()))
+	var (
+		sender = common.Address{1}
+		addr   = common.Address{2}
+		other  = common.Address{3}
+		slot   = common.Hash{1}
+	)
+	state.PrepareAccessList(sender, &addr, nil, types.AccessList{{Address: other, StorageKeys: []common.Hash{slot}}})
+	if !state.AddressInAccessList(sender) || !state.AddressInAccessList(addr) {
+		t.Fatal("sender and recipient should be in the access list")
+	}
+	if addrOk, slotOk := state.SlotInAccessList(other, slot); !addrOk || !slotOk {
+		t.Fatal("declared slot should be in the access list")
+	}
+//快照之后加入的项在回滚时撤消，之前的项保留
+	id := state.Snapshot()
+	state.AddSlotToAccessList(common.Address{4}, slot)
+	state.AddSlotToAccessList(addr, slot)
+	state.RevertToSnapshot(id)
+
+	if state.AddressInAccessList(common.Address{4}) {
+		t.Error("address added after snapshot should be reverted")
+	}
+	if _, slotOk := state.SlotInAccessList(addr, slot); slotOk {
+		t.Error("slot added after snapshot should be reverted")
+	}
+	if !state.AddressInAccessList(addr) {
+		t.Error("address added before snapshot should be kept")
+	}
+//副本有独立的访问列表
+	cpy := state.Copy()
+	cpy.AddAddressToAccessList(common.Address{5})
+	if state.AddressInAccessList(common.Address{5}) {
+		t.Error("copy should not share the access list")
+	}
+}
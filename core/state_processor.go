@@ -13,6 +13,8 @@
 package core
 
 import (
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
@@ -28,9 +30,10 @@ import (
 //
 //StateProcessor实现处理器。
 type StateProcessor struct {
-config *params.ChainConfig //链配置选项
-bc     *BlockChain         //规范区块链
-engine consensus.Engine    //集体奖励的共识引擎
+config   *params.ChainConfig //链配置选项
+bc       *BlockChain         //规范区块链
+engine   consensus.Engine    //集体奖励的共识引擎
+parallel int                 //并行执行交易的线程数，小于2时顺序执行
 }
 
 //NewStateProcessor初始化新的StateProcessor。
@@ -42,6 +45,11 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 	}
 }
 
+//SetParallel设置验证区块时乐观并行执行交易的线程数，小于2时顺序执行
+func (p *StateProcessor) SetParallel(workers int) {
+	p.parallel = workers
+}
+
 //进程通过运行来根据以太坊规则处理状态更改
 //事务消息使用statedb并对两者应用任何奖励
 //处理器（coinbase）和任何包括的叔叔。
@@ -61,6 +69,10 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
 	}
+//跟踪执行时不能并发
+	if p.parallel > 1 && !cfg.Debug && len(block.Transactions()) > 1 {
+		return p.processParallel(block, statedb, cfg)
+	}
 //设置块DPOS上下文
 //迭代并处理单个事务
 	for i, tx := range block.Transactions() {
@@ -73,7 +85,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs = append(allLogs, receipt.Logs...)
 	}
 //完成区块，应用任何共识引擎特定的额外项目（例如区块奖励）
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts, block.DposCtx())
+	if _, err := p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts, block.DposCtx()); err != nil {
+		return nil, nil, 0, err
+	}
 
 	return receipts, allLogs, *usedGas, nil
 }
@@ -125,6 +139,9 @@ func applyTransaction（config*params.chainconfig，bc chainContext，author*com
 */
 
 func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error) {
+	if err := checkEnvelope(config, header.Number, tx); err != nil {
+		return nil, 0, err
+	}
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
 	if err != nil {
 		return nil, 0, err
@@ -136,6 +153,7 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 
 //创建要在EVM环境中使用的新上下文
 	context := NewEVMContext(msg, header, bc, author)
+	context.DposContext = dposContext
 //创建一个保存所有相关信息的新环境
 //关于事务和调用机制。
 	vmenv := vm.NewEVM(context, statedb, config, cfg)
@@ -145,7 +163,7 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 		return nil, 0, err
 	}
 	if msg.Type() != types.Binary {
-		if err = applyDposMessage(dposContext, msg); err != nil {
+		if err = ApplyDposMessage(dposContext, msg); err != nil {
 			return nil, 0, err
 		}
 	}
@@ -175,7 +193,8 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 	return receipt, gas, err
 }
 //更新包会执行所有的块内交易，如果发现交易类型不是转帐或合同调剂类型，将新的用户信息写入到候选人数据库中（候选人）
-func applyDposMessage(dposContext *types.DposContext, msg types.Message) error {
+//ApplyDposMessage导出给在ApplyTransaction之外重放交易的调用者（例如交易跟踪）
+func ApplyDposMessage(dposContext *types.DposContext, msg types.Message) error {
 	switch msg.Type() {
 	case types.RegCandidate:
 		dposContext.BecomeCandidate(msg.From())
@@ -191,4 +210,14 @@ func applyDposMessage(dposContext *types.DposContext, msg types.Message) error {
 	return nil
 }
 
-
+//checkEnvelope检查交易编码是否符合区块的规则：交易信封分叉之前只能使用旧编码，
+//之后只能使用传统RLP列表或带类型的信封；访问列表分叉之前不接受访问列表交易
+func checkEnvelope(config *params.ChainConfig, number *big.Int, tx *types.Transaction) error {
+	if tx.IsPreEnvelope() == config.IsTxEnvelope(number) {
+		return types.ErrTxTypeNotSupported
+	}
+	if tx.EnvelopeType() == types.AccessListTxType && !config.IsAccessList(number) {
+		return types.ErrTxTypeNotSupported
+	}
+	return nil
+}
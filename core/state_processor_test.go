@@ -0,0 +1,149 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+//stakingCaller返回把调用数据转发给质押系统合约的合约代码，revert为true时转发后回滚
+func stakingCaller(revert bool) []byte {
+	code := []byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATACOPY),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH20),
+	}
+	code = append(code, vm.DposStakingAddress.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP))
+	if revert {
+		return append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT))
+	}
+	return append(code, byte(vm.STOP))
+}
+
+//测试通过ApplyTransaction执行的交易经合约调用质押系统合约时，交易回滚会撤销
+//对DPoS状态的修改，交易成功时修改保留在区块的DPoS上下文中。
+func TestApplyTransactionStakingRevert(t *testing.T) {
+	var (
+		db        = ethdb.NewMemDatabase()
+		key, _    = crypto.GenerateKey()
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		candidate = common.Address{0xc1}
+		reverter  = common.Address{0xb1}
+		forwarder = common.Address{0xb2}
+		coinbase  = common.Address{0xcb}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetBalance(sender, big.NewInt(1000000000000000000))
+	statedb.SetCode(reverter, stakingCaller(true))
+	statedb.SetCode(forwarder, stakingCaller(false))
+
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create dpos context: %v", err)
+	}
+	if err := dposContext.BecomeCandidate(candidate); err != nil {
+		t.Fatalf("failed to register candidate: %v", err)
+	}
+	dposRoot := dposContext.Root()
+
+	config := *params.TestChainConfig
+	config.Dpos = &params.DposConfig{StakingBlock: big.NewInt(0)}
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		GasLimit:   10000000,
+		Time:       big.NewInt(10),
+		Difficulty: big.NewInt(1),
+		Coinbase:   coinbase,
+	}
+	var (
+		signer  = types.MakeSigner(&config, header.Number)
+		gp      = new(GasPool).AddGas(header.GasLimit)
+		usedGas uint64
+	)
+	delegate := append(crypto.Keccak256([]byte("delegate(address)"))[:4], common.LeftPadBytes(candidate.Bytes(), 32)...)
+	apply := func(nonce uint64, to common.Address) *types.Receipt {
+		tx, _ := types.SignTx(types.NewTransaction(types.Binary, nonce, to, big.NewInt(0), 200000, big.NewInt(1), delegate), signer, key)
+		statedb.Prepare(tx.Hash(), common.Hash{}, int(nonce))
+		receipt, _, err := ApplyTransaction(&config, dposContext, nil, &coinbase, gp, statedb, header, tx, &usedGas, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to apply transaction: %v", err)
+		}
+		return receipt
+	}
+	vote := func(delegator common.Address) common.Address {
+		return common.BytesToAddress(dposContext.VoteTrie().Get(delegator.Bytes()))
+	}
+//合约投票后回滚，DPoS状态不变
+	if receipt := apply(0, reverter); receipt.Status != types.ReceiptStatusFailed {
+		t.Fatalf("reverting transaction succeeded")
+	}
+	if have := vote(reverter); have != (common.Address{}) {
+		t.Fatalf("vote of reverted transaction kept: have %x", have)
+	}
+	if have := dposContext.Root(); have != dposRoot {
+		t.Fatalf("dpos root changed by reverted transaction: have %x, want %x", have, dposRoot)
+	}
+//合约投票后正常结束，投票写入区块的DPoS上下文
+	if receipt := apply(1, forwarder); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("forwarding transaction failed")
+	}
+	if have := vote(forwarder); have != candidate {
+		t.Fatalf("vote mismatch: have %x, want %x", have, candidate)
+	}
+}
+
+//测试访问列表分叉之前ApplyTransaction拒绝访问列表交易，即使访问列表为空，
+//而费用市场分叉已激活时签名者本身接受该交易类型。
+func TestApplyTransactionAccessListFork(t *testing.T) {
+	var (
+		db       = ethdb.NewMemDatabase()
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		coinbase = common.Address{0xcb}
+		to       = common.Address{0xaa}
+	)
+	config := *params.TestChainConfig
+	config.TxEnvelopeBlock = big.NewInt(0)
+	config.FeeMarketBlock = big.NewInt(0)
+	config.AccessListBlock = big.NewInt(2)
+
+	apply := func(number int64) error {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.SetBalance(sender, big.NewInt(1000000000000000000))
+		header := &types.Header{
+			Number:     big.NewInt(number),
+			GasLimit:   10000000,
+			Time:       big.NewInt(10),
+			Difficulty: big.NewInt(1),
+			Coinbase:   coinbase,
+			BaseFee:    big.NewInt(1),
+		}
+		var (
+			signer  = types.MakeSigner(&config, header.Number)
+			gp      = new(GasPool).AddGas(header.GasLimit)
+			usedGas uint64
+		)
+		tx, err := types.SignTx(types.NewAccessListTransaction(config.ChainID, 0, &to, big.NewInt(0), 21000, big.NewInt(1), nil, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, 0)
+		_, _, err = ApplyTransaction(&config, nil, nil, &coinbase, gp, statedb, header, tx, &usedGas, vm.Config{})
+		return err
+	}
+	if err := apply(1); err != types.ErrTxTypeNotSupported {
+		t.Fatalf("pre-fork error mismatch: have %v, want %v", err, types.ErrTxTypeNotSupported)
+	}
+	if err := apply(2); err != nil {
+		t.Fatalf("failed to apply transaction after the fork: %v", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -64,16 +65,19 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasFeeCap() *big.Int
+	GasTipCap() *big.Int
 	Gas() uint64
 	Value() *big.Int
 
 	Nonce() uint64
 	CheckNonce() bool
 	Data() []byte
+	AccessList() types.AccessList
 }
 
-//IntrinsicGas用给定的数据计算消息的“固有气体”。
-func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error) {
+//IntrinsicGas用给定的数据和访问列表计算消息的“固有气体”。
+func IntrinsicGas(data []byte, accessList types.AccessList, contractCreation, homestead bool) (uint64, error) {
 //设置原始交易的起始气体
 	var gas uint64
 	if contractCreation && homestead {
@@ -102,6 +106,11 @@ func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error)
 		}
 		gas += z * params.TxDataZeroGas
 	}
+//访问列表中的每个地址和存储槽按固定价格预付
+	if accessList != nil {
+		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	}
 	return gas, nil
 }
 
@@ -111,13 +120,26 @@ func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition
 		gp:       gp,
 		evm:      evm,
 		msg:      msg,
-		gasPrice: msg.GasPrice(),
+		gasPrice: EffectiveGasPrice(msg, evm.BaseFee),
 		value:    msg.Value(),
 		data:     msg.Data(),
 		state:    evm.StateDB,
 	}
 }
 
+//EffectiveGasPrice返回消息在给定基础费用下每单位燃气实际支付的价格，
+//即基础费用加小费，但不超过最高费用。baseFee为nil时就是燃气价格。
+func EffectiveGasPrice(msg Message, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return msg.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, msg.GasTipCap())
+	if price.Cmp(msg.GasFeeCap()) > 0 {
+		price.Set(msg.GasFeeCap())
+	}
+	return price
+}
+
 //ApplyMessage通过应用给定消息来计算新状态
 //反对环境中的旧状态。
 //
@@ -131,118 +153,148 @@ func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, bool,
 
 //返回邮件的收件人。
 func (st *StateTransition) to() common.Address {
- /*st.msg==nil st.msg.to（）==nil/*合同创建*/
-  返回公共地址
- }
- 返回*st.msg.to（）。
+	if st.msg == nil || st.msg.To() == nil /* 合同创建 */ {
+		return common.Address{}
+	}
+	return *st.msg.To()
 }
 
-func（st*statetransition）usegas（amount uint64）错误
- 如果st.gas<amount
-  返回vm.erroutofgas
- }
- ST.气体-=数量
+func (st *StateTransition) useGas(amount uint64) error {
+	if st.gas < amount {
+		return vm.ErrOutOfGas
+	}
+	st.gas -= amount
 
- 返回零
+	return nil
 }
 
-func（st*statetransition）buygas（）错误
- mgval：=new（big.int）.mul（new（big.int）.setuint64（st.msg.gas（）），st.gasprice）
- if st.state.getbalance（st.msg.from（））.cmp（mgval）<0_
-  返回不平衡气体
- }
- 如果错误：=st.gp.subgas（st.msg.gas（））；错误！= nIL{
-  返回错误
- }
- st.gas+=st.msg.gas（）。
-
- st.initialgas=st.msg.gas（）。
- st.state.subbalance（st.msg.from（），mgval）
- 返回零
+func (st *StateTransition) buyGas() error {
+	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
+//费用市场交易按最高费用检查余额，按实际价格扣费
+	balanceCheck := mgval
+	if st.evm.BaseFee != nil {
+		balanceCheck = new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.msg.GasFeeCap())
+	}
+	if st.state.GetBalance(st.msg.From()).Cmp(balanceCheck) < 0 {
+		return errInsufficientBalanceForGas
+	}
+	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
+		return err
+	}
+	st.gas += st.msg.Gas()
+
+	st.initialGas = st.msg.Gas()
+	st.state.SubBalance(st.msg.From(), mgval)
+	return nil
 }
 
-func（st*statetransition）precheck（）错误
- //确保此事务的nonce是正确的。
- if st.msg.checknonce（）
-  nonce:=st.state.getnonce（st.msg.from（））
-  如果nonce<st.msg.nonce（）
-   返回erroncetohohigh
-  else if nonce>st.msg.nonce（）
-   返回erroncetoolow
-  }
- }
- 返回圣布伊加斯（St.Buygas）
+func (st *StateTransition) preCheck() error {
+//确保此事务的nonce是正确的。
+	if st.msg.CheckNonce() {
+		nonce := st.state.GetNonce(st.msg.From())
+		if nonce < st.msg.Nonce() {
+			return ErrNonceTooHigh
+		} else if nonce > st.msg.Nonce() {
+			return ErrNonceTooLow
+		}
+	}
+//访问列表分叉之前不接受带访问列表的消息，访问列表为空的访问列表交易同样拒绝
+	if st.msg.AccessList() != nil && !st.evm.ChainConfig().IsAccessList(st.evm.BlockNumber) {
+		return types.ErrTxTypeNotSupported
+	}
+//费用市场分叉之后，最高费用必须覆盖基础费用。不检查nonce的调用（eth_call）不受限制。
+	if st.evm.BaseFee != nil && st.msg.CheckNonce() {
+		if st.msg.GasFeeCap().Cmp(st.msg.GasTipCap()) < 0 {
+			return ErrTipAboveFeeCap
+		}
+		if st.msg.GasFeeCap().Cmp(st.evm.BaseFee) < 0 {
+			return ErrFeeCapTooLow
+		}
+	}
+	return st.buyGas()
 }
 
 //transitionDB将通过应用当前消息和
 //返回包括已用气体在内的结果。如果失败，则返回错误。
 //错误表示一致性问题。
-func（st*statetransition）transitiondb（）（ret[]byte，usedgas uint64，failed bool，err error）
- 如果err=st.precheck（）；err！= nIL{
-  返回
- }
- MSG：= ST.MSG
- 发件人：=vm.accountRef（msg.from（））
- homestead:=st.evm.chainconfig（）.ishomestead（st.evm.blocknumber）
- 合同创建：=msg.to（）==nil
-
- //支付天然气
- 气体，误差：=内部气体（St.Data，ContractCreation，Homestead）
- 如果犯错！= nIL{
-  返回nil、0、false、err
- }
- 如果err=st.usegas（gas）；err！= nIL{
-  返回nil、0、false、err
- }
-
- var
-  EVM＝ST.EVM
-  //VM错误不影响共识，因此
-  //未分配给err，余额不足除外
-  /错误。
-  VMER误差
- ）
- 如果合同创建
-  ret，，st.gas，vmerr=evm.create（发送方，st.data，st.gas，st.value）
- }否则{
-  //为下一个事务增加nonce
-  st.state.setnonce（msg.from（），st.state.getnonce（sender.address（））+1）
-  ret，st.gas，vmerr=evm.call（sender，st.to（），st.data，st.gas，st.value）
- }
- 如果VMRR！= nIL{
-  log.debug（“返回的VM有错误”，“err”，vm err）
-  //唯一可能的共识错误是如果没有
-  //有足够的余额进行转移。第一
-  //余额转移永远不会失败。
-  如果vmerr==vm.errUnsuffictBalance
-   返回nil、0、false、vmerr
-  }
- }
- S.ReffgdGas（）
- 添加平衡（st.evm.coinbase，new（big.int）.mul（new（big.int）.setuint64（st.gasused（）），st.gasprice）
-
- 返回ret，st.gasused（），vmerr！=零
+func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bool, err error) {
+	if err = st.preCheck(); err != nil {
+		return
+	}
+	msg := st.msg
+	sender := vm.AccountRef(msg.From())
+	homestead := st.evm.ChainConfig().IsHomestead(st.evm.BlockNumber)
+	contractCreation := msg.To() == nil
+
+//支付天然气
+	gas, err := IntrinsicGas(st.data, msg.AccessList(), contractCreation, homestead)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.useGas(gas); err != nil {
+		return nil, 0, false, err
+	}
+//访问列表分叉之后，发送者、接收者、预编译合约和交易访问列表中的项一开始就按已访问计价
+	if st.evm.ChainConfig().IsAccessList(st.evm.BlockNumber) {
+		st.state.PrepareAccessList(msg.From(), msg.To(), st.evm.ActivePrecompiles(), msg.AccessList())
+	}
+
+	var (
+		evm = st.evm
+//VM错误不影响共识，因此
+//未分配给err，余额不足除外
+//错误。
+		vmerr error
+	)
+	if contractCreation {
+		ret, _, st.gas, vmerr = evm.Create(sender, st.data, st.gas, st.value)
+	} else {
+//为下一个事务增加nonce
+		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
+		ret, st.gas, vmerr = evm.Call(sender, st.to(), st.data, st.gas, st.value)
+	}
+	if vmerr != nil {
+		log.Debug("VM returned with error", "err", vmerr)
+//唯一可能的共识错误是如果没有
+//有足够的余额进行转移。第一
+//余额转移永远不会失败。
+		if vmerr == vm.ErrInsufficientBalance {
+			return nil, 0, false, vmerr
+		}
+	}
+	st.refundGas()
+
+//费用市场分叉之后基础费用部分被销毁，出块者只得到小费
+	tip := st.gasPrice
+	if st.evm.BaseFee != nil {
+		tip = new(big.Int).Sub(st.gasPrice, st.evm.BaseFee)
+		if tip.Sign() < 0 {
+			tip.SetUint64(0)
+		}
+	}
+	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), tip))
+
+	return ret, st.gasUsed(), vmerr != nil, err
 }
 
-func（st*statetransition）refundgas（）
- //申请退款柜台，上限为已用气体的一半。
- 退款：=st.gasused（）/2
- 如果退款>st.state.get退款（）
-  退款=st.state.get退款（）
- }
- ST.GAS+=退款
-
- //剩余气体返回eth，按原汇率交换。
- 剩余：=new（big.int）.mul（new（big.int）.setuint64（st.gas），st.gasprice）
- st.state.addbalance（st.msg.from（），剩余）
-
- //同时将剩余气体返回至区块气计数器，因此
- //可用于下一个事务。
- 添加气体（ST.GAS）
+func (st *StateTransition) refundGas() {
+//申请退款柜台，上限为已用气体的一半。
+	refund := st.gasUsed() / 2
+	if refund > st.state.GetRefund() {
+		refund = st.state.GetRefund()
+	}
+	st.gas += refund
+
+//剩余气体返回eth，按原汇率交换。
+	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
+	st.state.AddBalance(st.msg.From(), remaining)
+
+//同时将剩余气体返回至区块气计数器，因此
+//可用于下一个事务。
+	st.gp.AddGas(st.gas)
 }
 
 //gas used返回状态转换所消耗的气体量。
-func（st*statetransition）gasused（）uint64_
- 返回St.InitialGas-St.Gas
+func (st *StateTransition) gasUsed() uint64 {
+	return st.initialGas - st.gas
 }
-
@@ -360,33 +360,45 @@ func (l *txList) Flatten() types.Transactions {
 }
 
 //PriceHeap是一个堆。用于检索的事务的接口实现
-//池满时要丢弃的按价格排序的交易记录。
-type priceHeap []*types.Transaction
+//池满时要丢弃的按价格排序的交易记录。费用市场分叉之后按待打包
+//区块基础费用下付给出块者的实际小费排序。
+type priceHeap struct {
+baseFee *big.Int             //排序所用的基础费用，分叉之前为nil
+list    []*types.Transaction //堆中的交易
+}
 
-func (h priceHeap) Len() int      { return len(h) }
-func (h priceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *priceHeap) Len() int      { return len(h.list) }
+func (h *priceHeap) Swap(i, j int) { h.list[i], h.list[j] = h.list[j], h.list[i] }
 
-func (h priceHeap) Less(i, j int) bool {
-//主要按价格排序，返回较便宜的
-	switch h[i].GasPrice().Cmp(h[j].GasPrice()) {
+func (h *priceHeap) Less(i, j int) bool {
+//主要按实际小费排序，返回较便宜的
+	switch h.cmp(h.list[i], h.list[j]) {
 	case -1:
 		return true
 	case 1:
 		return false
 	}
 //如果价格匹配，通过nonce稳定（高nonce更糟）
-	return h[i].Nonce() > h[j].Nonce()
+	return h.list[i].Nonce() > h.list[j].Nonce()
+}
+
+//cmp先比较两笔交易在基础费用下的实际小费，相同时再比较最高费用
+func (h *priceHeap) cmp(a, b *types.Transaction) int {
+	if c := a.EffectiveGasTip(h.baseFee).Cmp(b.EffectiveGasTip(h.baseFee)); c != 0 {
+		return c
+	}
+	return a.GasFeeCap().Cmp(b.GasFeeCap())
 }
 
 func (h *priceHeap) Push(x interface{}) {
-	*h = append(*h, x.(*types.Transaction))
+	h.list = append(h.list, x.(*types.Transaction))
 }
 
 func (h *priceHeap) Pop() interface{} {
-	old := *h
+	old := h.list
 	n := len(old)
 	x := old[n-1]
-	*h = old[0 : n-1]
+	h.list = old[0 : n-1]
 	return x
 }
 
@@ -417,15 +429,26 @@ func (l *txPricedList) Put(tx *types.Transaction) {
 func (l *txPricedList) Removed() {
 //撞击陈旧的计数器，但如果仍然过低（<25%），则退出。
 	l.stales++
-	if l.stales <= len(*l.items)/4 {
+	if l.stales <= len(l.items.list)/4 {
 		return
 	}
 //似乎我们已经达到了一个关键的陈旧的交易数量，reheap
-	reheap := make(priceHeap, 0, l.all.Count())
+	l.reheap()
+}
+
+//SetBaseFee更新排序所用的待打包区块基础费用并重建堆
+func (l *txPricedList) SetBaseFee(baseFee *big.Int) {
+	l.items.baseFee = baseFee
+	l.reheap()
+}
 
-	l.stales, l.items = 0, &reheap
+//reheap丢弃过时的价格点并用池中的全部交易重建堆
+func (l *txPricedList) reheap() {
+	reheap := &priceHeap{baseFee: l.items.baseFee, list: make([]*types.Transaction, 0, l.all.Count())}
+
+	l.stales, l.items = 0, reheap
 	l.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
-		*l.items = append(*l.items, tx)
+		l.items.list = append(l.items.list, tx)
 		return true
 	})
 	heap.Init(l.items)
@@ -437,7 +460,7 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 drop := make(types.Transactions, 0, 128) //要删除的远程低价交易
 save := make(types.Transactions, 0, 64)  //要保留的本地定价过低交易
 
-	for len(*l.items) > 0 {
+	for len(l.items.list) > 0 {
 //如果在清理过程中发现过时的事务，则放弃这些事务
 		tx := heap.Pop(l.items).(*types.Transaction)
 		if l.all.Get(tx.Hash()) == nil {
@@ -445,7 +468,7 @@ save := make(types.Transactions, 0, 64)  //要保留的本地定价过低交易
 			continue
 		}
 //如果我们达到了临界值，就停止丢弃
-		if tx.GasPrice().Cmp(threshold) >= 0 {
+		if tx.EffectiveGasTip(l.items.baseFee).Cmp(threshold) >= 0 {
 			save = append(save, tx)
 			break
 		}
@@ -464,14 +487,18 @@ save := make(types.Transactions, 0, 64)  //要保留的本地定价过低交易
 
 //低价检查交易是否比
 //当前正在跟踪的最低价格交易记录。
-func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) bool {
+func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet, dposSlots uint64) bool {
 //本地交易不能定价过低
 	if local.containsTx(tx) {
 		return false
 	}
+//DPoS交易未占满dposSlots时直接占用一个受保护的名额
+	if tx.Type() != types.Binary && uint64(l.all.DposCount()) < dposSlots {
+		return false
+	}
 //如果在堆开始处找到过时的价格点，则丢弃它们
-	for len(*l.items) > 0 {
-		head := []*types.Transaction(*l.items)[0]
+	for len(l.items.list) > 0 {
+		head := l.items.list[0]
 		if l.all.Get(head.Hash()) == nil {
 			l.stales--
 			heap.Pop(l.items)
@@ -480,34 +507,43 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 		break
 	}
 //检查交易是否定价过低
-	if len(*l.items) == 0 {
+	if len(l.items.list) == 0 {
 log.Error("Pricing query for empty pool") //这不可能发生，打印以捕获编程错误
 		return false
 	}
-	cheapest := []*types.Transaction(*l.items)[0]
-	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+	cheapest := l.items.list[0]
+	return l.items.cmp(cheapest, tx) >= 0
 }
 
 //Discard查找许多定价最低的事务，将它们从
 //并返回它们以便从整个池中进一步删除。
-func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions {
-drop := make(types.Transactions, 0, count) //要删除的远程低价交易
-save := make(types.Transactions, 0, 64)    //要保留的本地定价过低交易
+//池中DPoS交易超出dposSlots的部分从最便宜的开始可被驱逐，其余受保护。
+func (l *txPricedList) Discard(count int, local *accountSet, dposSlots uint64) types.Transactions {
+drop := make(types.Transactions, 0, count)   //要删除的远程低价交易
+save := make(types.Transactions, 0, 64)      //要保留的本地定价过低交易
+excess := l.all.DposCount() - int(dposSlots) //超出保护名额的DPoS交易数量
 
-	for len(*l.items) > 0 && count > 0 {
+	for len(l.items.list) > 0 && count > 0 {
 //如果在清理过程中发现过时的事务，则放弃这些事务
 		tx := heap.Pop(l.items).(*types.Transaction)
 		if l.all.Get(tx.Hash()) == nil {
 			l.stales--
 			continue
 		}
-//找到未过期的事务，除非本地
+//找到未过期的事务，除非是本地交易或保护名额内的DPoS交易
 		if local.containsTx(tx) {
 			save = append(save, tx)
-		} else {
-			drop = append(drop, tx)
-			count--
+			continue
+		}
+		if tx.Type() != types.Binary {
+			if excess <= 0 {
+				save = append(save, tx)
+				continue
+			}
+			excess--
 		}
+		drop = append(drop, tx)
+		count--
 	}
 	for _, tx := range save {
 		heap.Push(l.items, tx)
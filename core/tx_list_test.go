@@ -13,9 +13,11 @@
 package core
 
 import (
+	"math/big"
 	"math/rand"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -46,3 +48,37 @@ func TestStrictTxListAdd(t *testing.T) {
 	}
 }
 
+
+//测试价格堆在设置基础费用后按实际小费而不是最高费用驱逐交易。
+func TestTxPricedListEffectiveTip(t *testing.T) {
+	var (
+		signer = types.NewFeeMarketSigner(common.Big1)
+		to     = common.Address{1}
+		all    = newTxLookup()
+		priced = newTxPricedList(all)
+	)
+	fees := []struct{ tip, cap int64 }{
+		{tip: 2, cap: 100}, //小费2
+		{tip: 8, cap: 13},  //最高费用限制，小费3
+		{tip: 4, cap: 50},  //小费4
+	}
+	txs := make(types.Transactions, len(fees))
+	for i, fee := range fees {
+		key, _ := crypto.GenerateKey()
+		txs[i], _ = types.SignTx(types.NewDynamicFeeTransaction(common.Big1, 0, &to, common.Big0, 21000, big.NewInt(fee.tip), big.NewInt(fee.cap), nil), signer, key)
+		all.Add(txs[i])
+		priced.Put(txs[i])
+	}
+	priced.SetBaseFee(big.NewInt(10))
+
+	drop := priced.Discard(2, newAccountSet(signer), 0)
+	if len(drop) != 2 || drop[0] != txs[0] || drop[1] != txs[1] {
+		t.Fatalf("discarded transactions mismatch: have %v, want %v", drop, txs[:2])
+	}
+//最高费用高但小费低的交易同样被视为更便宜
+	key, _ := crypto.GenerateKey()
+	cheap, _ := types.SignTx(types.NewDynamicFeeTransaction(common.Big1, 0, &to, common.Big0, 21000, big.NewInt(3), big.NewInt(1000), nil), signer, key)
+	if !priced.Underpriced(cheap, newAccountSet(signer), 0) {
+		t.Errorf("transaction with lower tip not underpriced")
+	}
+}
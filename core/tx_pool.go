@@ -13,15 +13,18 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"os"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
@@ -72,6 +75,12 @@ var (
 //而不是用户可能使用的一些有意义的限制。这不是共识错误
 //使事务无效，而不是DoS保护。
 	ErrOversizedData = errors.New("oversized data")
+
+//如果DPoS交易针对的地址在当前DPoS上下文中不是候选人，则返回ErrNotCandidate
+	ErrNotCandidate = errors.New("not a dpos candidate")
+
+//如果取消投票交易的候选人与当前投票记录不符，则返回ErrVoteMismatch
+	ErrVoteMismatch = errors.New("dpos vote mismatch")
 )
 
 var (
@@ -124,6 +133,8 @@ NoLocals  bool             //是否应禁用本地事务处理
 Journal   string           //在节点重新启动后幸存的本地事务日志
 Rejournal time.Duration    //重新生成本地事务日记帐的时间间隔
 
+RemoteJournal string //保存远程交易（包括排队交易）的日志，节点重启后重新校验并载入，为空时不保存
+
 PriceLimit uint64 //用于验收的最低天然气价格
 PriceBump  uint64 //替换已存在交易的最低价格波动百分比（nonce）
 
@@ -133,6 +144,8 @@ AccountQueue uint64 //每个帐户允许的最大不可执行事务槽数
 GlobalQueue  uint64 //所有帐户的最大不可执行事务槽数
 
 Lifetime time.Duration //非可执行事务排队的最长时间
+
+DposSlots uint64 //池满时免于按价格驱逐的DPoS交易的最大数量
 }
 
 //DefaultTxPoolConfig包含事务的默认配置
@@ -150,6 +163,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	DposSlots: 1024,
 }
 
 //清理检查提供的用户配置并更改
@@ -197,6 +212,8 @@ currentMaxGas uint64              //交易上限的当前天然气限额
 locals  *accountSet //要免除逐出规则的本地事务集
 journal *txJournal  //备份到磁盘的本地事务日志
 
+remoteJournal *txJournal //备份到磁盘的远程交易日志
+
 pending map[common.Address]*txList   //所有当前可处理的事务
 queue   map[common.Address]*txList   //排队但不可处理的事务
 beats   map[common.Address]time.Time //每个已知帐户的最后一个心跳
@@ -206,6 +223,21 @@ priced  *txPricedList                //按价格排序的所有交易记录
 wg sync.WaitGroup //用于关机同步
 
 	homestead bool
+envelope   bool //下一个区块是否在交易信封分叉之后
+feeMarket  bool //下一个区块是否在费用市场分叉之后
+accessList bool //下一个区块是否在访问列表分叉之后
+}
+
+//newPoolSigner返回交易池验证签名使用的签名者，配置了费用市场或访问列表分叉时
+//同时接受对应类型的交易
+func newPoolSigner(chainconfig *params.ChainConfig) types.Signer {
+	if chainconfig.FeeMarketBlock != nil {
+		return types.NewFeeMarketSigner(chainconfig.ChainID)
+	}
+	if chainconfig.AccessListBlock != nil {
+		return types.NewAccessListSigner(chainconfig.ChainID)
+	}
+	return types.NewEIP155Signer(chainconfig.ChainID)
 }
 
 //newtxpool创建一个新的事务池来收集、排序和筛选入站事务
@@ -219,7 +251,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.NewEIP155Signer(chainconfig.ChainID),
+		signer:      newPoolSigner(chainconfig),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
@@ -246,6 +278,17 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+//如果启用了远程交易日志，按当前状态和DPoS上下文重新校验后载入
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newTxJournal(config.RemoteJournal)
+
+		if err := pool.remoteJournal.load(pool.addRemotesRevalidated); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 //从区块链订阅事件
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
@@ -334,6 +377,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				pool.mu.Lock()
+				if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -409,6 +459,13 @@ newHead = pool.chain.CurrentBlock().Header() //测试过程中的特殊情况
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.envelope = pool.chainconfig.IsTxEnvelope(next)
+	pool.feeMarket = pool.chainconfig.IsFeeMarket(next)
+	pool.accessList = pool.chainconfig.IsAccessList(next)
+	if pool.feeMarket {
+		pool.priced.SetBaseFee(misc.CalcBaseFee(pool.chainconfig, newHead))
+	}
 
 //插入由于重新排序而丢弃的任何事务
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -443,6 +500,15 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+//远程交易不逐笔写入日志，停止时保存最新的池内容
+	if pool.remoteJournal != nil {
+		pool.mu.Lock()
+		if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote tx journal", "err", err)
+		}
+		pool.mu.Unlock()
+		pool.remoteJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -559,6 +625,148 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+//remote检索所有当前已知的非本地交易，按来源帐户分组并按nonce排序。
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
+//ExportTransactions把池中全部挂起和排队的交易写入path，返回写入的交易数
+func (pool *TxPool) ExportTransactions(path string) (int, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	all := make(map[common.Address]types.Transactions)
+	count := 0
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range lists {
+			txs := list.Flatten()
+			all[addr] = append(all[addr], txs...)
+			count += len(txs)
+		}
+	}
+	journal := newTxJournal(path)
+	if err := journal.rotate(all); err != nil {
+		return 0, err
+	}
+	return count, journal.close()
+}
+
+//ImportTransactions从path载入ExportTransactions导出的交易，按远程交易重新校验后
+//加入池中，返回加入的交易数和丢弃的交易数
+func (pool *TxPool) ImportTransactions(path string) (int, int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, 0, err
+	}
+	added, dropped := 0, 0
+	err := newTxJournal(path).load(func(txs []*types.Transaction) []error {
+		errs := pool.addRemotesRevalidated(txs)
+		for _, err := range errs {
+			if err != nil {
+				dropped++
+			} else {
+				added++
+			}
+		}
+		return errs
+	})
+	return added, dropped, err
+}
+
+//addRemotesRevalidated按当前头区块的DPoS上下文检查DPoS交易，
+//再把通过检查的交易作为远程交易加入池中。
+func (pool *TxPool) addRemotesRevalidated(txs []*types.Transaction) []error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	errs := make([]error, len(txs))
+
+	var dposContext *types.DposContext
+	if proto := pool.chain.CurrentBlock().Header().DposContext; proto != nil {
+		ctx, err := types.NewDposContextFromProto(pool.currentState.Database().TrieDB(), proto)
+		if err != nil {
+			log.Warn("Failed to open dpos context for revalidation", "err", err)
+		} else {
+			dposContext = ctx
+		}
+	}
+//同一批中注册候选人的交易可能排在针对它的投票之前
+	registering := make(map[common.Address]bool)
+	for _, tx := range txs {
+		if tx.Type() == types.RegCandidate {
+			if from, err := types.Sender(pool.signer, tx); err == nil {
+				registering[from] = true
+			}
+		}
+	}
+	var (
+		valid []*types.Transaction
+		index []int
+	)
+	for i, tx := range txs {
+		if dposContext != nil {
+			if errs[i] = pool.validateDposTx(dposContext, tx, registering); errs[i] != nil {
+				continue
+			}
+		}
+		valid = append(valid, tx)
+		index = append(index, i)
+	}
+	for i, err := range pool.addTxsLocked(valid, false) {
+		errs[index[i]] = err
+	}
+	return errs
+}
+
+//validateDposTx检查DPoS交易在给定的DPoS上下文中是否仍然可以执行
+func (pool *TxPool) validateDposTx(dposContext *types.DposContext, tx *types.Transaction, registering map[common.Address]bool) error {
+	if tx.Type() == types.Binary || tx.Type() == types.RegCandidate {
+		return nil
+	}
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return ErrInvalidSender
+	}
+	isCandidate := func(addr common.Address) bool {
+		if registering[addr] {
+			return true
+		}
+		data, err := dposContext.CandidateTrie().TryGet(addr.Bytes())
+		return err == nil && data != nil
+	}
+	switch tx.Type() {
+	case types.UnregCandidate:
+		if !isCandidate(from) {
+			return ErrNotCandidate
+		}
+	case types.Delegate:
+		if tx.To() == nil || !isCandidate(*tx.To()) {
+			return ErrNotCandidate
+		}
+	case types.UnDelegate:
+		if tx.To() == nil || !isCandidate(*tx.To()) {
+			return ErrNotCandidate
+		}
+		vote, err := dposContext.VoteTrie().TryGet(from.Bytes())
+		if err != nil || !bytes.Equal(vote, tx.To().Bytes()) {
+			return ErrVoteMismatch
+		}
+	default:
+		return types.ErrInvalidType
+	}
+	return nil
+}
+
 //validatetx根据共识检查交易是否有效
 //规则并遵守本地节点的一些启发式限制（价格和大小）。
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -575,6 +783,23 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
 	}
+//交易信封分叉之前只接受旧编码的交易，之后只接受新编码的交易
+	if tx.IsPreEnvelope() == pool.envelope {
+		return types.ErrTxTypeNotSupported
+	}
+//费用市场交易只在分叉之后接受，小费不能高于最高费用
+	if tx.IsDynamicFee() {
+		if !pool.feeMarket {
+			return types.ErrTxTypeNotSupported
+		}
+		if tx.GasFeeCap().Cmp(tx.GasTipCap()) < 0 {
+			return ErrTipAboveFeeCap
+		}
+	}
+//访问列表交易只在访问列表分叉之后接受
+	if tx.EnvelopeType() == types.AccessListTxType && !pool.accessList {
+		return types.ErrTxTypeNotSupported
+	}
 //确保事务签名正确
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
@@ -594,7 +819,7 @@ local = local || pool.locals.contains(from) //即使事务从网络到达，帐
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, pool.homestead)
 	if err != nil {
 		return err
 	}
@@ -628,13 +853,13 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 //如果事务池已满，则放弃定价过低的事务
 	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 //如果新交易定价过低，不要接受
-		if !local && pool.priced.Underpriced(tx, pool.locals) {
+		if !local && pool.priced.Underpriced(tx, pool.locals, pool.config.DposSlots) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
 			return false, ErrUnderpriced
 		}
 //新的交易比我们糟糕的交易好，给它腾出空间。
-		drop := pool.priced.Discard(pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
+		drop := pool.priced.Discard(pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals, pool.config.DposSlots)
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
@@ -1207,6 +1432,7 @@ func (as *accountSet) flatten() []common.Address {
 //txpool.mutex。
 type txLookup struct {
 	all  map[common.Hash]*types.Transaction
+	dpos int //DPoS交易的数量
 	lock sync.RWMutex
 }
 
@@ -1245,11 +1471,22 @@ func (t *txLookup) Count() int {
 	return len(t.all)
 }
 
+//DposCount返回查找中DPoS交易的数量
+func (t *txLookup) DposCount() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.dpos
+}
+
 //添加将事务添加到查找中。
 func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if _, ok := t.all[tx.Hash()]; !ok && tx.Type() != types.Binary {
+		t.dpos++
+	}
 	t.all[tx.Hash()] = tx
 }
 
@@ -1258,6 +1495,9 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if tx, ok := t.all[hash]; ok && tx.Type() != types.Binary {
+		t.dpos--
+	}
 	delete(t.all, hash)
 }
 
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 //testxtpoolconfig是没有状态磁盘的事务池配置
@@ -1676,6 +1677,220 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+//测试池满时限额内的DPoS交易不会因为价格低被驱逐
+func TestTransactionPoolDposProtection(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 0
+	config.DposSlots = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	delegate, _ := types.SignTx(types.NewTransaction(types.Delegate, 0, common.Address{0x01}, big.NewInt(0), 100000, big.NewInt(1), nil), pool.signer, keys[0])
+	if err := pool.AddRemote(delegate); err != nil {
+		t.Fatalf("failed to add delegate transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[1])); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+//池已满，更高价的交易只能挤出普通交易
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), keys[2])); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.Get(delegate.Hash()) == nil {
+		t.Fatalf("dpos transaction evicted")
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+//测试池满时只有DposSlots个DPoS交易受保护，超出名额的部分从最便宜的开始被驱逐
+func TestTransactionPoolDposQuota(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 4
+	config.GlobalQueue = 0
+	config.DposSlots = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 7)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	delegate := func(price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(types.Delegate, 0, common.Address{0x01}, big.NewInt(0), 100000, big.NewInt(price), nil), pool.signer, key)
+		return tx
+	}
+//用超出名额一个的DPoS交易和一个普通交易填满池
+	delegates := []*types.Transaction{delegate(1, keys[0]), delegate(2, keys[1]), delegate(3, keys[2])}
+	for i, tx := range delegates {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add delegate transaction %d: %v", i, err)
+		}
+	}
+	plain := pricedTransaction(0, 100000, big.NewInt(4), keys[3])
+	if err := pool.AddRemote(plain); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+//超出名额的最便宜DPoS交易被驱逐
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(5), keys[4])); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.Get(delegates[0].Hash()) != nil {
+		t.Fatalf("dpos transaction beyond the quota not evicted")
+	}
+	if pool.all.DposCount() != 2 {
+		t.Fatalf("dpos transactions mismatched: have %d, want %d", pool.all.DposCount(), 2)
+	}
+//名额内的DPoS交易受保护，改为驱逐普通交易
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(6), keys[5])); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.Get(delegates[1].Hash()) == nil || pool.Get(delegates[2].Hash()) == nil {
+		t.Fatalf("dpos transaction within the quota evicted")
+	}
+	if pool.Get(plain.Hash()) != nil {
+		t.Fatalf("plain transaction not evicted")
+	}
+//名额已满时新的DPoS交易按价格参与竞争
+	if err := pool.AddRemote(delegate(1, keys[6])); err != ErrUnderpriced {
+		t.Fatalf("adding underpriced dpos transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if pending, _ := pool.Stats(); pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+//测试启用远程交易日志时远程的挂起和排队交易在重启后恢复
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+//为日志创建临时文件，只需要路径
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	file.Close()
+	os.Remove(journal)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.RemoteJournal = journal
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+//两个可执行的交易和一个nonce有间隔的排队交易
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.AddRemote(transaction(nonce, 100000, remote)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	pool.Stop()
+
+//重启后按新的状态重新校验，nonce过低的交易被丢弃
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+//测试交易池按当前dpos上下文校验候选人、投票及撤票交易
+func TestValidateDposTx(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPool()
+	defer pool.Stop()
+
+	dposContext, err := types.NewDposContext(trie.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create dpos context: %v", err)
+	}
+	candidateKey, _ := crypto.GenerateKey()
+	voterKey, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+
+	candidate := crypto.PubkeyToAddress(candidateKey.PublicKey)
+	voter := crypto.PubkeyToAddress(voterKey.PublicKey)
+	registering := common.Address{0x01}
+	stranger := common.Address{0x02}
+
+	if err := dposContext.BecomeCandidate(candidate); err != nil {
+		t.Fatalf("failed to register candidate: %v", err)
+	}
+	if err := dposContext.Delegate(voter, candidate); err != nil {
+		t.Fatalf("failed to delegate: %v", err)
+	}
+	tests := []struct {
+		txType types.TxType
+		to     common.Address
+		key    *ecdsa.PrivateKey
+		err    error
+	}{
+		{types.Binary, stranger, otherKey, nil},
+		{types.RegCandidate, common.Address{}, otherKey, nil},
+		{types.UnregCandidate, common.Address{}, otherKey, ErrNotCandidate},
+		{types.UnregCandidate, common.Address{}, candidateKey, nil},
+		{types.Delegate, stranger, voterKey, ErrNotCandidate},
+		{types.Delegate, candidate, otherKey, nil},
+		{types.Delegate, registering, otherKey, nil},
+		{types.UnDelegate, stranger, voterKey, ErrNotCandidate},
+		{types.UnDelegate, candidate, otherKey, ErrVoteMismatch},
+		{types.UnDelegate, registering, voterKey, ErrVoteMismatch},
+		{types.UnDelegate, candidate, voterKey, nil},
+	}
+	for i, tt := range tests {
+		tx, _ := types.SignTx(types.NewTransaction(tt.txType, 0, tt.to, big.NewInt(0), 100000, big.NewInt(1), nil), pool.signer, tt.key)
+		if err := pool.validateDposTx(dposContext, tx, map[common.Address]bool{registering: true}); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
 //TestTransactionStatusCheck测试池是否可以正确检索
 //单个交易的挂起状态。
 func TestTransactionStatusCheck(t *testing.T) {
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//AccessList是交易预先声明要访问的帐户和存储槽列表。列表中的帐户和存储槽
+//在交易开始时就按已访问计价，不再收取第一次访问的费用。
+type AccessList []AccessTuple
+
+//AccessTuple是访问列表中的一项：一个帐户及其要访问的存储槽
+type AccessTuple struct {
+	Address     common.Address `json:"address"     gencodec:"required"`
+	StorageKeys []common.Hash  `json:"storageKeys" gencodec:"required"`
+}
+
+//StorageKeys返回访问列表中存储槽的总数
+func (al AccessList) StorageKeys() int {
+	sum := 0
+	for _, tuple := range al {
+		sum += len(tuple.StorageKeys)
+	}
+	return sum
+}
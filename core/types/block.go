@@ -15,6 +15,7 @@ package types
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
 	"math/big"
 	"sort"
@@ -84,6 +85,99 @@ type Header struct {
 	Nonce       BlockNonce     `json:"nonce"            gencodec:"required"`
 MaxValidatorSize   uint64  `json:"maxValidatorSize" gencodec:"required"`  //添加
 BlockInterval	uint64	   `json:"blockInterval" 	gencodec:"required"`  //添加
+
+//BaseFee是费用市场分叉之后每单位燃气的基础费用，分叉之前为nil
+	BaseFee *big.Int `json:"baseFeePerGas" rlp:"-"`
+}
+
+//headerRLP是区块头的RLP编码格式。基础费用只在费用市场分叉之后作为
+//列表的最后一项出现，分叉之前的区块头编码和哈希不变。
+type headerRLP struct {
+	ParentHash       common.Hash
+	UncleHash        common.Hash
+	Validator        common.Address
+	Coinbase         common.Address
+	Root             common.Hash
+	TxHash           common.Hash
+	ReceiptHash      common.Hash
+	DposContext      *DposContextProto
+	Bloom            Bloom
+	Difficulty       *big.Int
+	Number           *big.Int
+	GasLimit         uint64
+	GasUsed          uint64
+	Time             *big.Int
+	Extra            []byte
+	MixDigest        common.Hash
+	Nonce            BlockNonce
+	MaxValidatorSize uint64
+	BlockInterval    uint64
+	BaseFee          []*big.Int `rlp:"tail"`
+}
+
+//EncodeRLP实现rlp.Encoder
+func (h *Header) EncodeRLP(w io.Writer) error {
+	enc := headerRLP{
+		ParentHash:       h.ParentHash,
+		UncleHash:        h.UncleHash,
+		Validator:        h.Validator,
+		Coinbase:         h.Coinbase,
+		Root:             h.Root,
+		TxHash:           h.TxHash,
+		ReceiptHash:      h.ReceiptHash,
+		DposContext:      h.DposContext,
+		Bloom:            h.Bloom,
+		Difficulty:       h.Difficulty,
+		Number:           h.Number,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Time:             h.Time,
+		Extra:            h.Extra,
+		MixDigest:        h.MixDigest,
+		Nonce:            h.Nonce,
+		MaxValidatorSize: h.MaxValidatorSize,
+		BlockInterval:    h.BlockInterval,
+	}
+	if h.BaseFee != nil {
+		enc.BaseFee = []*big.Int{h.BaseFee}
+	}
+	return rlp.Encode(w, &enc)
+}
+
+//DecodeRLP实现rlp.Decoder
+func (h *Header) DecodeRLP(s *rlp.Stream) error {
+	var dec headerRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.BaseFee) > 1 {
+		return errors.New("rlp: too many elements for types.Header")
+	}
+	*h = Header{
+		ParentHash:       dec.ParentHash,
+		UncleHash:        dec.UncleHash,
+		Validator:        dec.Validator,
+		Coinbase:         dec.Coinbase,
+		Root:             dec.Root,
+		TxHash:           dec.TxHash,
+		ReceiptHash:      dec.ReceiptHash,
+		DposContext:      dec.DposContext,
+		Bloom:            dec.Bloom,
+		Difficulty:       dec.Difficulty,
+		Number:           dec.Number,
+		GasLimit:         dec.GasLimit,
+		GasUsed:          dec.GasUsed,
+		Time:             dec.Time,
+		Extra:            dec.Extra,
+		MixDigest:        dec.MixDigest,
+		Nonce:            dec.Nonce,
+		MaxValidatorSize: dec.MaxValidatorSize,
+		BlockInterval:    dec.BlockInterval,
+	}
+	if len(dec.BaseFee) == 1 {
+		h.BaseFee = dec.BaseFee[0]
+	}
+	return nil
 }
 
 //gencodec的字段类型重写
@@ -94,6 +188,7 @@ type headerMarshaling struct {
 	GasUsed    hexutil.Uint64
 	Time       *hexutil.Big
 	Extra      hexutil.Bytes
+	BaseFee    *hexutil.Big
 Hash       common.Hash `json:"hash"` //在marshaljson中添加对hash（）的调用
 }
 
@@ -105,7 +200,7 @@ func (h *Header) Hash() common.Hash {
 
 //hashnononce返回用作工作证明搜索输入的哈希。
 func (h *Header) HashNoNonce() common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		h.ParentHash,
 		h.UncleHash,
 		h.Validator,
@@ -120,7 +215,11 @@ func (h *Header) HashNoNonce() common.Hash {
 		h.GasUsed,
 		h.Time,
 		h.Extra,
-	})
+	}
+	if h.BaseFee != nil {
+		fields = append(fields, h.BaseFee)
+	}
+	return rlpHash(fields)
 }
 
 //SIZE返回所有内部内容使用的大致内存。它被使用
@@ -253,6 +352,9 @@ func CopyHeader(h *Header) *Header {
 	if cpy.Number = new(big.Int); h.Number != nil {
 		cpy.Number.Set(h.Number)
 	}
+	if h.BaseFee != nil {
+		cpy.BaseFee = new(big.Int).Set(h.BaseFee)
+	}
 	if len(h.Extra) > 0 {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
@@ -317,6 +419,14 @@ func (b *Block) GasUsed() uint64      { return b.header.GasUsed }
 func (b *Block) Difficulty() *big.Int { return new(big.Int).Set(b.header.Difficulty) }
 func (b *Block) Time() *big.Int       { return new(big.Int).Set(b.header.Time) }
 
+//BaseFee返回区块的基础费用，费用市场分叉之前返回nil
+func (b *Block) BaseFee() *big.Int {
+	if b.header.BaseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(b.header.BaseFee)
+}
+
 func (b *Block) NumberU64() uint64        { return b.header.Number.Uint64() }
 func (b *Block) MixDigest() common.Hash   { return b.header.MixDigest }
 func (b *Block) Nonce() uint64            { return binary.BigEndian.Uint64(b.header.Nonce[:]) }
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -65,3 +66,103 @@ func TestBlockEncoding(t *testing.T) {
 	}
 }
 
+
+//baselineTx和baselineBlock按交易信封分叉之前的格式编码区块，类型是交易列表的第一个元素
+type baselineTx struct {
+	Type         TxType
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	V, R, S      *big.Int
+}
+
+type baselineBlock struct {
+	Header *Header
+	Txs    []*baselineTx
+	Uncles []*Header
+}
+
+//rawList按给定的编码计算交易根
+type rawList [][]byte
+
+func (l rawList) Len() int            { return len(l) }
+func (l rawList) GetRlp(i int) []byte { return l[i] }
+
+//测试交易信封分叉之前编码的区块可以解码，重新编码后字节、交易哈希和交易根不变，
+//签名按原来的规则恢复发送者。
+func TestPreEnvelopeBlockEncoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(5)
+
+	txs := []*baselineTx{
+		{Type: Binary, AccountNonce: 0, Price: big.NewInt(1), GasLimit: 21000, Recipient: &common.Address{0x01}, Amount: big.NewInt(10), Payload: []byte{}},
+		{Type: Delegate, AccountNonce: 1, Price: big.NewInt(1), GasLimit: 30000, Recipient: &common.Address{0xc1}, Amount: new(big.Int), Payload: []byte{}},
+	}
+	raw := make(rawList, len(txs))
+	for i, tx := range txs {
+//签名不覆盖类型
+		h := rlpHash([]interface{}{tx.AccountNonce, tx.Price, tx.GasLimit, tx.Recipient, tx.Amount, tx.Payload, chainID, uint(0), uint(0)})
+		sig, err := crypto.Sign(h[:], key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		tx.R, tx.S = new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+		tx.V = new(big.Int).SetUint64(uint64(sig[64]) + 35 + 2*chainID.Uint64())
+		if raw[i], err = rlp.EncodeToBytes(tx); err != nil {
+			t.Fatalf("failed to encode transaction: %v", err)
+		}
+	}
+	header := &Header{
+		Number:      big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		Time:        big.NewInt(10),
+		GasLimit:    1000000,
+		DposContext: &DposContextProto{},
+		TxHash:      DeriveSha(raw),
+	}
+	blockEnc, err := rlp.EncodeToBytes(&baselineBlock{Header: header, Txs: txs})
+	if err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+
+	var block Block
+	if err := rlp.DecodeBytes(blockEnc, &block); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(block.Transactions()) != len(txs) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(block.Transactions()), len(txs))
+	}
+	signer := NewEIP155Signer(chainID)
+	for i, tx := range block.Transactions() {
+		if !tx.IsPreEnvelope() {
+			t.Errorf("tx %d: not decoded as pre-envelope", i)
+		}
+		if tx.Type() != txs[i].Type {
+			t.Errorf("tx %d: type mismatch: have %d, want %d", i, tx.Type(), txs[i].Type)
+		}
+		if want := crypto.Keccak256Hash(raw[i]); tx.Hash() != want {
+			t.Errorf("tx %d: hash mismatch: have %x, want %x", i, tx.Hash(), want)
+		}
+		if from, err := Sender(signer, tx); err != nil || from != addr {
+			t.Errorf("tx %d: sender mismatch: have %x (%v), want %x", i, from, err, addr)
+		}
+		enc, err := tx.MarshalBinary()
+		if err != nil || !bytes.Equal(enc, raw[i]) {
+			t.Errorf("tx %d: binary encoding mismatch: have %x (%v), want %x", i, enc, err, raw[i])
+		}
+	}
+	if root := DeriveSha(block.Transactions()); root != header.TxHash {
+		t.Errorf("tx root mismatch: have %x, want %x", root, header.TxHash)
+	}
+	ourBlockEnc, err := rlp.EncodeToBytes(&block)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	if !bytes.Equal(ourBlockEnc, blockEnc) {
+		t.Errorf("encoded block mismatch:\ngot:  %x\nwant: %x", ourBlockEnc, blockEnc)
+	}
+}
@@ -134,6 +134,7 @@ func (d *DposContext) Copy() *DposContext {
 		voteTrie:      &voteTrie,
 		candidateTrie: &candidateTrie,
 		mintCntTrie:   &mintCntTrie,
+		db:            d.db,
 	}
 }
 
@@ -382,10 +383,12 @@ func (dc *DposContext) SetVote(vote *trie.Trie)           { dc.voteTrie = vote }
 func (dc *DposContext) SetCandidate(candidate *trie.Trie) { dc.candidateTrie = candidate }
 func (dc *DposContext) SetMintCnt(mintCnt *trie.Trie)     { dc.mintCntTrie = mintCnt }
 
+//ValidatorsKey是验证人列表在epoch trie中的键，轻客户端用它验证默克尔证明
+var ValidatorsKey = []byte("validator")
+
 func (dc *DposContext) GetValidators() ([]common.Address, error) {
 	var validators []common.Address
-	key := []byte("validator")
-	validatorsRLP := dc.epochTrie.Get(key)
+	validatorsRLP := dc.epochTrie.Get(ValidatorsKey)
 	if err := rlp.DecodeBytes(validatorsRLP, &validators); err != nil {
 		return nil, fmt.Errorf("failed to decode validators: %s", err)
 	}
@@ -393,12 +396,11 @@ func (dc *DposContext) GetValidators() ([]common.Address, error) {
 }
 
 func (dc *DposContext) SetValidators(validators []common.Address) error {
-	key := []byte("validator")
 	validatorsRLP, err := rlp.EncodeToBytes(validators)
 	if err != nil {
 		return fmt.Errorf("failed to encode validators to rlp bytes: %s", err)
 	}
-	dc.epochTrie.Update(key, validatorsRLP)
+	dc.epochTrie.Update(ValidatorsKey, validatorsRLP)
 	return nil
 }
 
@@ -26,6 +26,7 @@ var _ = (*txdataMarshaling)(nil)
 
 func (t txdata) MarshalJSON() ([]byte, error) {
 	type txdata struct {
+		Type         hexutil.Uint64  `json:"action,omitempty" rlp:"-"`
 		AccountNonce hexutil.Uint64  `json:"nonce"    gencodec:"required"`
 		Price        *hexutil.Big    `json:"gasPrice" gencodec:"required"`
 		GasLimit     hexutil.Uint64  `json:"gas"      gencodec:"required"`
@@ -35,9 +36,15 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
-		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		ChainID      *hexutil.Big    `json:"chainId,omitempty"              rlp:"-"`
+		GasTipCap    *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+		GasFeeCap    *hexutil.Big    `json:"maxFeePerGas,omitempty"         rlp:"-"`
+		AccessList   AccessList      `json:"accessList,omitempty" rlp:"-"`
+		Hash         *common.Hash    `json:"hash"           rlp:"-"`
+		EnvelopeType *hexutil.Uint64 `json:"type,omitempty" rlp:"-"`
 	}
 	var enc txdata
+	enc.Type = hexutil.Uint64(t.Type)
 	enc.AccountNonce = hexutil.Uint64(t.AccountNonce)
 	enc.Price = (*hexutil.Big)(t.Price)
 	enc.GasLimit = hexutil.Uint64(t.GasLimit)
@@ -47,12 +54,18 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	enc.V = (*hexutil.Big)(t.V)
 	enc.R = (*hexutil.Big)(t.R)
 	enc.S = (*hexutil.Big)(t.S)
+	enc.ChainID = (*hexutil.Big)(t.ChainID)
+	enc.GasTipCap = (*hexutil.Big)(t.GasTipCap)
+	enc.GasFeeCap = (*hexutil.Big)(t.GasFeeCap)
+	enc.AccessList = t.AccessList
 	enc.Hash = t.Hash
+	enc.EnvelopeType = (*hexutil.Uint64)(t.EnvelopeType)
 	return json.Marshal(&enc)
 }
 
 func (t *txdata) UnmarshalJSON(input []byte) error {
 	type txdata struct {
+		Type         *hexutil.Uint64 `json:"action,omitempty" rlp:"-"`
 		AccountNonce *hexutil.Uint64 `json:"nonce"    gencodec:"required"`
 		Price        *hexutil.Big    `json:"gasPrice" gencodec:"required"`
 		GasLimit     *hexutil.Uint64 `json:"gas"      gencodec:"required"`
@@ -62,12 +75,20 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
-		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		ChainID      *hexutil.Big    `json:"chainId,omitempty"              rlp:"-"`
+		GasTipCap    *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+		GasFeeCap    *hexutil.Big    `json:"maxFeePerGas,omitempty"         rlp:"-"`
+		AccessList   *AccessList     `json:"accessList,omitempty" rlp:"-"`
+		Hash         *common.Hash    `json:"hash"           rlp:"-"`
+		EnvelopeType *hexutil.Uint64 `json:"type,omitempty" rlp:"-"`
 	}
 	var dec txdata
 	if err := json.Unmarshal(input, &dec); err != nil {
 		return err
 	}
+	if dec.Type != nil {
+		t.Type = TxType(*dec.Type)
+	}
 	if dec.AccountNonce == nil {
 		return errors.New("missing required field 'nonce' for txdata")
 	}
@@ -103,9 +124,24 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 's' for txdata")
 	}
 	t.S = (*big.Int)(dec.S)
+	if dec.ChainID != nil {
+		t.ChainID = (*big.Int)(dec.ChainID)
+	}
+	if dec.GasTipCap != nil {
+		t.GasTipCap = (*big.Int)(dec.GasTipCap)
+	}
+	if dec.GasFeeCap != nil {
+		t.GasFeeCap = (*big.Int)(dec.GasFeeCap)
+	}
+	if dec.AccessList != nil {
+		t.AccessList = *dec.AccessList
+	}
 	if dec.Hash != nil {
 		t.Hash = dec.Hash
 	}
+	if dec.EnvelopeType != nil {
+		t.EnvelopeType = (*uint64)(dec.EnvelopeType)
+	}
 	return nil
 }
 
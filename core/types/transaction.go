@@ -36,12 +36,23 @@ Delegate                     //用户为候选人投票
 UnDelegate                   //销售发票（授权委托书）
 )
 
+//交易信封的类型字节。传统转账和合约交易没有类型字节，按标准的RLP列表编码，
+//签名与普通钱包兼容；其余交易编码为类型字节 || rlp(负载)，在区块中作为RLP字符串出现。
+const (
+	LegacyTxType     = 0x00
+	AccessListTxType = 0x01
+	DynamicFeeTxType = 0x02
+//DPoS操作交易的类型字节，取在以太坊已分配的类型之外
+	DposTxType = 0x64
+)
+
 var (
-	ErrInvalidSig     = errors.New("invalid transaction v, r, s values")
-	errNoSigner       = errors.New("missing signing methods")
-	ErrInvalidType    = errors.New("invalid transaction type")
-	ErrInvalidAddress = errors.New("invalid transaction payload address")
-	ErrInvalidAction  = errors.New("invalid transaction payload action")
+	ErrInvalidSig         = errors.New("invalid transaction v, r, s values")
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	errNoSigner           = errors.New("missing signing methods")
+	ErrInvalidType        = errors.New("invalid transaction type")
+	ErrInvalidAddress     = errors.New("invalid transaction payload address")
+	ErrInvalidAction      = errors.New("invalid transaction payload action")
 )
 type Transaction struct {
 	data txdata
@@ -52,7 +63,7 @@ type Transaction struct {
 }
 
 type txdata struct {
-Type         TxType //累犯
+Type         TxType          `json:"action,omitempty" rlp:"-"` //DPoS操作，只出现在DposTxType信封中
 	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
 	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
 	GasLimit     uint64          `json:"gas"      gencodec:"required"`
@@ -65,8 +76,71 @@ Recipient    *common.Address `json:"to"       rlp:"nil"` //零是指合同创建
 	R *big.Int `json:"r" gencodec:"required"`
 	S *big.Int `json:"s" gencodec:"required"`
 
+//费用市场交易的字段，传统交易为nil
+	ChainID   *big.Int `json:"chainId,omitempty"              rlp:"-"`
+	GasTipCap *big.Int `json:"maxPriorityFeePerGas,omitempty" rlp:"-"`
+	GasFeeCap *big.Int `json:"maxFeePerGas,omitempty"         rlp:"-"`
+
+//访问列表交易和费用市场交易预先声明的访问列表，传统交易为nil
+	AccessList AccessList `json:"accessList,omitempty" rlp:"-"`
+
 //这仅在封送到JSON时使用。
-	Hash *common.Hash `json:"hash" rlp:"-"`
+	Hash         *common.Hash `json:"hash"           rlp:"-"`
+	EnvelopeType *uint64      `json:"type,omitempty" rlp:"-"`
+
+//交易信封分叉之前的编码，类型作为RLP列表的第一个元素
+	preEnvelope bool
+}
+
+//preEnvelopeTx是交易信封分叉之前的RLP编码格式，DPoS操作类型是列表的第一个元素，
+//普通交易该元素为零。签名不覆盖该元素。
+type preEnvelopeTx struct {
+	Type         TxType
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	V, R, S      *big.Int
+}
+
+//dynamicFeeTx是费用市场交易在类型字节之后的RLP编码格式
+type dynamicFeeTx struct {
+	ChainID   *big.Int
+	Nonce     uint64
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+	Gas       uint64
+	To        *common.Address `rlp:"nil"`
+	Value     *big.Int
+	Data      []byte
+	V, R, S   *big.Int
+}
+
+//accessListTx是访问列表交易在类型字节之后的RLP编码格式
+type accessListTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasPrice   *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"`
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+	V, R, S    *big.Int
+}
+
+//dposTx是DPoS操作交易在类型字节之后的RLP编码格式。DPoS操作不转账也不带数据，
+//所以负载中没有value和data。
+type dposTx struct {
+	ChainID  *big.Int
+	Action   TxType
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       *common.Address `rlp:"nil"`
+	V, R, S  *big.Int
 }
 
 type txdataMarshaling struct {
@@ -75,7 +149,11 @@ type txdataMarshaling struct {
 	GasLimit     hexutil.Uint64
 	Amount       *hexutil.Big
 	Payload      hexutil.Bytes
-	Type         TxType
+	ChainID      *hexutil.Big
+	GasTipCap    *hexutil.Big
+	GasFeeCap    *hexutil.Big
+	Type         hexutil.Uint64
+	EnvelopeType *hexutil.Uint64
 	V            *hexutil.Big
 	R            *hexutil.Big
 	S            *hexutil.Big
@@ -108,6 +186,7 @@ func newTransaction(txType TxType, nonce uint64, to *common.Address, amount *big
 		V:            new(big.Int),
 		R:            new(big.Int),
 		S:            new(big.Int),
+		preEnvelope:  true,
 	}
 	if amount != nil {
 		d.Amount.Set(amount)
@@ -119,11 +198,82 @@ func newTransaction(txType TxType, nonce uint64, to *common.Address, amount *big
 	return &Transaction{data: d}
 }
 
+//NewDynamicFeeTransaction创建费用市场交易。gasFeeCap是每单位燃气愿意支付的
+//最高费用，gasTipCap是其中最多付给出块者的小费，其余按区块的基础费用销毁。
+func NewDynamicFeeTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasTipCap, gasFeeCap *big.Int, data []byte) *Transaction {
+	tx := newTransaction(Binary, nonce, to, amount, gasLimit, gasFeeCap, data)
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.GasTipCap = new(big.Int)
+	if gasTipCap != nil {
+		tx.data.GasTipCap.Set(gasTipCap)
+	}
+	tx.data.GasFeeCap = new(big.Int).Set(tx.data.Price)
+	return tx
+}
+
+//NewAccessListTransaction创建访问列表交易。accessList中的帐户和存储槽在交易开始时
+//就按已访问计价。
+func NewAccessListTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList) *Transaction {
+	tx := newTransaction(Binary, nonce, to, amount, gasLimit, gasPrice, data)
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.AccessList = copyAccessList(accessList)
+	return tx
+}
+
+//copyAccessList深拷贝访问列表，结果总是非nil
+func copyAccessList(list AccessList) AccessList {
+	cpy := make(AccessList, len(list))
+	for i, tuple := range list {
+		cpy[i] = AccessTuple{
+			Address:     tuple.Address,
+			StorageKeys: append([]common.Hash{}, tuple.StorageKeys...),
+		}
+	}
+	return cpy
+}
+
 //chainID返回为此事务签名的链ID（如果有）
 func (tx *Transaction) ChainId() *big.Int {
+	if tx.EnvelopeType() != LegacyTxType {
+		if tx.data.ChainID == nil {
+			return new(big.Int)
+		}
+		return new(big.Int).Set(tx.data.ChainID)
+	}
 	return deriveChainId(tx.data.V)
 }
 
+//IsDynamicFee返回交易是否是费用市场交易
+func (tx *Transaction) IsDynamicFee() bool {
+	return tx.data.GasFeeCap != nil
+}
+
+//EnvelopeType返回交易信封的类型字节：费用市场交易为DynamicFeeTxType，
+//DPoS操作为DposTxType，带访问列表的为AccessListTxType，其余为LegacyTxType
+func (tx *Transaction) EnvelopeType() uint8 {
+	return envelopeType(&tx.data)
+}
+
+func envelopeType(data *txdata) uint8 {
+	switch {
+	case data.preEnvelope:
+		return LegacyTxType
+	case data.GasFeeCap != nil:
+		return DynamicFeeTxType
+	case data.Type != Binary:
+		return DposTxType
+	case data.AccessList != nil:
+		return AccessListTxType
+	}
+	return LegacyTxType
+}
+
 //当类型不是二进制时，该事务有效
 func (tx *Transaction) Validate() error {
 	if tx.Type() != Binary {
@@ -142,7 +292,7 @@ func (tx *Transaction) Validate() error {
 
 //protected返回事务是否受到重播保护。
 func (tx *Transaction) Protected() bool {
-	return isProtectedV(tx.data.V)
+	return tx.EnvelopeType() != LegacyTxType || isProtectedV(tx.data.V)
 }
 
 func isProtectedV(V *big.Int) bool {
@@ -154,20 +304,254 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-//encoderlp实现rlp.encoder
+//IsPreEnvelope返回交易是否使用交易信封分叉之前的编码
+func (tx *Transaction) IsPreEnvelope() bool {
+	return tx.data.preEnvelope
+}
+
+//WithEnvelope返回使用交易信封编码的交易副本，用于在交易信封分叉之后创建交易，
+//应在签名之前调用。NewTransaction和NewContractCreation默认使用分叉之前的编码，
+//MakeSigner返回的分叉之后的签名者在SignTx中自动转换。
+func (tx *Transaction) WithEnvelope() *Transaction {
+	if !tx.data.preEnvelope {
+		return tx
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.preEnvelope = false
+	return cpy
+}
+
+//encoderlp实现rlp.encoder。带类型的交易编码为包含类型字节和负载的RLP字符串。
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.data.preEnvelope {
+		return rlp.Encode(w, &preEnvelopeTx{
+			Type:         tx.data.Type,
+			AccountNonce: tx.data.AccountNonce,
+			Price:        tx.data.Price,
+			GasLimit:     tx.data.GasLimit,
+			Recipient:    tx.data.Recipient,
+			Amount:       tx.data.Amount,
+			Payload:      tx.data.Payload,
+			V:            tx.data.V,
+			R:            tx.data.R,
+			S:            tx.data.S,
+		})
+	}
+	if tx.EnvelopeType() == LegacyTxType {
+		return rlp.Encode(w, &tx.data)
+	}
+	enc, err := tx.encodeTyped()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
-//decoderlp实现rlp.解码器
+//decoderlp实现rlp.解码器。RLP列表按元素个数区分：交易信封分叉之前的编码有10个元素，
+//传统交易有9个元素。
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+	kind, _, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	if kind == rlp.List {
+		raw, err := s.Raw()
+		if err != nil {
+			return err
+		}
+		return tx.decodeList(raw)
+	}
+	b, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	return tx.decodeTyped(b)
+}
+
+//MarshalBinary返回交易的规范编码：传统交易是RLP列表，
+//带类型的交易是类型字节加负载，与交易哈希的原像相同。
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.EnvelopeType() == LegacyTxType {
+		return rlp.EncodeToBytes(tx)
+	}
+	return tx.encodeTyped()
+}
+
+//UnmarshalBinary解码MarshalBinary产生的规范编码
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		return tx.decodeList(b)
+	}
+	return tx.decodeTyped(b)
+}
+
+//decodeList解码交易信封分叉之前的编码或传统交易的RLP列表
+func (tx *Transaction) decodeList(b []byte) error {
+	content, _, err := rlp.SplitList(b)
+	if err != nil {
+		return err
+	}
+	count, err := rlp.CountValues(content)
+	if err != nil {
+		return err
+	}
+	if count == 10 {
+		var dec preEnvelopeTx
+		if err := rlp.DecodeBytes(b, &dec); err != nil {
+			return err
+		}
+		if dec.Type > UnDelegate {
+			return ErrInvalidType
+		}
+		tx.data = txdata{
+			Type:         dec.Type,
+			AccountNonce: dec.AccountNonce,
+			Price:        dec.Price,
+			GasLimit:     dec.GasLimit,
+			Recipient:    dec.Recipient,
+			Amount:       dec.Amount,
+			Payload:      dec.Payload,
+			V:            dec.V,
+			R:            dec.R,
+			S:            dec.S,
+			preEnvelope:  true,
+		}
+	} else {
+		var data txdata
+		if err := rlp.DecodeBytes(b, &data); err != nil {
+			return err
+		}
+		tx.data = data
+	}
+	tx.size.Store(common.StorageSize(len(b)))
+	return nil
+}
+
+//encodeTyped返回带类型交易的类型字节和负载
+func (tx *Transaction) encodeTyped() ([]byte, error) {
+	var inner interface{}
+	switch tx.EnvelopeType() {
+	case DynamicFeeTxType:
+		inner = &dynamicFeeTx{
+			ChainID:   tx.data.ChainID,
+			Nonce:     tx.data.AccountNonce,
+			GasTipCap: tx.data.GasTipCap,
+			GasFeeCap: tx.data.GasFeeCap,
+			Gas:       tx.data.GasLimit,
+			To:        tx.data.Recipient,
+			Value:     tx.data.Amount,
+			Data:      tx.data.Payload,
+			V:         tx.data.V,
+			R:         tx.data.R,
+			S:         tx.data.S,
+		}
+	case AccessListTxType:
+		inner = &accessListTx{
+			ChainID:    tx.data.ChainID,
+			Nonce:      tx.data.AccountNonce,
+			GasPrice:   tx.data.Price,
+			Gas:        tx.data.GasLimit,
+			To:         tx.data.Recipient,
+			Value:      tx.data.Amount,
+			Data:       tx.data.Payload,
+			AccessList: tx.data.AccessList,
+			V:          tx.data.V,
+			R:          tx.data.R,
+			S:          tx.data.S,
+		}
+	case DposTxType:
+		inner = &dposTx{
+			ChainID:  tx.data.ChainID,
+			Action:   tx.data.Type,
+			Nonce:    tx.data.AccountNonce,
+			GasPrice: tx.data.Price,
+			Gas:      tx.data.GasLimit,
+			To:       tx.data.Recipient,
+			V:        tx.data.V,
+			R:        tx.data.R,
+			S:        tx.data.S,
+		}
+	default:
+		return nil, ErrTxTypeNotSupported
+	}
+	payload, err := rlp.EncodeToBytes(inner)
+	if err != nil {
+		return nil, err
 	}
+	return append([]byte{tx.EnvelopeType()}, payload...), nil
+}
 
-	return err
+//decodeTyped解码类型字节和负载
+func (tx *Transaction) decodeTyped(b []byte) error {
+	if len(b) == 0 {
+		return errors.New("typed transaction too short")
+	}
+	switch b[0] {
+	case DynamicFeeTxType:
+		var dec dynamicFeeTx
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			Type:         Binary,
+			AccountNonce: dec.Nonce,
+			Price:        dec.GasFeeCap,
+			GasLimit:     dec.Gas,
+			Recipient:    dec.To,
+			Amount:       dec.Value,
+			Payload:      dec.Data,
+			V:            dec.V,
+			R:            dec.R,
+			S:            dec.S,
+			ChainID:      dec.ChainID,
+			GasTipCap:    dec.GasTipCap,
+			GasFeeCap:    dec.GasFeeCap,
+		}
+	case AccessListTxType:
+		var dec accessListTx
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			Type:         Binary,
+			AccountNonce: dec.Nonce,
+			Price:        dec.GasPrice,
+			GasLimit:     dec.Gas,
+			Recipient:    dec.To,
+			Amount:       dec.Value,
+			Payload:      dec.Data,
+			V:            dec.V,
+			R:            dec.R,
+			S:            dec.S,
+			ChainID:      dec.ChainID,
+			AccessList:   copyAccessList(dec.AccessList),
+		}
+	case DposTxType:
+		var dec dposTx
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+//DPoS信封只能承载DPoS操作，普通交易必须使用传统编码
+		if dec.Action == Binary || dec.Action > UnDelegate {
+			return ErrInvalidType
+		}
+		tx.data = txdata{
+			Type:         dec.Action,
+			AccountNonce: dec.Nonce,
+			Price:        dec.GasPrice,
+			GasLimit:     dec.Gas,
+			Recipient:    dec.To,
+			Amount:       new(big.Int),
+			V:            dec.V,
+			R:            dec.R,
+			S:            dec.S,
+			ChainID:      dec.ChainID,
+		}
+	default:
+		return ErrTxTypeNotSupported
+	}
+	tx.size.Store(common.StorageSize(len(b)))
+	return nil
 }
 
 //marshaljson编码Web3 RPC事务格式。
@@ -175,6 +559,11 @@ func (tx *Transaction) MarshalJSON() ([]byte, error) {
 	hash := tx.Hash()
 	data := tx.data
 	data.Hash = &hash
+//交易信封分叉之前的交易没有type字段
+	if !tx.data.preEnvelope {
+		envelope := uint64(tx.EnvelopeType())
+		data.EnvelopeType = &envelope
+	}
 	return data.MarshalJSON()
 }
 
@@ -184,8 +573,38 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if err := dec.UnmarshalJSON(input); err != nil {
 		return err
 	}
+	if dec.Type != Binary && (dec.GasFeeCap != nil || dec.AccessList != nil) {
+		return ErrInvalidType
+	}
+//访问列表只出现在访问列表交易中
+	if dec.GasFeeCap != nil && dec.AccessList != nil {
+		return ErrTxTypeNotSupported
+	}
+//没有type字段的是交易信封分叉之前的交易
+	if dec.EnvelopeType == nil && dec.GasFeeCap == nil && dec.AccessList == nil {
+		dec.preEnvelope = true
+	}
+//空访问列表在JSON中被省略
+	if dec.EnvelopeType != nil && *dec.EnvelopeType == AccessListTxType && dec.AccessList == nil {
+		dec.AccessList = AccessList{}
+	}
+	typ := envelopeType(&dec)
+	if dec.EnvelopeType != nil && *dec.EnvelopeType != uint64(typ) {
+		return ErrTxTypeNotSupported
+	}
 	var V byte
-	if isProtectedV(dec.V) {
+	if typ != LegacyTxType {
+		if typ == DynamicFeeTxType && dec.GasTipCap == nil {
+			return errors.New("missing fee market fields for dynamic fee transaction")
+		}
+		if dec.ChainID == nil {
+			return errors.New("missing chainId for typed transaction")
+		}
+		if dec.V.BitLen() > 8 {
+			return ErrInvalidSig
+		}
+		V = byte(dec.V.Uint64())
+	} else if isProtectedV(dec.V) {
 		chainID := deriveChainId(dec.V).Uint64()
 		V = byte(dec.V.Uint64() - 35 - 2*chainID)
 	} else {
@@ -206,6 +625,38 @@ func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
 func (tx *Transaction) CheckNonce() bool   { return true }
 func (tx *Transaction) Type() TxType       { return tx.data.Type }
 
+//AccessList返回交易的访问列表，传统交易和DPoS操作为nil
+func (tx *Transaction) AccessList() AccessList { return tx.data.AccessList }
+
+//GasFeeCap返回每单位燃气愿意支付的最高费用，传统交易即燃气价格
+func (tx *Transaction) GasFeeCap() *big.Int {
+	if tx.data.GasFeeCap == nil {
+		return new(big.Int).Set(tx.data.Price)
+	}
+	return new(big.Int).Set(tx.data.GasFeeCap)
+}
+
+//GasTipCap返回每单位燃气最多付给出块者的小费，传统交易即燃气价格
+func (tx *Transaction) GasTipCap() *big.Int {
+	if tx.data.GasTipCap == nil {
+		return new(big.Int).Set(tx.data.Price)
+	}
+	return new(big.Int).Set(tx.data.GasTipCap)
+}
+
+//EffectiveGasTip返回在给定基础费用下每单位燃气实际付给出块者的小费，
+//基础费用高于最高费用时结果为负。baseFee为nil时返回GasTipCap。
+func (tx *Transaction) EffectiveGasTip(baseFee *big.Int) *big.Int {
+	tip := tx.GasTipCap()
+	if baseFee == nil {
+		return tip
+	}
+	if room := new(big.Int).Sub(tx.GasFeeCap(), baseFee); room.Cmp(tip) < 0 {
+		return room
+	}
+	return tip
+}
+
 //返回事务的收件人地址。
 //如果交易是合同创建，则返回零。
 func (tx *Transaction) To() *common.Address {
@@ -222,7 +673,13 @@ func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.EnvelopeType() != LegacyTxType {
+		enc, _ := tx.encodeTyped()
+		v = crypto.Keccak256Hash(enc)
+	} else {
+		v = rlpHash(tx)
+	}
 	tx.hash.Store(v)
 	return v
 }
@@ -234,7 +691,12 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	if tx.EnvelopeType() != LegacyTxType {
+		enc, _ := tx.encodeTyped()
+		c = writeCounter(len(enc))
+	} else {
+		rlp.Encode(&c, tx)
+	}
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -249,10 +711,13 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		nonce:      tx.data.AccountNonce,
 		gasLimit:   tx.data.GasLimit,
 		gasPrice:   new(big.Int).Set(tx.data.Price),
+		gasFeeCap:  tx.GasFeeCap(),
+		gasTipCap:  tx.GasTipCap(),
 		to:         tx.data.Recipient,
 		amount:     tx.data.Amount,
 		data:       tx.data.Payload,
 		txType:     tx.data.Type,
+		accessList: tx.data.AccessList,
 		checkNonce: true,
 	}
 
@@ -269,6 +734,10 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 		return nil, err
 	}
 	cpy := &Transaction{data: tx.data}
+//DPoS操作交易的链ID在签名时确定
+	if cpy.EnvelopeType() == DposTxType {
+		cpy.data.ChainID = signer.ChainID()
+	}
 	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
 	return cpy, nil
 }
@@ -346,55 +815,87 @@ func (s *TxByPrice) Pop() interface{} {
 	return x
 }
 
+//TxByTip按给定基础费用下付给出块者的实际小费实现堆接口，
+//供打包时在费用市场分叉之后排序。
+type TxByTip struct {
+	txs     Transactions
+	baseFee *big.Int
+}
+
+func (s TxByTip) Len() int { return len(s.txs) }
+func (s TxByTip) Less(i, j int) bool {
+	return s.txs[i].EffectiveGasTip(s.baseFee).Cmp(s.txs[j].EffectiveGasTip(s.baseFee)) > 0
+}
+func (s TxByTip) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
+
+func (s *TxByTip) Push(x interface{}) {
+	s.txs = append(s.txs, x.(*Transaction))
+}
+
+func (s *TxByTip) Pop() interface{} {
+	old := s.txs
+	n := len(old)
+	x := old[n-1]
+	s.txs = old[0 : n-1]
+	return x
+}
+
 //TransactionsByPriceAndOnce表示一组可以返回的事务
 //按利润最大化排序的交易，同时支持删除
 //不可执行帐户的整批事务。
 type TransactionsByPriceAndNonce struct {
-txs    map[common.Address]Transactions //按科目当前排序的交易记录列表
-heads  TxByPrice                       //每个唯一帐户的下一个事务（价格堆）
-signer Signer                          //事务集的签名者
+txs     map[common.Address]Transactions //按科目当前排序的交易记录列表
+heads   TxByTip                         //每个唯一帐户的下一个事务（小费堆）
+signer  Signer                          //事务集的签名者
+baseFee *big.Int                        //排序所用的基础费用，分叉之前为nil
 }
 
 //newTransactionByPriceAndOnce创建一个可以检索
-//以不兑现的方式对交易进行价格排序。
+//以不兑现的方式按baseFee下的实际小费对交易排序。最高费用
+//低于baseFee的交易无法打包，其帐户的后续交易一并跳过。
 //
 //注意，输入映射是重新拥有的，因此调用方不应再与
 //如果在提供给构造函数之后。
-func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions) *TransactionsByPriceAndNonce {
-//用头事务初始化基于价格的堆
-	heads := make(TxByPrice, 0, len(txs))
+func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, baseFee *big.Int) *TransactionsByPriceAndNonce {
+//用头事务初始化基于小费的堆
+	heads := TxByTip{txs: make(Transactions, 0, len(txs)), baseFee: baseFee}
 	for from, accTxs := range txs {
-		heads = append(heads, accTxs[0])
 //确保发件人地址来自签名者
 		acc, _ := Sender(signer, accTxs[0])
-		txs[acc] = accTxs[1:]
 		if from != acc {
 			delete(txs, from)
 		}
+		if accTxs[0].EffectiveGasTip(baseFee).Sign() < 0 {
+			delete(txs, acc)
+			continue
+		}
+		heads.txs = append(heads.txs, accTxs[0])
+		txs[acc] = accTxs[1:]
 	}
 	heap.Init(&heads)
 
 //组装并返回事务集
 	return &TransactionsByPriceAndNonce{
-		txs:    txs,
-		heads:  heads,
-		signer: signer,
+		txs:     txs,
+		heads:   heads,
+		signer:  signer,
+		baseFee: baseFee,
 	}
 }
 
-//Peek按价格返回下一个交易。
+//Peek按实际小费返回下一个交易。
 func (t *TransactionsByPriceAndNonce) Peek() *Transaction {
-	if len(t.heads) == 0 {
+	if len(t.heads.txs) == 0 {
 		return nil
 	}
-	return t.heads[0]
+	return t.heads.txs[0]
 }
 
 //SHIFT将当前的最佳标题替换为同一帐户中的下一个标题。
 func (t *TransactionsByPriceAndNonce) Shift() {
-	acc, _ := Sender(t.signer, t.heads[0])
-	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
-		t.heads[0], t.txs[acc] = txs[0], txs[1:]
+	acc, _ := Sender(t.signer, t.heads.txs[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 && txs[0].EffectiveGasTip(t.baseFee).Sign() >= 0 {
+		t.heads.txs[0], t.txs[acc] = txs[0], txs[1:]
 		heap.Fix(&t.heads, 0)
 	} else {
 		heap.Pop(&t.heads)
@@ -418,12 +919,15 @@ type Message struct {
 	amount     *big.Int
 	gasLimit   uint64
 	gasPrice   *big.Int
+	gasFeeCap  *big.Int
+	gasTipCap  *big.Int
 	data       []byte
+	accessList AccessList
 	checkNonce bool
 	txType                  TxType
 }
 
-func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool) Message {
+func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList, checkNonce bool) Message {
 	return Message{
 		from:       from,
 		to:         to,
@@ -431,18 +935,24 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 		amount:     amount,
 		gasLimit:   gasLimit,
 		gasPrice:   gasPrice,
+		gasFeeCap:  gasPrice,
+		gasTipCap:  gasPrice,
 		data:       data,
+		accessList: accessList,
 		checkNonce: checkNonce,
 	}
 }
 
-func (m Message) From() common.Address { return m.from }
-func (m Message) To() *common.Address  { return m.to }
-func (m Message) GasPrice() *big.Int   { return m.gasPrice }
-func (m Message) Value() *big.Int      { return m.amount }
-func (m Message) Gas() uint64          { return m.gasLimit }
-func (m Message) Nonce() uint64        { return m.nonce }
-func (m Message) Data() []byte         { return m.data }
-func (m Message) CheckNonce() bool     { return m.checkNonce }
-func (m Message) Type() TxType         { return m.txType }
+func (m Message) From() common.Address   { return m.from }
+func (m Message) To() *common.Address    { return m.to }
+func (m Message) GasPrice() *big.Int     { return m.gasPrice }
+func (m Message) GasFeeCap() *big.Int    { return m.gasFeeCap }
+func (m Message) GasTipCap() *big.Int    { return m.gasTipCap }
+func (m Message) Value() *big.Int        { return m.amount }
+func (m Message) Gas() uint64            { return m.gasLimit }
+func (m Message) Nonce() uint64          { return m.nonce }
+func (m Message) Data() []byte           { return m.data }
+func (m Message) AccessList() AccessList { return m.accessList }
+func (m Message) CheckNonce() bool       { return m.checkNonce }
+func (m Message) Type() TxType           { return m.txType }
 
@@ -21,6 +21,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var (
@@ -35,11 +36,19 @@ type sigCache struct {
 }
 
 //MakeSigner根据给定的链配置和块号返回一个签名者。
+//交易信封分叉之后的签名者在SignTx中把旧编码的交易转换为信封编码。
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
+	eip155 := NewEIP155Signer(config.ChainID)
+	eip155.envelope = config.IsTxEnvelope(blockNumber)
+
 	var signer Signer
 	switch {
+	case config.IsFeeMarket(blockNumber):
+		signer = FeeMarketSigner{AccessListSigner{eip155}}
+	case config.IsAccessList(blockNumber):
+		signer = AccessListSigner{eip155}
 	case config.IsEIP155(blockNumber):
-		signer = NewEIP155Signer(config.ChainID)
+		signer = eip155
 	case config.IsHomestead(blockNumber):
 		signer = HomesteadSigner{}
 	default:
@@ -50,6 +59,9 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 
 //signtx使用给定的签名者和私钥对事务进行签名
 func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	if e, ok := s.(interface{ txEnvelope() bool }); ok && e.txEnvelope() {
+		tx = tx.WithEnvelope()
+	}
 	h := s.Hash(tx)
 	sig, err := crypto.Sign(h[:], prv)
 	if err != nil {
@@ -96,11 +108,132 @@ type Signer interface {
 	Hash(tx *Transaction) common.Hash
 //如果给定的签名者与接收者相同，则equal返回true。
 	Equal(Signer) bool
+//ChainID返回签名者的链ID，不区分链的签名者返回nil。
+	ChainID() *big.Int
+}
+
+//AccessListSigner在EIP155规则之外接受访问列表交易。访问列表交易的签名
+//覆盖类型字节和负载，V是0或1。
+type AccessListSigner struct{ EIP155Signer }
+
+func NewAccessListSigner(chainId *big.Int) AccessListSigner {
+	return AccessListSigner{NewEIP155Signer(chainId)}
+}
+
+func (s AccessListSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(AccessListSigner)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+func (s AccessListSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.EnvelopeType() != AccessListTxType {
+		return s.EIP155Signer.Sender(tx)
+	}
+	if tx.data.ChainID.Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+//V是0或1，恢复时按27或28处理
+	V := new(big.Int).Add(tx.data.V, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+}
+
+func (s AccessListSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.EnvelopeType() != AccessListTxType {
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	}
+	if tx.data.ChainID.Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _, err = FrontierSigner{}.signatureValues(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
 }
 
-//EIP155事务使用EIP155规则实现签名者。
+//hash返回发送方要签名的哈希，访问列表交易为类型字节和不含签名的负载的哈希。
+func (s AccessListSigner) Hash(tx *Transaction) common.Hash {
+	if tx.EnvelopeType() != AccessListTxType {
+		return s.EIP155Signer.Hash(tx)
+	}
+	payload, _ := rlp.EncodeToBytes([]interface{}{
+		s.chainId,
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		tx.data.AccessList,
+	})
+	return crypto.Keccak256Hash([]byte{AccessListTxType}, payload)
+}
+
+//FeeMarketSigner在访问列表规则之外接受费用市场交易。费用市场交易的签名
+//覆盖类型字节和负载，V是0或1。
+type FeeMarketSigner struct{ AccessListSigner }
+
+func NewFeeMarketSigner(chainId *big.Int) FeeMarketSigner {
+	return FeeMarketSigner{NewAccessListSigner(chainId)}
+}
+
+func (s FeeMarketSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(FeeMarketSigner)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+func (s FeeMarketSigner) Sender(tx *Transaction) (common.Address, error) {
+	if !tx.IsDynamicFee() {
+		return s.AccessListSigner.Sender(tx)
+	}
+	if tx.data.ChainID.Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+//V是0或1，恢复时按27或28处理
+	V := new(big.Int).Add(tx.data.V, big.NewInt(27))
+	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+}
+
+func (s FeeMarketSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if !tx.IsDynamicFee() {
+		return s.AccessListSigner.SignatureValues(tx, sig)
+	}
+	if tx.data.ChainID.Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _, err = FrontierSigner{}.signatureValues(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+//hash返回发送方要签名的哈希，费用市场交易为类型字节和不含签名的负载的哈希。
+func (s FeeMarketSigner) Hash(tx *Transaction) common.Hash {
+	if !tx.IsDynamicFee() {
+		return s.AccessListSigner.Hash(tx)
+	}
+	payload, _ := rlp.EncodeToBytes([]interface{}{
+		s.chainId,
+		tx.data.AccountNonce,
+		tx.data.GasTipCap,
+		tx.data.GasFeeCap,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+	})
+	return crypto.Keccak256Hash([]byte{DynamicFeeTxType}, payload)
+}
+
+//EIP155事务使用EIP155规则实现签名者。DPoS操作交易使用DposTxType信封，
+//签名覆盖类型字节和不含签名的负载，V是0或1。
 type EIP155Signer struct {
 	chainId, chainIdMul *big.Int
+
+	envelope bool //交易信封分叉之后签名，由MakeSigner设置
 }
 
 func NewEIP155Signer(chainId *big.Int) EIP155Signer {
@@ -118,9 +251,29 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 	return ok && eip155.chainId.Cmp(s.chainId) == 0
 }
 
+func (s EIP155Signer) ChainID() *big.Int {
+	return new(big.Int).Set(s.chainId)
+}
+
+//txEnvelope返回签名者所在区块是否在交易信封分叉之后
+func (s EIP155Signer) txEnvelope() bool {
+	return s.envelope
+}
+
 var big8 = big.NewInt(8)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	switch tx.EnvelopeType() {
+	case DynamicFeeTxType, AccessListTxType:
+		return common.Address{}, ErrTxTypeNotSupported
+	case DposTxType:
+		if tx.ChainId().Cmp(s.chainId) != 0 {
+			return common.Address{}, ErrInvalidChainId
+		}
+//V是0或1，恢复时按27或28处理
+		V := new(big.Int).Add(tx.data.V, big.NewInt(27))
+		return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
@@ -135,6 +288,13 @@ func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
 //WithSignature返回具有给定签名的新事务。这个签名
 //需要采用[R V]格式，其中V为0或1。
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	switch tx.EnvelopeType() {
+	case DynamicFeeTxType, AccessListTxType:
+		return nil, nil, nil, ErrTxTypeNotSupported
+	case DposTxType:
+		R, S, _, err = FrontierSigner{}.signatureValues(sig)
+		return R, S, big.NewInt(int64(sig[64])), err
+	}
 	R, S, V, err = HomesteadSigner{}.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -149,6 +309,18 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 //hash返回发送方要签名的哈希。
 //它不能唯一标识事务。
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
+	if tx.EnvelopeType() == DposTxType {
+//DPoS操作的签名覆盖类型字节、链ID和操作类型
+		payload, _ := rlp.EncodeToBytes([]interface{}{
+			s.chainId,
+			tx.data.Type,
+			tx.data.AccountNonce,
+			tx.data.Price,
+			tx.data.GasLimit,
+			tx.data.Recipient,
+		})
+		return crypto.Keccak256Hash([]byte{DposTxType}, payload)
+	}
 	return rlpHash([]interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
@@ -169,6 +341,8 @@ func (s HomesteadSigner) Equal(s2 Signer) bool {
 	return ok
 }
 
+func (s HomesteadSigner) ChainID() *big.Int { return nil }
+
 //SignatureValues返回签名值。这个签名
 //需要采用[R V]格式，其中V为0或1。
 func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error) {
@@ -176,6 +350,9 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.EnvelopeType() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(hs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
 }
 
@@ -186,9 +363,18 @@ func (s FrontierSigner) Equal(s2 Signer) bool {
 	return ok
 }
 
+func (s FrontierSigner) ChainID() *big.Int { return nil }
+
 //SignatureValues返回签名值。这个签名
 //需要采用[R V]格式，其中V为0或1。
 func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error) {
+	if tx.EnvelopeType() != LegacyTxType {
+		return nil, nil, nil, ErrTxTypeNotSupported
+	}
+	return fs.signatureValues(sig)
+}
+
+func (fs FrontierSigner) signatureValues(sig []byte) (r, s, v *big.Int, err error) {
 	if len(sig) != 65 {
 		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
 	}
@@ -212,6 +398,9 @@ func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.EnvelopeType() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return recoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
 }
 
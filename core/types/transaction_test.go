@@ -17,6 +17,7 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -140,7 +141,7 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 		}
 	}
 //对事务进行排序并交叉检查非紧急排序
-	txset := NewTransactionsByPriceAndNonce(signer, groups)
+	txset := NewTransactionsByPriceAndNonce(signer, groups, nil)
 
 	txs := Transactions{}
 	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
@@ -173,6 +174,38 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 	}
 }
 
+//测试费用市场分叉之后按基础费用下的实际小费排序，而不是按最高费用，
+//最高费用低于基础费用的帐户整批跳过。
+func TestTransactionTipSort(t *testing.T) {
+	var (
+		signer  = NewFeeMarketSigner(common.Big1)
+		baseFee = big.NewInt(10)
+		to      = common.Address{1}
+		groups  = map[common.Address]Transactions{}
+	)
+	fees := []struct{ tip, cap int64 }{
+		{tip: 5, cap: 100}, //小费5
+		{tip: 8, cap: 13},  //最高费用限制，小费3
+		{tip: 4, cap: 14},  //小费4
+		{tip: 1, cap: 9},   //低于基础费用
+	}
+	for _, fee := range fees {
+		key, _ := crypto.GenerateKey()
+		tx, _ := SignTx(NewDynamicFeeTransaction(common.Big1, 0, &to, common.Big0, 21000, big.NewInt(fee.tip), big.NewInt(fee.cap), nil), signer, key)
+		groups[crypto.PubkeyToAddress(key.PublicKey)] = Transactions{tx}
+	}
+	txset := NewTransactionsByPriceAndNonce(signer, groups, baseFee)
+
+	var tips []int64
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		tips = append(tips, tx.EffectiveGasTip(baseFee).Int64())
+		txset.Shift()
+	}
+	if want := []int64{5, 4, 3}; !reflect.DeepEqual(tips, want) {
+		t.Errorf("tip ordering mismatch: have %v, want %v", tips, want)
+	}
+}
+
 //TestTransactionJSON测试对JSON进行序列化/反序列化。
 func TestTransactionJSON(t *testing.T) {
 	key, err := crypto.GenerateKey()
@@ -215,3 +248,184 @@ func TestTransactionJSON(t *testing.T) {
 	}
 }
 
+
+func TestDynamicFeeTransaction(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewFeeMarketSigner(common.Big1)
+
+	to := common.Address{1}
+	tx, err := SignTx(NewDynamicFeeTransaction(common.Big1, 3, &to, common.Big2, 21000, big.NewInt(2), big.NewInt(10), []byte("abcdef")), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if _, err := Sender(NewEIP155Signer(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("eip155 signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+//小费受限于最高费用减去基础费用
+	if tip := tx.EffectiveGasTip(big.NewInt(9)); tip.Cmp(common.Big1) != 0 {
+		t.Errorf("effective tip mismatch: have %v, want 1", tip)
+	}
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("could not encode transaction: %v", err)
+	}
+	if blob[0] != DynamicFeeTxType {
+		t.Fatalf("type byte mismatch: have %#x, want %#x", blob[0], DynamicFeeTxType)
+	}
+	parsed := new(Transaction)
+	if err := parsed.UnmarshalBinary(blob); err != nil {
+		t.Fatalf("could not decode transaction: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", parsed.Hash(), tx.Hash())
+	}
+	if parsed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 || parsed.GasTipCap().Cmp(tx.GasTipCap()) != 0 {
+		t.Errorf("fee mismatch: have %v/%v, want %v/%v", parsed.GasFeeCap(), parsed.GasTipCap(), tx.GasFeeCap(), tx.GasTipCap())
+	}
+//在区块体中作为RLP字符串编码
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatalf("could not rlp encode transaction: %v", err)
+	}
+	parsed = new(Transaction)
+	if err := rlp.DecodeBytes(enc, parsed); err != nil {
+		t.Fatalf("could not rlp decode transaction: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() {
+		t.Errorf("rlp hash mismatch: have %x, want %x", parsed.Hash(), tx.Hash())
+	}
+}
+
+func TestAccessListTransaction(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewAccessListSigner(common.Big1)
+
+	to := common.Address{1}
+	acl := AccessList{{Address: common.Address{2}, StorageKeys: []common.Hash{{1}, {2}}}}
+	tx, err := SignTx(NewAccessListTransaction(common.Big1, 3, &to, common.Big2, 30000, big.NewInt(10), nil, acl), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+//费用市场签名者同样接受访问列表交易
+	if from, err := Sender(NewFeeMarketSigner(common.Big1), tx); err != nil || from != addr {
+		t.Fatalf("fee market sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if _, err := Sender(NewEIP155Signer(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("eip155 signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("could not encode transaction: %v", err)
+	}
+	if blob[0] != AccessListTxType {
+		t.Fatalf("type byte mismatch: have %#x, want %#x", blob[0], AccessListTxType)
+	}
+	parsed := new(Transaction)
+	if err := parsed.UnmarshalBinary(blob); err != nil {
+		t.Fatalf("could not decode transaction: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", parsed.Hash(), tx.Hash())
+	}
+	if got := parsed.AccessList(); len(got) != 1 || got.StorageKeys() != 2 || got[0].Address != acl[0].Address {
+		t.Errorf("access list mismatch: have %v, want %v", got, acl)
+	}
+//空访问列表在JSON往返之后仍是访问列表交易
+	empty, err := SignTx(NewAccessListTransaction(common.Big1, 4, &to, common.Big0, 21000, big.NewInt(10), nil, nil), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	enc, err := json.Marshal(empty)
+	if err != nil {
+		t.Fatalf("could not json encode transaction: %v", err)
+	}
+	parsed = new(Transaction)
+	if err := json.Unmarshal(enc, parsed); err != nil {
+		t.Fatalf("could not json decode transaction: %v", err)
+	}
+	if parsed.EnvelopeType() != AccessListTxType || parsed.Hash() != empty.Hash() {
+		t.Errorf("json roundtrip mismatch: have type %d hash %x, want type %d hash %x", parsed.EnvelopeType(), parsed.Hash(), AccessListTxType, empty.Hash())
+	}
+}
+
+func TestDposTransactionEnvelope(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewEIP155Signer(common.Big1)
+
+//交易信封分叉之前的签名者保留默认的旧编码
+	tx, err := SignTx(NewTransaction(Delegate, 5, common.Address{1}, common.Big0, 100000, common.Big1, nil), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if !tx.IsPreEnvelope() || tx.EnvelopeType() != LegacyTxType {
+		t.Fatalf("pre-fork signer changed the encoding: have envelope type %d", tx.EnvelopeType())
+	}
+	signer.envelope = true
+	tx, err = SignTx(NewTransaction(Delegate, 5, common.Address{1}, common.Big0, 100000, common.Big1, nil), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if tx.IsPreEnvelope() {
+		t.Fatalf("post-fork signer kept the pre-envelope encoding")
+	}
+	if tx.ChainId().Cmp(common.Big1) != 0 {
+		t.Errorf("chain id mismatch: have %v, want 1", tx.ChainId())
+	}
+	if from, err := Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	if _, err := Sender(NewEIP155Signer(common.Big2), tx); err != ErrInvalidChainId {
+		t.Errorf("foreign chain error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	if _, err := Sender(HomesteadSigner{}, tx); err != ErrTxTypeNotSupported {
+		t.Errorf("homestead signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("could not encode transaction: %v", err)
+	}
+	if blob[0] != DposTxType {
+		t.Fatalf("type byte mismatch: have %#x, want %#x", blob[0], DposTxType)
+	}
+	parsed := new(Transaction)
+	if err := parsed.UnmarshalBinary(blob); err != nil {
+		t.Fatalf("could not decode transaction: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() || parsed.Type() != Delegate {
+		t.Errorf("decoded transaction mismatch: have %x/%d, want %x/%d", parsed.Hash(), parsed.Type(), tx.Hash(), Delegate)
+	}
+//篡改操作类型会改变签名者
+	tampered := &Transaction{data: parsed.data}
+	tampered.data.Type = UnDelegate
+	if from, err := Sender(signer, tampered); err == nil && from == addr {
+		t.Errorf("tampered action recovered original sender")
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	parsed = new(Transaction)
+	if err := json.Unmarshal(data, parsed); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if parsed.Hash() != tx.Hash() {
+		t.Errorf("json hash mismatch: have %x, want %x", parsed.Hash(), tx.Hash())
+	}
+//传统交易仍然是标准的RLP列表
+	legacy, err := SignTx(NewTransaction(Binary, 5, common.Address{1}, common.Big1, 21000, common.Big1, nil), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if blob, _ = legacy.MarshalBinary(); blob[0] < 0xc0 {
+		t.Errorf("legacy transaction not encoded as list: %x", blob)
+	}
+	if legacy.Hash() != rlpHash(legacy) {
+		t.Errorf("legacy hash mismatch: have %x, want %x", legacy.Hash(), rlpHash(legacy))
+	}
+}
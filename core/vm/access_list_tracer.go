@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//AccessListTracer是记录执行期间访问的帐户和存储槽的跟踪程序，用于生成交易的访问列表。
+//发送者、接收者和预编译合约一开始就按已访问计价，不单独列入，但它们的存储槽照常列入。
+type AccessListTracer struct {
+excl        map[common.Address]struct{}                 //不需要列入的帐户
+list        map[common.Address]map[common.Hash]struct{} //已记录的帐户和存储槽
+precompiles bool                                        //预编译合约是否已加入excl
+}
+
+//NewAccessListTracer创建跟踪程序，acl中的项从一开始就在记录中
+func NewAccessListTracer(acl types.AccessList) *AccessListTracer {
+	t := &AccessListTracer{
+		list: make(map[common.Address]map[common.Hash]struct{}),
+	}
+	for _, tuple := range acl {
+		t.addAddress(tuple.Address)
+		for _, key := range tuple.StorageKeys {
+			t.addSlot(tuple.Address, key)
+		}
+	}
+	return t
+}
+
+func (t *AccessListTracer) addAddress(addr common.Address) {
+	if _, ok := t.list[addr]; !ok {
+		t.list[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+func (t *AccessListTracer) addSlot(addr common.Address, slot common.Hash) {
+	t.addAddress(addr)
+	t.list[addr][slot] = struct{}{}
+}
+
+func (t *AccessListTracer) touchAddress(addr common.Address) {
+	if _, ok := t.excl[addr]; !ok {
+		t.addAddress(addr)
+	}
+}
+
+func (t *AccessListTracer) CaptureStart(from common.Address, to common.Address, call bool, input []byte, gas uint64, value *big.Int) error {
+	t.excl = map[common.Address]struct{}{from: {}, to: {}}
+	t.precompiles = false
+	return nil
+}
+
+func (t *AccessListTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+//预编译合约与区块有关，第一次执行指令时才能确定
+	if !t.precompiles {
+		for _, addr := range env.ActivePrecompiles() {
+			t.excl[addr] = struct{}{}
+		}
+		t.precompiles = true
+	}
+	switch op {
+	case SLOAD, SSTORE:
+		if stack.len() >= 1 {
+			t.addSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+		}
+	case BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH, SELFDESTRUCT:
+		if stack.len() >= 1 {
+			t.touchAddress(common.BigToAddress(stack.Back(0)))
+		}
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		if stack.len() >= 2 {
+			t.touchAddress(common.BigToAddress(stack.Back(1)))
+		}
+	}
+	return nil
+}
+
+func (t *AccessListTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, tm time.Duration, err error) error {
+	return nil
+}
+
+//AccessList返回记录的帐户和存储槽
+func (t *AccessListTracer) AccessList() types.AccessList {
+	acl := make(types.AccessList, 0, len(t.list))
+	for addr, slots := range t.list {
+		tuple := types.AccessTuple{Address: addr, StorageKeys: []common.Hash{}}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		acl = append(acl, tuple)
+	}
+	return acl
+}
+
+//Equal返回两个跟踪程序记录的帐户和存储槽是否相同
+func (t *AccessListTracer) Equal(other *AccessListTracer) bool {
+	if len(t.list) != len(other.list) {
+		return false
+	}
+	for addr, slots := range t.list {
+		otherSlots, ok := other.list[addr]
+		if !ok || len(slots) != len(otherSlots) {
+			return false
+		}
+		for slot := range slots {
+			if _, ok := otherSlots[slot]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
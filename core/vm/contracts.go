@@ -13,15 +13,18 @@
 package vm
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/bn256"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -55,6 +58,19 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+//PrecompiledContractsDpos包含读取区块DPoS状态的预编译合约，在DPoS链配置的
+//PrecompileBlock之后启用。输入是不带函数选择器的32字节参数，输出按ABI编码。
+var PrecompiledContractsDpos = map[common.Address]dposContract{
+	common.BytesToAddress([]byte{1, 0}): &dposValidators{},
+	common.BytesToAddress([]byte{1, 1}): &dposCandidate{},
+	common.BytesToAddress([]byte{1, 2}): &dposVote{},
+	common.BytesToAddress([]byte{1, 3}): &dposVoteCount{},
+}
+
+//DposStakingAddress是质押系统合约的地址。合约或外部帐户直接CALL它，
+//以调用者的身份注册候选人或投票，在DPoS链配置的StakingBlock之后启用。
+var DposStakingAddress = common.BytesToAddress([]byte{1, 4})
+
 //runPrecompiledContract运行并评估预编译合同的输出。
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -355,3 +371,185 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	return false32Byte, nil
 }
 
+
+//errNoDposContext在EVM上下文没有DPoS状态时返回
+var errNoDposContext = errors.New("dpos context unavailable")
+
+//dposContract是读取DPoS状态的预编译合约。读取的项数在执行前未知，
+//所以由合约在执行中自行扣除燃气。
+type dposContract interface {
+	run(evm *EVM, input []byte, contract *Contract) ([]byte, error)
+}
+
+//useDposGas从合约中扣除燃气，不足时返回ErrOutOfGas
+func useDposGas(contract *Contract, gas uint64) error {
+	if !contract.UseGas(gas) {
+		return ErrOutOfGas
+	}
+	return nil
+}
+
+//dposContext扣除基础燃气并返回EVM上下文的DPoS状态
+func dposContext(evm *EVM, contract *Contract) (*types.DposContext, error) {
+	if err := useDposGas(contract, params.DposReadGas); err != nil {
+		return nil, err
+	}
+	if evm.DposContext == nil {
+		return nil, errNoDposContext
+	}
+	return evm.DposContext, nil
+}
+
+//dposValidators返回当前周期的验证人列表，编码为address[]
+type dposValidators struct{}
+
+func (c *dposValidators) run(evm *EVM, input []byte, contract *Contract) ([]byte, error) {
+	dposCtx, err := dposContext(evm, contract)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := dposCtx.GetValidators()
+	if err != nil {
+		return nil, err
+	}
+	if err := useDposGas(contract, uint64(len(validators))*params.DposPerItemGas); err != nil {
+		return nil, err
+	}
+	ret := make([]byte, 0, 64+32*len(validators))
+	ret = append(ret, common.LeftPadBytes(big.NewInt(32).Bytes(), 32)...)
+	ret = append(ret, common.LeftPadBytes(big.NewInt(int64(len(validators))).Bytes(), 32)...)
+	for _, validator := range validators {
+		ret = append(ret, common.LeftPadBytes(validator.Bytes(), 32)...)
+	}
+	return ret, nil
+}
+
+//dposCandidate返回输入的地址是否是候选人，编码为bool
+type dposCandidate struct{}
+
+func (c *dposCandidate) run(evm *EVM, input []byte, contract *Contract) ([]byte, error) {
+	dposCtx, err := dposContext(evm, contract)
+	if err != nil {
+		return nil, err
+	}
+	candidate := common.BytesToAddress(getData(input, 0, 32))
+	value, err := dposCtx.CandidateTrie().TryGet(candidate.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return false32Byte, nil
+	}
+	return true32Byte, nil
+}
+
+//dposVote返回输入的投票人投票的候选人，没有投票时返回零地址
+type dposVote struct{}
+
+func (c *dposVote) run(evm *EVM, input []byte, contract *Contract) ([]byte, error) {
+	dposCtx, err := dposContext(evm, contract)
+	if err != nil {
+		return nil, err
+	}
+	delegator := common.BytesToAddress(getData(input, 0, 32))
+	candidate, err := dposCtx.VoteTrie().TryGet(delegator.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return common.LeftPadBytes(candidate, 32), nil
+}
+
+//dposVoteCount返回输入的候选人得到的票数和投票人数，编码为(uint256, uint256)。
+//与选举验证人时相同，票数是所有投票人余额之和。
+type dposVoteCount struct{}
+
+func (c *dposVoteCount) run(evm *EVM, input []byte, contract *Contract) ([]byte, error) {
+	dposCtx, err := dposContext(evm, contract)
+	if err != nil {
+		return nil, err
+	}
+	candidate := common.BytesToAddress(getData(input, 0, 32))
+	var (
+		votes      = new(big.Int)
+		delegators int64
+	)
+	iter := trie.NewIterator(dposCtx.DelegateTrie().PrefixIterator(candidate.Bytes()))
+	for iter.Next() {
+		if err := useDposGas(contract, params.DposDelegatorGas); err != nil {
+			return nil, err
+		}
+		votes.Add(votes, evm.StateDB.GetBalance(common.BytesToAddress(iter.Value)))
+		delegators++
+	}
+	if iter.Err != nil {
+		return nil, iter.Err
+	}
+	ret := make([]byte, 0, 64)
+	ret = append(ret, math.PaddedBigBytes(votes, 32)...)
+	ret = append(ret, common.LeftPadBytes(big.NewInt(delegators).Bytes(), 32)...)
+	return ret, nil
+}
+
+var (
+	errStakingIndirectCall = errors.New("staking contract must be called directly")
+	errStakingValue        = errors.New("staking contract does not accept value")
+
+//质押系统合约的函数选择器，与同名Solidity接口的ABI编码一致
+	becomeCandidateSelector     = crypto.Keccak256([]byte("becomeCandidate()"))[:4]
+	unregisterCandidateSelector = crypto.Keccak256([]byte("unregisterCandidate()"))[:4]
+	delegateSelector            = crypto.Keccak256([]byte("delegate(address)"))[:4]
+	unDelegateSelector          = crypto.Keccak256([]byte("unDelegate(address)"))[:4]
+
+	dposStakingContract = &dposStaking{}
+)
+
+//dposStaking是质押系统合约，以调用者为候选人或投票人修改DPoS状态，
+//效果与对应类型的交易相同。操作失败时回滚，退还剩余燃气。
+type dposStaking struct{}
+
+func (c *dposStaking) run(evm *EVM, input []byte, contract *Contract) ([]byte, error) {
+	if err := useDposGas(contract, params.DposStakingGas); err != nil {
+		return nil, err
+	}
+	if evm.DposContext == nil {
+		return nil, errNoDposContext
+	}
+//DELEGATECALL和CALLCODE会以调用者的地址执行，不允许
+	if contract.Address() != *contract.CodeAddr {
+		return nil, errStakingIndirectCall
+	}
+	if evm.interpreter.IsReadOnly() {
+		return nil, errWriteProtection
+	}
+	if contract.Value().Sign() != 0 {
+		return nil, errStakingValue
+	}
+	if len(input) < 4 {
+		return nil, errExecutionReverted
+	}
+	var (
+		selector = input[:4]
+		caller   = contract.Caller()
+		err      error
+	)
+	evm.snapshotDpos()
+	switch {
+	case bytes.Equal(selector, becomeCandidateSelector):
+		err = evm.DposContext.BecomeCandidate(caller)
+	case bytes.Equal(selector, unregisterCandidateSelector):
+		err = evm.DposContext.KickoutCandidate(caller)
+	case bytes.Equal(selector, delegateSelector):
+		err = evm.DposContext.Delegate(caller, common.BytesToAddress(getData(input, 4, 32)))
+	case bytes.Equal(selector, unDelegateSelector):
+		err = evm.DposContext.UnDelegate(caller, common.BytesToAddress(getData(input, 4, 32)))
+	default:
+		err = errExecutionReverted
+	}
+	if err != nil {
+		if _, ok := err.(*trie.MissingNodeError); ok {
+			return nil, err
+		}
+		return nil, errExecutionReverted
+	}
+	return true32Byte, nil
+}
@@ -18,6 +18,11 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 //预编译测试定义预编译合同测试的输入/输出对。
@@ -478,3 +483,125 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 	}
 }
 
+
+//测试读取DPoS状态的预编译合约
+func TestPrecompiledDpos(t *testing.T) {
+	var (
+		db        = ethdb.NewMemDatabase()
+		candidate = common.HexToAddress("0x0000000000000000000000000000000000000c01")
+		delegator = common.HexToAddress("0x0000000000000000000000000000000000000d01")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetBalance(delegator, big.NewInt(7))
+
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create dpos context: %v", err)
+	}
+	if err := dposContext.SetValidators([]common.Address{candidate}); err != nil {
+		t.Fatalf("failed to set validators: %v", err)
+	}
+	if err := dposContext.BecomeCandidate(candidate); err != nil {
+		t.Fatalf("failed to register candidate: %v", err)
+	}
+	if err := dposContext.Delegate(delegator, candidate); err != nil {
+		t.Fatalf("failed to delegate: %v", err)
+	}
+	config := *params.TestChainConfig
+	config.Dpos = &params.DposConfig{PrecompileBlock: big.NewInt(0)}
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1), DposContext: dposContext}, statedb, &config, Config{})
+
+	word := func(addr common.Address) string {
+		return common.Bytes2Hex(common.LeftPadBytes(addr.Bytes(), 32))
+	}
+	tests := []struct {
+		addr     common.Address
+		input    string
+		expected string
+	}{
+		{common.BytesToAddress([]byte{1, 0}), "",
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000001" + word(candidate)},
+		{common.BytesToAddress([]byte{1, 1}), word(candidate), common.Bytes2Hex(true32Byte)},
+		{common.BytesToAddress([]byte{1, 1}), word(delegator), common.Bytes2Hex(false32Byte)},
+		{common.BytesToAddress([]byte{1, 2}), word(delegator), word(candidate)},
+		{common.BytesToAddress([]byte{1, 2}), word(candidate), word(common.Address{})},
+		{common.BytesToAddress([]byte{1, 3}), word(candidate),
+			"0000000000000000000000000000000000000000000000000000000000000007" +
+				"0000000000000000000000000000000000000000000000000000000000000001"},
+	}
+	for i, test := range tests {
+		p := evm.dposPrecompile(test.addr)
+		if p == nil {
+			t.Fatalf("test %d: precompile %x not enabled", i, test.addr)
+		}
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), 100000)
+		res, err := p.run(evm, common.Hex2Bytes(test.input), contract)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if common.Bytes2Hex(res) != test.expected {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, common.Bytes2Hex(res))
+		}
+	}
+//分叉之前不启用
+	config.Dpos.PrecompileBlock = big.NewInt(2)
+	if p := evm.dposPrecompile(common.BytesToAddress([]byte{1, 0})); p != nil {
+		t.Errorf("precompile enabled before fork block")
+	}
+}
+
+//测试质押系统合约以调用者身份投票，并随调用回滚
+func TestDposStaking(t *testing.T) {
+	var (
+		db        = ethdb.NewMemDatabase()
+		candidate = common.HexToAddress("0x0000000000000000000000000000000000000c01")
+		pool      = common.HexToAddress("0x0000000000000000000000000000000000000b01")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dposContext, err := types.NewDposContext(trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create dpos context: %v", err)
+	}
+	if err := dposContext.BecomeCandidate(candidate); err != nil {
+		t.Fatalf("failed to register candidate: %v", err)
+	}
+	config := *params.TestChainConfig
+	config.Dpos = &params.DposConfig{StakingBlock: big.NewInt(0)}
+	context := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+		DposContext: dposContext,
+	}
+	evm := NewEVM(context, statedb, &config, Config{})
+
+	vote := func() common.Address {
+		return common.BytesToAddress(dposContext.VoteTrie().Get(pool.Bytes()))
+	}
+	delegate := append(append([]byte{}, delegateSelector...), common.LeftPadBytes(candidate.Bytes(), 32)...)
+
+//投票给不是候选人的地址失败
+	invalid := append(append([]byte{}, delegateSelector...), common.LeftPadBytes(pool.Bytes(), 32)...)
+	if _, _, err := evm.Call(AccountRef(pool), DposStakingAddress, invalid, 100000, new(big.Int)); err != errExecutionReverted {
+		t.Fatalf("delegate to non-candidate: have %v, want %v", err, errExecutionReverted)
+	}
+	if _, _, err := evm.Call(AccountRef(pool), DposStakingAddress, delegate, 100000, big.NewInt(1)); err != errStakingValue {
+		t.Fatalf("delegate with value: have %v, want %v", err, errStakingValue)
+	}
+	snapshot := statedb.Snapshot()
+	if _, _, err := evm.Call(AccountRef(pool), DposStakingAddress, delegate, 100000, new(big.Int)); err != nil {
+		t.Fatalf("delegate failed: %v", err)
+	}
+	if have := vote(); have != candidate {
+		t.Fatalf("vote mismatch: have %x, want %x", have, candidate)
+	}
+//外层调用回滚时投票一并撤销
+	statedb.RevertToSnapshot(snapshot)
+	evm.revertDpos(snapshot)
+	if have := vote(); have != (common.Address{}) {
+		t.Fatalf("vote not reverted: have %x", have)
+	}
+	if evm.DposWritten() {
+		t.Fatalf("dpos journal not cleared after revert")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -46,6 +47,10 @@ func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 		if p := precompiles[*contract.CodeAddr]; p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
+		if p := evm.dposPrecompile(*contract.CodeAddr); p != nil {
+			evm.dposRead = true
+			return p.run(evm, input, contract)
+		}
 	}
 	for _, interpreter := range evm.interpreters {
 		if interpreter.CanRun(contract.Code) {
@@ -84,6 +89,11 @@ GasLimit    uint64         //提供气体限制信息
 BlockNumber *big.Int       //提供数字信息
 Time        *big.Int       //提供时间信息
 Difficulty  *big.Int       //为困难提供信息
+
+BaseFee     *big.Int       //提供基础费用信息，费用市场分叉之前为nil
+
+//DPoS信息
+DposContext *types.DposContext //提供区块的DPoS状态，读取DPoS状态的预编译合约使用
 }
 
 //EVM是以太坊虚拟机基础对象，它提供
@@ -121,6 +131,16 @@ type EVM struct {
 //根据63/64规则和更高版本，可用气体在GasCall*中计算。
 //在opcall*中应用。
 	callGasTemp uint64
+//dposRead记录执行期间是否通过预编译合约读取过DPoS状态
+	dposRead bool
+//dposJournal记录系统合约修改DPoS状态之前的快照，调用回滚时一并回滚
+	dposJournal []dposRevision
+}
+
+//dposRevision是系统合约修改DPoS状态之前的快照及当时的状态快照编号
+type dposRevision struct {
+	id       int
+	snapshot *types.DposContext
 }
 
 //new evm返回新的evm。返回的EVM不是线程安全的，应该
@@ -152,6 +172,70 @@ func (evm *EVM) Interpreter() Interpreter {
 	return evm.interpreter
 }
 
+//DposRead返回执行期间是否通过预编译合约读取过DPoS状态
+func (evm *EVM) DposRead() bool {
+	return evm.dposRead
+}
+
+//DposWritten返回执行期间是否通过系统合约修改过DPoS状态
+func (evm *EVM) DposWritten() bool {
+	return len(evm.dposJournal) > 0
+}
+
+//snapshotDpos在系统合约修改DPoS状态之前保存快照
+func (evm *EVM) snapshotDpos() {
+	evm.dposJournal = append(evm.dposJournal, dposRevision{
+		id:       evm.StateDB.Snapshot(),
+		snapshot: evm.DposContext.Snapshot(),
+	})
+}
+
+//revertDpos撤销状态快照snapshot之后系统合约对DPoS状态的修改
+func (evm *EVM) revertDpos(snapshot int) {
+	for i, rev := range evm.dposJournal {
+		if rev.id >= snapshot {
+			evm.DposContext.RevertToSnapShot(rev.snapshot)
+			evm.dposJournal = evm.dposJournal[:i]
+			return
+		}
+	}
+}
+
+//dposPrecompile返回addr处读取DPoS状态的预编译合约，未启用时返回nil
+func (evm *EVM) dposPrecompile(addr common.Address) dposContract {
+	if addr == DposStakingAddress {
+		if !evm.ChainConfig().IsDposStaking(evm.BlockNumber) {
+			return nil
+		}
+		return dposStakingContract
+	}
+	if !evm.ChainConfig().IsDposPrecompile(evm.BlockNumber) {
+		return nil
+	}
+	return PrecompiledContractsDpos[addr]
+}
+
+//ActivePrecompiles返回当前区块启用的所有预编译合约地址，包括DPoS预编译合约
+func (evm *EVM) ActivePrecompiles() []common.Address {
+	precompiles := PrecompiledContractsHomestead
+	if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
+		precompiles = PrecompiledContractsByzantium
+	}
+	addrs := make([]common.Address, 0, len(precompiles)+len(PrecompiledContractsDpos)+1)
+	for addr := range precompiles {
+		addrs = append(addrs, addr)
+	}
+	for addr := range PrecompiledContractsDpos {
+		if evm.dposPrecompile(addr) != nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	if evm.dposPrecompile(DposStakingAddress) != nil {
+		addrs = append(addrs, DposStakingAddress)
+	}
+	return addrs
+}
+
 //调用执行与给定输入为的addr关联的协定
 //参数。它还处理任何必要的价值转移，并采取
 //创建帐户和在
@@ -179,7 +263,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
 			precompiles = PrecompiledContractsByzantium
 		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if precompiles[addr] == nil && evm.dposPrecompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 //调用一个不存在的帐户，不要做任何事情，只需ping跟踪程序
 			if evm.vmConfig.Debug && evm.depth == 0 {
 				evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
@@ -213,6 +297,7 @@ defer func() { //参数的延迟评估
 //当我们在宅基地时，这也算是代码存储气体错误。
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertDpos(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -254,6 +339,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertDpos(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -287,6 +373,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertDpos(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -330,6 +417,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertDpos(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -349,6 +437,10 @@ func (evm *EVM) create(caller ContractRef, code []byte, gas uint64, value *big.I
 	}
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
+//访问列表分叉之后新合同地址按已访问处理，即使创建失败也不回滚
+	if evm.chainRules.IsAccessList {
+		evm.StateDB.AddAddressToAccessList(address)
+	}
 
 //确保指定地址没有现有合同
 	contractHash := evm.StateDB.GetCodeHash(address)
@@ -400,6 +492,7 @@ func (evm *EVM) create(caller ContractRef, code []byte, gas uint64, value *big.I
 //当我们在宅基地时，这也算是代码存储气体错误。
 	if maxCodeSizeExceeded || (err != nil && (evm.ChainConfig().IsHomestead(evm.BlockNumber) || err != ErrCodeStoreOutOfGas)) {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertDpos(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
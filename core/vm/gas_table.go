@@ -472,3 +472,66 @@ func gasDup(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem
 	return GasFastestStep, nil
 }
 
+//makeAccountAccessGasEIP2929包装访问帐户的指令的燃气函数，帐户地址在栈上第addrIndex项。
+//帐户不在访问列表中时按冷访问收费并加入列表，否则按热访问收费。
+func makeAccountAccessGasEIP2929(oldCalculator gasFunc, addrIndex int) gasFunc {
+	return func(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		addr := common.BigToAddress(stack.Back(addrIndex))
+		cost := params.WarmStorageReadCostEIP2929
+		if !evm.StateDB.AddressInAccessList(addr) {
+			evm.StateDB.AddAddressToAccessList(addr)
+			cost = params.ColdAccountAccessCostEIP2929
+		}
+		gt.Balance, gt.ExtcodeSize, gt.ExtcodeCopy, gt.ExtcodeHash, gt.Calls = cost, cost, cost, cost, cost
+		return oldCalculator(gt, evm, contract, stack, mem, memorySize)
+	}
+}
+
+//gasSLoadEIP2929按存储槽是否在访问列表中收取热或冷访问的费用
+func gasSLoadEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	slot := common.BigToHash(stack.Back(0))
+	if _, slotOk := evm.StateDB.SlotInAccessList(contract.Address(), slot); !slotOk {
+		evm.StateDB.AddSlotToAccessList(contract.Address(), slot)
+		return params.ColdSloadCostEIP2929, nil
+	}
+	return params.WarmStorageReadCostEIP2929, nil
+}
+
+//gasSStoreEIP2929在gasSStore的基础上对冷存储槽额外收取冷读取的费用，
+//修改和清除的费用相应减去冷读取的部分。
+func gasSStoreEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var (
+		slot = common.BigToHash(stack.Back(0))
+		cost uint64
+	)
+	if _, slotOk := evm.StateDB.SlotInAccessList(contract.Address(), slot); !slotOk {
+		evm.StateDB.AddSlotToAccessList(contract.Address(), slot)
+		cost = params.ColdSloadCostEIP2929
+	}
+	gas, err := gasSStore(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+//修改和清除的费用相同，都不是设置新值
+	if gas == params.SstoreResetGas {
+		gas -= params.ColdSloadCostEIP2929
+	}
+	return gas + cost, nil
+}
+
+//gasSuicideEIP2929在gasSuicide的基础上，受益人不在访问列表中时额外收取冷访问的费用
+func gasSuicideEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var (
+		address = common.BigToAddress(stack.Back(0))
+		cost    uint64
+	)
+	if !evm.StateDB.AddressInAccessList(address) {
+		evm.StateDB.AddAddressToAccessList(address)
+		cost = params.ColdAccountAccessCostEIP2929
+	}
+	gas, err := gasSuicide(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return gas + cost, nil
+}
@@ -51,6 +51,15 @@ type StateDB interface {
 //根据EIP161定义（balance=nonce=code=0）。
 	Empty(common.Address) bool
 
+//PrepareAccessList在执行交易前重置访问列表，并加入发送者、接收者、预编译合约和交易的访问列表
+	PrepareAccessList(sender common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList)
+	AddressInAccessList(addr common.Address) bool
+	SlotInAccessList(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool)
+//AddAddressToAccessList把帐户加入访问列表，可回滚
+	AddAddressToAccessList(addr common.Address)
+//AddSlotToAccessList把存储槽加入访问列表，可回滚
+	AddSlotToAccessList(addr common.Address, slot common.Hash)
+
 	RevertToSnapshot(int)
 	Snapshot() int
 
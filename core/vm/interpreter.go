@@ -81,6 +81,8 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 //我们将设置默认跳转表。
 	if !cfg.JumpTable[STOP].valid {
 		switch {
+		case evm.ChainConfig().IsAccessList(evm.BlockNumber):
+			cfg.JumpTable = accessListInstructionSet
 		case evm.ChainConfig().IsConstantinople(evm.BlockNumber):
 			cfg.JumpTable = constantinopleInstructionSet
 		case evm.ChainConfig().IsByzantium(evm.BlockNumber):
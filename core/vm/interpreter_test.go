@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+//accessListGasUsed在访问列表分叉之后执行合约代码并返回消耗的燃气。调用者和合约
+//按交易开始时的规则预先访问，list中的帐户和存储槽同样预先加入访问列表。
+func accessListGasUsed(t *testing.T, code []byte, list types.AccessList) uint64 {
+	var (
+		caller   = common.Address{0xca}
+		contract = common.Address{0xcc}
+		gas      = uint64(100000)
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.SetCode(contract, code)
+
+	config := *params.TestChainConfig
+	config.AccessListBlock = big.NewInt(0)
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		GasPrice:    big.NewInt(1),
+		GasLimit:    10000000,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+	}
+	evm := NewEVM(ctx, statedb, &config, Config{})
+	statedb.PrepareAccessList(caller, &contract, evm.ActivePrecompiles(), list)
+
+	_, left, err := evm.Call(AccountRef(caller), contract, nil, gas, new(big.Int))
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return gas - left
+}
+
+//测试访问列表分叉之后SLOAD、SSTORE、CALL和BALANCE第一次访问按冷访问收费，
+//再次访问或交易访问列表中已声明时按热访问收费。
+func TestAccessListGas(t *testing.T) {
+	var (
+		contract = common.Address{0xcc}
+		target   = common.Address{0xaa}
+		slots    = types.AccessList{{Address: contract, StorageKeys: []common.Hash{{}}}}
+		accounts = types.AccessList{{Address: target}}
+	)
+	twice := func(code []byte) []byte {
+		return append(append([]byte{}, code...), code...)
+	}
+	pushTarget := append([]byte{byte(PUSH20)}, target.Bytes()...)
+
+	sload := []byte{byte(PUSH1), 0, byte(SLOAD)}
+	sstore := []byte{
+		byte(PUSH1), 1, byte(PUSH1), 0, byte(SSTORE),
+		byte(PUSH1), 2, byte(PUSH1), 0, byte(SSTORE),
+	}
+	balance := append(append([]byte{}, pushTarget...), byte(BALANCE))
+	call := append([]byte{byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0}, pushTarget...)
+	call = append(call, byte(GAS), byte(CALL), byte(POP))
+
+	tests := []struct {
+		name string
+		code []byte
+		list types.AccessList
+		want uint64
+	}{
+//冷读取2100，热读取100
+		{"sload", twice(sload), nil, 3 + 2100 + 3 + 100},
+		{"sload-declared", twice(sload), slots, 3 + 100 + 3 + 100},
+//新值20000加冷读取2100，再次修改5000减去冷读取部分
+		{"sstore", sstore, nil, 12 + 20000 + 2100 + 2900},
+		{"sstore-declared", sstore, slots, 12 + 20000 + 2900},
+//冷访问帐户2600，热访问100
+		{"balance", twice(balance), nil, 3 + 2600 + 3 + 100},
+		{"balance-declared", twice(balance), accounts, 3 + 100 + 3 + 100},
+//调用不存在的帐户不消耗转发的燃气
+		{"call", twice(call), nil, 22 + 2600 + 22 + 100},
+		{"call-declared", twice(call), accounts, 22 + 100 + 22 + 100},
+	}
+	for _, test := range tests {
+		if have := accessListGasUsed(t, test.code, test.list); have != test.want {
+			t.Errorf("%s: gas used mismatch: have %d, want %d", test.name, have, test.want)
+		}
+	}
+}
@@ -51,8 +51,27 @@ var (
 	homesteadInstructionSet      = newHomesteadInstructionSet()
 	byzantiumInstructionSet      = newByzantiumInstructionSet()
 	constantinopleInstructionSet = newConstantinopleInstructionSet()
+	accessListInstructionSet     = newAccessListInstructionSet()
 )
 
+//newAccessListInstructionSet返回访问列表分叉之后的指令：在康坦丁堡指令的基础上，
+//访问帐户和存储槽的指令按冷热访问收费。
+func newAccessListInstructionSet() [256]operation {
+	instructionSet := newConstantinopleInstructionSet()
+	instructionSet[SLOAD].gasCost = gasSLoadEIP2929
+	instructionSet[SSTORE].gasCost = gasSStoreEIP2929
+	instructionSet[BALANCE].gasCost = makeAccountAccessGasEIP2929(gasBalance, 0)
+	instructionSet[EXTCODESIZE].gasCost = makeAccountAccessGasEIP2929(gasExtCodeSize, 0)
+	instructionSet[EXTCODECOPY].gasCost = makeAccountAccessGasEIP2929(gasExtCodeCopy, 0)
+	instructionSet[EXTCODEHASH].gasCost = makeAccountAccessGasEIP2929(gasExtCodeHash, 0)
+	instructionSet[CALL].gasCost = makeAccountAccessGasEIP2929(gasCall, 1)
+	instructionSet[CALLCODE].gasCost = makeAccountAccessGasEIP2929(gasCallCode, 1)
+	instructionSet[DELEGATECALL].gasCost = makeAccountAccessGasEIP2929(gasDelegateCall, 1)
+	instructionSet[STATICCALL].gasCost = makeAccountAccessGasEIP2929(gasStaticCall, 1)
+	instructionSet[SELFDESTRUCT].gasCost = gasSuicideEIP2929
+	return instructionSet
+}
+
 //NewConstantinopleinstructionset返回边界，宅基地
 //拜占庭和康坦丁堡的指示。
 func newConstantinopleInstructionSet() [256]operation {
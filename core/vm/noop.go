@@ -65,3 +65,9 @@ func (NoopStateDB) AddLog(*types.Log)
 func (NoopStateDB) AddPreimage(common.Hash, []byte)                                    {}
 func (NoopStateDB) ForEachStorage(common.Address, func(common.Hash, common.Hash) bool) {}
 
+func (NoopStateDB) PrepareAccessList(common.Address, *common.Address, []common.Address, types.AccessList) {
+}
+func (NoopStateDB) AddressInAccessList(common.Address) bool                   { return false }
+func (NoopStateDB) SlotInAccessList(common.Address, common.Hash) (bool, bool) { return false, false }
+func (NoopStateDB) AddAddressToAccessList(common.Address)                     {}
+func (NoopStateDB) AddSlotToAccessList(common.Address, common.Hash)           {}
@@ -0,0 +1,207 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+//addrIndexSectionSize是地址索引器一次处理的区块数
+	addrIndexSectionSize = 32
+
+//addrIndexConfirms是区块段被编入地址索引之前需要的确认数
+	addrIndexConfirms = 32
+
+//addrIndexThrottling是处理两个连续区块段之间的等待时间
+	addrIndexThrottling = 100 * time.Millisecond
+
+//addrIndexPageSize是eth_getTransactionsByAddress每页返回的交易数
+	addrIndexPageSize = 100
+)
+
+//地址在交易中的角色，一个地址可以同时有多个角色
+const (
+	addrRoleFrom    uint8 = 1 << iota //交易发送者
+	addrRoleTo                        //交易接收者
+	addrRoleCreated                   //交易创建的合约
+	addrRoleDpos                      //DPoS操作（注册、注销候选人，投票、取消投票）的参与者
+)
+
+var addrRoleNames = []string{"from", "to", "created", "dpos"}
+
+var errAddrIndexDisabled = errors.New("address index not enabled")
+
+//AddressIndexer实现core.ChainIndexerBackend，为规范链上的每笔交易
+//按发送者、接收者、创建的合约以及DPoS操作的参与者建立地址索引。
+//重组后重新处理的区块段会先删除旧的索引条目。
+type AddressIndexer struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+	batch  ethdb.Batch
+}
+
+//NewAddressIndexer返回为规范链建立地址交易索引的链索引器
+func NewAddressIndexer(db ethdb.Database, config *params.ChainConfig) *core.ChainIndexer {
+	backend := &AddressIndexer{
+		db:     db,
+		config: config,
+	}
+	table := ethdb.NewTable(db, string(rawdb.AddressIndexPrefix))
+	return core.NewChainIndexer(db, table, backend, addrIndexSectionSize, addrIndexConfirms, addrIndexThrottling, "addrindex")
+}
+
+//Reset实现core.ChainIndexerBackend，开始处理新的区块段
+func (a *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	a.batch = a.db.NewBatch()
+	return nil
+}
+
+//Process实现core.ChainIndexerBackend，删除该高度旧的索引条目并
+//把区块中交易涉及的地址加入索引。
+func (a *AddressIndexer) Process(ctx context.Context, header *types.Header) error {
+	number, hash := header.Number.Uint64(), header.Hash()
+
+	rawdb.DeleteAddressTxEntries(a.db, number)
+
+	body := rawdb.ReadBody(a.db, hash, number)
+	if body == nil {
+		return errors.New("block body missing")
+	}
+	signer := types.MakeSigner(a.config, header.Number)
+
+	var (
+		addresses []common.Address
+		entries   []*rawdb.AddressTxEntry
+	)
+	for i, tx := range body.Transactions {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		roles := make(map[common.Address]uint8)
+		roles[from] |= addrRoleFrom
+		if to := tx.To(); to != nil {
+			roles[*to] |= addrRoleTo
+		} else {
+			roles[crypto.CreateAddress(from, tx.Nonce())] |= addrRoleCreated
+		}
+		if tx.Type() != types.Binary {
+			for addr := range roles {
+				roles[addr] |= addrRoleDpos
+			}
+		}
+		for addr, role := range roles {
+			addresses = append(addresses, addr)
+			entries = append(entries, &rawdb.AddressTxEntry{
+				BlockHash:  hash,
+				BlockIndex: number,
+				Index:      uint64(i),
+				TxHash:     tx.Hash(),
+				Roles:      role,
+			})
+		}
+	}
+	rawdb.WriteAddressTxEntries(a.batch, number, addresses, entries)
+	if a.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := a.batch.Write(); err != nil {
+			return err
+		}
+		a.batch.Reset()
+	}
+	return nil
+}
+
+//Commit实现core.ChainIndexerBackend，把区块段的索引写入数据库
+func (a *AddressIndexer) Commit() error {
+	return a.batch.Write()
+}
+
+//PublicAddressIndexAPI提供按地址查询历史交易的API
+type PublicAddressIndexAPI struct {
+	e *Ethereum
+}
+
+//NewPublicAddressIndexAPI创建新的地址索引API
+func NewPublicAddressIndexAPI(e *Ethereum) *PublicAddressIndexAPI {
+	return &PublicAddressIndexAPI{e}
+}
+
+//AddressTransaction是地址相关的一笔交易以及地址在其中的角色
+type AddressTransaction struct {
+	*ethapi.RPCTransaction
+	Roles []string `json:"roles"`
+}
+
+//AddressTransactions是eth_getTransactionsByAddress返回的一页交易
+type AddressTransactions struct {
+	Transactions []*AddressTransaction `json:"transactions"`
+	IndexedHead  hexutil.Uint64        `json:"indexedHead"` //已经编入索引的最高区块
+	More         bool                  `json:"more"`        //是否还有下一页
+}
+
+//GetTransactionsByAddress按区块顺序返回地址在[fromBlock, toBlock]范围内
+//参与的交易，每页最多addrIndexPageSize笔，page从0开始。
+//尚未编入索引的区块不会返回，toBlock超过IndexedHead时截断。
+func (api *PublicAddressIndexAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, page hexutil.Uint64) (*AddressTransactions, error) {
+	indexer := api.e.addrIndexer
+	if indexer == nil {
+		return nil, errAddrIndexDisabled
+	}
+	sections, _, _ := indexer.Sections()
+	result := &AddressTransactions{Transactions: []*AddressTransaction{}}
+	if sections == 0 {
+		return result, nil
+	}
+	head := sections*addrIndexSectionSize - 1
+	result.IndexedHead = hexutil.Uint64(head)
+
+	from, to := uint64(0), head
+	if fromBlock >= 0 {
+		from = uint64(fromBlock)
+	}
+	if toBlock >= 0 && uint64(toBlock) < head {
+		to = uint64(toBlock)
+	}
+	if from > to {
+		return result, nil
+	}
+	db := api.e.ChainDb()
+	skip := int(page) * addrIndexPageSize
+
+	entries := rawdb.ReadAddressTxEntries(db, address, from, to, skip, addrIndexPageSize+1)
+	if len(entries) > addrIndexPageSize {
+		entries, result.More = entries[:addrIndexPageSize], true
+	}
+	for _, entry := range entries {
+		if rawdb.ReadCanonicalHash(db, entry.BlockIndex) != entry.BlockHash {
+			continue
+		}
+		body := rawdb.ReadBody(db, entry.BlockHash, entry.BlockIndex)
+		if body == nil || uint64(len(body.Transactions)) <= entry.Index {
+			continue
+		}
+		var roles []string
+		for i, name := range addrRoleNames {
+			if entry.Roles&(1<<uint(i)) != 0 {
+				roles = append(roles, name)
+			}
+		}
+		result.Transactions = append(result.Transactions, &AddressTransaction{
+			RPCTransaction: ethapi.NewRPCTransaction(body.Transactions[entry.Index], entry.BlockHash, entry.BlockIndex, entry.Index),
+			Roles:          roles,
+		})
+	}
+	return result, nil
+}
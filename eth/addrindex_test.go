@@ -0,0 +1,105 @@
+package eth
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//测试链索引器按区块段处理规范链后，eth_getTransactionsByAddress
+//能按角色查到地址的交易，尚未编入索引的区块不返回
+func TestAddressIndexer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addrindexer_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var (
+		recipient = common.BytesToAddress([]byte{0x11})
+		gspec     = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		created = crypto.CreateAddress(testBank, 1)
+		txs     = make(map[uint64]common.Hash)
+	)
+//区块1转账给recipient，区块2创建合约，区块40在第一个区块段之后
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, addrIndexSectionSize+addrIndexConfirms+8, func(i int, block *core.BlockGen) {
+		var tx *types.Transaction
+		switch block.Number().Uint64() {
+		case 1, 40:
+			tx = types.NewTransaction(types.Binary, block.TxNonce(testBank), recipient, big.NewInt(1000), params.TxGas, nil, nil)
+		case 2:
+			tx = types.NewContractCreation(block.TxNonce(testBank), big.NewInt(0), 100000, nil, []byte{0x00})
+		default:
+			return
+		}
+		tx, err := types.SignTx(tx, types.MakeSigner(gspec.Config, block.Number()), testBankKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+		txs[block.Number().Uint64()] = tx.Hash()
+	})
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+
+	indexer := NewAddressIndexer(db, gspec.Config)
+	indexer.Start(blockchain)
+	defer indexer.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if sections, _, _ := indexer.Sections(); sections > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("address index section not processed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	api := NewPublicAddressIndexAPI(&Ethereum{chainDb: db, addrIndexer: indexer})
+	check := func(address common.Address, want map[common.Hash][]string) {
+		result, err := api.GetTransactionsByAddress(context.Background(), address, 0, rpc.LatestBlockNumber, 0)
+		if err != nil {
+			t.Fatalf("lookup of %x failed: %v", address, err)
+		}
+		if result.IndexedHead != addrIndexSectionSize-1 {
+			t.Errorf("indexed head mismatch: have %d, want %d", result.IndexedHead, addrIndexSectionSize-1)
+		}
+		have := make(map[common.Hash][]string)
+		for _, tx := range result.Transactions {
+			have[tx.Hash] = tx.Roles
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("transactions of %x mismatch: have %v, want %v", address, have, want)
+		}
+	}
+	check(testBank, map[common.Hash][]string{txs[1]: {"from"}, txs[2]: {"from"}})
+	check(recipient, map[common.Hash][]string{txs[1]: {"to"}})
+	check(created, map[common.Hash][]string{txs[2]: {"created"}})
+}
@@ -21,10 +21,11 @@ import (
 	"math/big"
 	"os"
 	"strings"
-//“时间”
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -76,6 +77,49 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().HashRate())
 }
 
+//privateTxValidators是私有交易转发到的即将出块的验证人数目
+const privateTxValidators = 3
+
+//SendPrivateRawTransaction把签名交易只转发给接下来几个出块时间槽的验证人，
+//交易不会在公网广播，直到被打包进区块。返回交易哈希。
+func (api *PublicEthereumAPI) SendPrivateRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return common.Hash{}, err
+	}
+	engine, ok := api.e.engine.(*dpos.Dpos)
+	if !ok {
+		return common.Hash{}, errors.New("private transactions require the dpos engine")
+	}
+	validators, err := engine.ScheduledValidators(api.e.blockchain.CurrentBlock(), time.Now().Unix(), api.e.chainConfig.Dpos.BlockInterval, privateTxValidators)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	txs := types.Transactions{tx}
+	api.e.protocolManager.MarkPrivateTxs(txs)
+
+//本节点就是即将出块的验证人之一时直接加入交易池
+	local := false
+	if self, err := api.e.Validator(); err == nil && api.e.IsMining() {
+		for _, validator := range validators {
+			if validator == self {
+				local = true
+				break
+			}
+		}
+	}
+	if local {
+		if err := api.e.txPool.AddRemote(tx); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	if sent := api.e.protocolManager.SendPrivateTxs(txs, validators); sent == 0 && !local {
+		return common.Hash{}, errors.New("no scheduled validator reachable")
+	}
+	log.Info("Submitted private transaction", "hash", tx.Hash().Hex(), "validators", len(validators))
+	return tx.Hash(), nil
+}
+
 //publicMinerapi提供了一个API来控制矿工。
 //它只提供对数据进行操作的方法，这些数据在公开访问时不会带来安全风险。
 type PublicMinerAPI struct {
@@ -154,6 +198,20 @@ func (api *PrivateMinerAPI) SetExtra(extra string) (bool, error) {
 	return true, nil
 }
 
+//SubmitBundle提交一组有序的签名交易，矿工在目标区块开头全部打包或
+//全部不打包，执行回滚的交易包被丢弃。返回交易包哈希。
+func (api *PrivateMinerAPI) SubmitBundle(encodedTxs []hexutil.Bytes, blockNumber hexutil.Uint64) (common.Hash, error) {
+	txs := make(types.Transactions, 0, len(encodedTxs))
+	for _, encodedTx := range encodedTxs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encodedTx); err != nil {
+			return common.Hash{}, err
+		}
+		txs = append(txs, tx)
+	}
+	return api.e.Miner().SubmitBundle(txs, uint64(blockNumber))
+}
+
 //setgasprice为矿工设定了最低可接受的天然气价格。
 func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	api.e.lock.Lock()
@@ -276,6 +334,27 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+//TxPoolImportResult是admin_importTxPool的结果
+type TxPoolImportResult struct {
+	Added   int `json:"added"`
+	Dropped int `json:"dropped"`
+}
+
+//ExportTxPool把交易池中全部挂起和排队的交易写入本地文件，返回导出的交易数。
+//与ExportChain一样读写任意本地路径，因此只在admin命名空间中提供
+func (api *PrivateAdminAPI) ExportTxPool(file string) (int, error) {
+	return api.eth.TxPool().ExportTransactions(file)
+}
+
+//ImportTxPool从本地文件导入交易，按当前状态和DPoS上下文重新校验后作为远程交易加入池中
+func (api *PrivateAdminAPI) ImportTxPool(file string) (*TxPoolImportResult, error) {
+	added, dropped, err := api.eth.TxPool().ImportTransactions(file)
+	if err != nil {
+		return nil, err
+	}
+	return &TxPoolImportResult{Added: added, Dropped: dropped}, nil
+}
+
 //publicDebugAPI是公开的以太坊完整节点API的集合
 //在公共调试终结点上。
 type PublicDebugAPI struct {
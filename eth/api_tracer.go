@@ -65,10 +65,11 @@ Error  string      `json:"error,omitempty"`  //示踪剂产生的示踪失效
 //当整个链为
 //被追踪。
 type blockTraceTask struct {
-statedb *state.StateDB   //准备跟踪的中间状态
-block   *types.Block     //用于跟踪事务的块
-rootref common.Hash      //为此任务保留的trie根引用
-results []*txTraceResult //跟踪结果按任务进行
+statedb     *state.StateDB     //准备跟踪的中间状态
+dposContext *types.DposContext //执行区块之前的DPoS上下文
+block       *types.Block       //用于跟踪事务的块
+rootref     common.Hash        //为此任务保留的trie根引用
+results     []*txTraceResult   //跟踪结果按任务进行
 }
 
 //blocktraceresult represets当一个完整的
@@ -82,8 +83,9 @@ Traces []*txTraceResult `json:"traces"` //跟踪任务生成的结果
 //txtracetask表示当整个块
 //正在跟踪。
 type txTraceTask struct {
-statedb *state.StateDB //准备跟踪的中间状态
-index   int            //块中的事务偏移量
+statedb     *state.StateDB     //准备跟踪的中间状态
+dposContext *types.DposContext //执行到该交易之前的DPoS上下文
+index       int                //块中的事务偏移量
 }
 
 //tracechain返回在执行evm期间创建的结构化日志
@@ -194,8 +196,12 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				for i, tx := range task.block.Transactions() {
 					msg, _ := tx.AsMessage(signer)
 					vmctx := core.NewEVMContext(msg, task.block.Header(), api.eth.blockchain, nil)
+					vmctx.DposContext = task.dposContext
 
 					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+					if err == nil && msg.Type() != types.Binary {
+						err = core.ApplyDposMessage(task.dposContext, msg)
+					}
 					if err != nil {
 						task.results[i] = &txTraceResult{Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
@@ -267,16 +273,20 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 			if number > origin {
 				txs := block.Transactions()
 
+				dposContext, err := api.dposContextAt(block, statedb)
+				if err != nil {
+					failed = err
+					break
+				}
 				select {
-				case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(txs))}:
+				case tasks <- &blockTraceTask{statedb: statedb.Copy(), dposContext: dposContext, block: block, rootref: proot, results: make([]*txTraceResult, len(txs))}:
 				case <-notifier.Closed():
 					return
 				}
 				traced += uint64(len(txs))
 			}
 //快速生成下一个状态快照，无需跟踪
-			_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
-			if err != nil {
+			if err := api.process(block, statedb); err != nil {
 				failed = err
 				break
 			}
@@ -411,6 +421,10 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	if err != nil {
 		return nil, err
 	}
+	dposContext, err := api.dposContextAt(block, statedb)
+	if err != nil {
+		return nil, err
+	}
 //同时执行块内包含的所有事务
 	var (
 		signer = types.MakeSigner(api.config, block.Number())
@@ -434,6 +448,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 			for task := range jobs {
 				msg, _ := txs[task.index].AsMessage(signer)
 				vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+				vmctx.DposContext = task.dposContext
 
 				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
 				if err != nil {
@@ -448,17 +463,24 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	var failed error
 	for i, tx := range txs {
 //发送跟踪任务以供执行
-		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
+		jobs <- &txTraceTask{statedb: statedb.Copy(), dposContext: dposContext.Copy(), index: i}
 
 //快速生成下一个状态快照，无需跟踪
 		msg, _ := tx.AsMessage(signer)
 		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+		vmctx.DposContext = dposContext
 
 		vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{})
 		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
 			failed = err
 			break
 		}
+		if msg.Type() != types.Binary {
+			if err := core.ApplyDposMessage(dposContext, msg); err != nil {
+				failed = err
+				break
+			}
+		}
 //最终确定状态，以便将任何修改写入trie
 		statedb.Finalise(true)
 	}
@@ -517,8 +539,7 @@ func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*
 		if block = api.eth.blockchain.GetBlockByNumber(block.NumberU64() + 1); block == nil {
 			return nil, fmt.Errorf("block #%d not found", block.NumberU64()+1)
 		}
-		_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
-		if err != nil {
+		if err := api.process(block, statedb); err != nil {
 			return nil, err
 		}
 //最终确定状态，以便将任何修改写入trie
@@ -621,6 +642,30 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	}
 }
 
+//dposContextAt打开执行block之前的DPoS上下文，即父块头记录的DPoS状态，
+//与导入区块时ApplyTransaction使用的上下文相同。
+func (api *PrivateDebugAPI) dposContextAt(block *types.Block, statedb *state.StateDB) (*types.DposContext, error) {
+	parent := api.eth.blockchain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	return types.NewDposContextFromProto(statedb.Database().TrieDB(), parent.DposContext)
+}
+
+//process在statedb上重新执行block。处理器会修改区块的DPoS上下文，
+//所以在区块的副本上打开上下文，不改动链缓存中的区块。
+func (api *PrivateDebugAPI) process(block *types.Block, statedb *state.StateDB) error {
+	dposContext, err := api.dposContextAt(block, statedb)
+	if err != nil {
+		return err
+	}
+	block = block.WithBody(block.Transactions(), block.Uncles())
+	block.DposContext = dposContext
+
+	_, _, _, err = api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+	return err
+}
+
 //computetxenv返回特定事务的执行环境。
 func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, reexec uint64) (core.Message, vm.Context, *state.StateDB, error) {
 //创建父状态数据库
@@ -636,6 +681,10 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 	if err != nil {
 		return nil, vm.Context{}, nil, err
 	}
+	dposContext, err := api.dposContextAt(block, statedb)
+	if err != nil {
+		return nil, vm.Context{}, nil, err
+	}
 //重新计算达到目标索引的事务。
 	signer := types.MakeSigner(api.config, block.Number())
 
@@ -643,6 +692,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 //组装事务调用消息并返回请求的偏移量
 		msg, _ := tx.AsMessage(signer)
 		context := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+		context.DposContext = dposContext
 		if idx == txIndex {
 			return msg, context, statedb, nil
 		}
@@ -651,6 +701,11 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
 			return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
 		}
+		if msg.Type() != types.Binary {
+			if err := core.ApplyDposMessage(dposContext, msg); err != nil {
+				return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
+			}
+		}
 //确保对国家进行任何修改
 		statedb.Finalise(true)
 	}
@@ -78,6 +78,7 @@ chainDb ethdb.Database //区块链数据库
 
 bloomRequests chan chan *bloombits.Retrieval //接收Bloom数据检索请求的通道
 bloomIndexer  *core.ChainIndexer             //块导入期间的Bloom索引器操作
+addrIndexer   *core.ChainIndexer             //地址交易索引器，未启用时为nil
 
 	APIBackend *EthAPIBackend
 
@@ -110,6 +111,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	if chainDb, err = OpenFreezer(ctx, config, chainDb); err != nil {
+		return nil, err
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -117,13 +121,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	engine := dpos.New(chainConfig.Dpos, chainDb)
+	if frdb, ok := chainDb.(*rawdb.FreezerDatabase); ok && !config.NoFreezer {
+		frdb.Freeze(freezeLimit(engine, config.FreezerMargin))
+	}
 	eth := &Ethereum{
 		config:         config,
 		chainDb:        chainDb,
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
-		engine:         dpos.New(chainConfig.Dpos, chainDb),
+		engine:         engine,
 		shutdownChan:   make(chan bool),
 		networkID:      config.NetworkId,
 		gasPrice:       config.MinerGasPrice,
@@ -149,6 +157,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	eth.blockchain.SetParallelTxs(config.ParallelTxs)
 //在不兼容的配置升级时倒带链。
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -156,10 +165,20 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.AddressIndex {
+		if _, ok := chainDb.(ethdb.Store); !ok {
+			return nil, errors.New("address index requires an iterable database engine")
+		}
+		eth.addrIndexer = NewAddressIndexer(chainDb, eth.chainConfig)
+		eth.addrIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = ctx.ResolvePath(config.TxPool.RemoteJournal)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
 
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
@@ -168,6 +187,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine, config.MinerRecommit)
 	eth.miner.SetExtra(makeExtraData(config.MinerExtraData))
+	eth.miner.SetDposQuota(config.MinerDposQuota)
 
 	eth.APIBackend = &EthAPIBackend{eth, nil}
 	gpoParams := config.GPO
@@ -202,12 +222,41 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*ethdb.LDBDatabase); ok {
+	if db, ok := db.(ethdb.Store); ok {
 		db.Meter("eth/db/chaindata/")
 	}
 	return db, nil
 }
 
+//OpenFreezer在持久化的链数据库之上打开冻结表，内存数据库原样返回
+func OpenFreezer(ctx *node.ServiceContext, config *Config, db ethdb.Database) (ethdb.Database, error) {
+	store, ok := db.(ethdb.Store)
+	if !ok {
+		return db, nil
+	}
+	dir := config.DatabaseFreezer
+	if dir == "" {
+		dir = ctx.ResolvePath("ancient")
+	}
+	frdb, err := rawdb.NewDatabaseWithFreezer(store, dir)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
+//freezeLimit返回冻结的上限：不可逆区块之前margin个块。还没有不可逆区块时不冻结
+func freezeLimit(engine *dpos.Dpos, margin uint64) func() uint64 {
+	return func() uint64 {
+		confirmed, ok := engine.ConfirmedNumber()
+		if !ok || confirmed <= margin {
+			return 0
+		}
+		return confirmed - margin
+	}
+}
+
 //API返回以太坊包提供的RPC服务集合。
 //注意，其中一些服务可能需要转移到其他地方。
 func (s *Ethereum) APIs() []rpc.API {
@@ -216,6 +265,16 @@ func (s *Ethereum) APIs() []rpc.API {
 //附加由共识引擎显式公开的任何API
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+//启用地址索引时附加按地址查询交易的API
+	if s.addrIndexer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicAddressIndexAPI(s),
+			Public:    true,
+		})
+	}
+
 //附加所有本地API并返回
 	return append(apis, []rpc.API{
 		{
@@ -330,13 +389,25 @@ func (s *Ethereum) StartMining(local bool) error {
 		return fmt.Errorf("coinbase missing: %v", err)
 	}
 
-	if dpos, ok := s.engine.(*dpos.Dpos); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: validator})
-		if wallet == nil || err != nil {
-			log.Error("Coinbase account unavailable locally", "err", err)
-			return fmt.Errorf("signer missing: %v", err)
+	if engine, ok := s.engine.(*dpos.Dpos); ok {
+		if s.config.DposSigner != "" {
+//出块签名交给外部签名者（clef），验证人私钥不在本节点
+			client, err := rpc.Dial(s.config.DposSigner)
+			if err != nil {
+				log.Error("Failed to dial external signer", "url", s.config.DposSigner, "err", err)
+				return fmt.Errorf("signer missing: %v", err)
+			}
+			engine.AuthorizeSealer(validator, dpos.NewRemoteSealer(client))
+		} else {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: validator})
+			if wallet == nil || err != nil {
+				log.Error("Coinbase account unavailable locally", "err", err)
+				return fmt.Errorf("signer missing: %v", err)
+			}
+			engine.Authorize(validator, wallet.SignHash)
 		}
-		dpos.Authorize(validator, wallet.SignHash)
+//向已连接的对等机声明验证人身份，以便接收私有交易
+		s.protocolManager.AnnounceValidator()
 	}
 	if local {
 //如果启动了本地（CPU）挖掘，我们可以禁用事务拒绝
@@ -394,6 +465,7 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 		maxPeers -= s.config.LightPeers
 	}
 //如果需要，启动网络层和轻型服务器
+	s.protocolManager.self = srvr.Self().ID
 	s.protocolManager.Start(maxPeers)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
@@ -405,6 +477,9 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 //以太坊协议。
 func (s *Ethereum) Stop() error {
 	s.bloomIndexer.Close()
+	if s.addrIndexer != nil {
+		s.addrIndexer.Close()
+	}
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.protocolManager.Stop()
@@ -39,13 +39,15 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
-	NetworkId:     1,
-	LightPeers:    100,
-	DatabaseCache: 768,
-	TrieCache:     256,
-	TrieTimeout:   60 * time.Minute,
-	MinerGasPrice: big.NewInt(18 * params.Shannon),
-	MinerRecommit: 3 * time.Second,
+	NetworkId:      1,
+	LightPeers:     100,
+	DatabaseCache:  768,
+	FreezerMargin:  8640,
+	TrieCache:      256,
+	TrieTimeout:    60 * time.Minute,
+	MinerGasPrice:  big.NewInt(18 * params.Shannon),
+	MinerRecommit:  3 * time.Second,
+	MinerDposQuota: 16,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -91,6 +93,18 @@ LightPeers int `toml:",omitempty"` //最大LES客户端对等数
 	TrieCache          int
 	TrieTimeout        time.Duration
 
+//冻结表选项：不可逆区块之前超过FreezerMargin个块的区块移入DatabaseFreezer目录
+//（默认是实例目录下的ancient）的平面文件，NoFreezer关闭移动
+	DatabaseFreezer string `toml:",omitempty"`
+	NoFreezer       bool   `toml:",omitempty"`
+	FreezerMargin   uint64 `toml:",omitempty"`
+
+//AddressIndex开启地址交易索引，提供eth_getTransactionsByAddress
+	AddressIndex bool `toml:",omitempty"`
+
+//ParallelTxs是验证区块时乐观并行执行交易的线程数，0或1表示顺序执行
+	ParallelTxs int `toml:",omitempty"`
+
 //Mining-related options
 //etherbase common.address`toml:“，omitempty”`
 	Validator    common.Address `toml:",omitempty"`
@@ -100,6 +114,8 @@ LightPeers int `toml:",omitempty"` //最大LES客户端对等数
 	MinerExtraData []byte         `toml:",omitempty"`
 	MinerGasPrice  *big.Int
 	MinerRecommit  time.Duration
+MinerDposQuota int //每个区块优先打包的DPoS交易数量
+DposSigner     string         `toml:",omitempty"` //外部签名者地址（clef），设置后出块签名不使用本地解锁账号
 
 //乙烯利选项
 	Ethash ethash.Config
@@ -0,0 +1,138 @@
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//maxFeeHistory是一次feeHistory请求最多返回的区块数
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+//txGasAndReward是区块中一笔交易用掉的燃气和给矿工的小费
+type txGasAndReward struct {
+	gasUsed uint64
+	reward  *big.Int
+}
+
+type sortGasAndReward []txGasAndReward
+
+func (s sortGasAndReward) Len() int           { return len(s) }
+func (s sortGasAndReward) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortGasAndReward) Less(i, j int) bool { return s[i].reward.Cmp(s[j].reward) < 0 }
+
+//FeeHistory返回以lastBlock结尾的最多blocks个区块的费用数据：每个区块的基础费用
+//（外加lastBlock下一个区块的基础费用）、燃气使用率，以及按燃气加权的小费分位数。
+//分叉之前的区块基础费用为0。
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	if blocks < 1 {
+		return nil, nil, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 || (i > 0 && p < rewardPercentiles[i-1]) {
+			return nil, nil, nil, nil, errInvalidPercentile
+		}
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return nil, nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return nil, nil, nil, nil, errRequestBeyondHead
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		config       = gpo.backend.ChainConfig()
+		reward       [][]*big.Int
+		baseFee      = make([]*big.Int, blocks+1)
+		gasUsedRatio = make([]float64, blocks)
+		lastHeader   *types.Header
+	)
+	if len(rewardPercentiles) > 0 {
+		reward = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			return nil, nil, nil, nil, err
+		}
+		header := block.Header()
+		if baseFee[i] = header.BaseFee; baseFee[i] == nil {
+			baseFee[i] = new(big.Int)
+		}
+		if header.GasLimit > 0 {
+			gasUsedRatio[i] = float64(header.GasUsed) / float64(header.GasLimit)
+		}
+		if reward != nil {
+			if reward[i], err = gpo.blockRewards(ctx, block, rewardPercentiles); err != nil {
+				return nil, nil, nil, nil, err
+			}
+		}
+		lastHeader = header
+	}
+//最后一项是下一个区块的基础费用
+	baseFee[blocks] = new(big.Int)
+	if config.IsFeeMarket(new(big.Int).Add(lastHeader.Number, big.NewInt(1))) {
+		baseFee[blocks] = misc.CalcBaseFee(config, lastHeader)
+	}
+	return new(big.Int).SetUint64(oldest), reward, baseFee, gasUsedRatio, nil
+}
+
+//blockRewards按燃气用量加权计算区块内交易小费的各个分位数，空区块返回全0
+func (gpo *Oracle) blockRewards(ctx context.Context, block *types.Block, percentiles []float64) ([]*big.Int, error) {
+	rewards := make([]*big.Int, len(percentiles))
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		for i := range rewards {
+			rewards[i] = new(big.Int)
+		}
+		return rewards, nil
+	}
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(txs) {
+		return nil, errors.New("receipts do not match block transactions")
+	}
+	sorted := make([]txGasAndReward, len(txs))
+	for i, tx := range txs {
+		sorted[i] = txGasAndReward{receipts[i].GasUsed, tx.EffectiveGasTip(block.BaseFee())}
+	}
+	sort.Sort(sortGasAndReward(sorted))
+
+	var (
+		index   int
+		sumUsed = sorted[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * p / 100)
+		for sumUsed < threshold && index < len(sorted)-1 {
+			index++
+			sumUsed += sorted[index].gasUsed
+		}
+		rewards[i] = sorted[index].reward
+	}
+	return rewards, nil
+}
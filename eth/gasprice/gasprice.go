@@ -19,6 +19,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
@@ -135,6 +136,10 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	if price.Cmp(maxPrice) > 0 {
 		price = new(big.Int).Set(maxPrice)
 	}
+//费用市场分叉之后，样本是小费，建议价格还需要加上下一个区块的基础费用
+	if config := gpo.backend.ChainConfig(); config.IsFeeMarket(new(big.Int).Add(head.Number, common.Big1)) {
+		price = new(big.Int).Add(price, misc.CalcBaseFee(config, head))
+	}
 
 	gpo.cacheLock.Lock()
 	gpo.lastHead = headHash
@@ -148,13 +153,19 @@ type getBlockPricesResult struct {
 	err   error
 }
 
-type transactionsByGasPrice []*types.Transaction
+//transactionsByGasTip按给矿工的有效小费排序，没有基础费用的区块中就是燃气价格
+type transactionsByGasTip struct {
+	txs     []*types.Transaction
+	baseFee *big.Int
+}
 
-func (t transactionsByGasPrice) Len() int           { return len(t) }
-func (t transactionsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
-func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPrice().Cmp(t[j].GasPrice()) < 0 }
+func (t transactionsByGasTip) Len() int      { return len(t.txs) }
+func (t transactionsByGasTip) Swap(i, j int) { t.txs[i], t.txs[j] = t.txs[j], t.txs[i] }
+func (t transactionsByGasTip) Less(i, j int) bool {
+	return t.txs[i].EffectiveGasTip(t.baseFee).Cmp(t.txs[j].EffectiveGasTip(t.baseFee)) < 0
+}
 
-//GetBlockPrices计算给定区块中的最低交易天然气价格
+//GetBlockPrices计算给定区块中的最低交易小费
 //并发送到结果通道。如果块为空，则价格为零。
 func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, ch chan getBlockPricesResult) {
 	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
@@ -166,12 +177,12 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 	blockTxs := block.Transactions()
 	txs := make([]*types.Transaction, len(blockTxs))
 	copy(txs, blockTxs)
-	sort.Sort(transactionsByGasPrice(txs))
+	sort.Sort(transactionsByGasTip{txs, block.BaseFee()})
 
 	for _, tx := range txs {
 		sender, err := types.Sender(signer, tx)
 		if err == nil && sender != block.Coinbase() {
-			ch <- getBlockPricesResult{tx.GasPrice(), nil}
+			ch <- getBlockPricesResult{tx.EffectiveGasTip(block.BaseFee()), nil}
 			return
 		}
 	}
@@ -41,6 +41,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DatabaseCache           int
 		TrieCache               int
 		TrieTimeout             time.Duration
+		DatabaseFreezer         string `toml:",omitempty"`
+		NoFreezer               bool   `toml:",omitempty"`
+		FreezerMargin           uint64 `toml:",omitempty"`
+		AddressIndex            bool   `toml:",omitempty"`
+		ParallelTxs             int    `toml:",omitempty"`
 //etherbase common.address`toml:“，omitempty”`
 		Validator               common.Address `toml:",omitempty"`
 		Coinbase                common.Address `toml:",omitempty"`
@@ -49,6 +54,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		MinerExtraData          hexutil.Bytes  `toml:",omitempty"`
 		MinerGasPrice           *big.Int
 		MinerRecommit           time.Duration
+		MinerDposQuota          int
+		DposSigner              string `toml:",omitempty"`
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
@@ -67,6 +74,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseCache = c.DatabaseCache
 	enc.TrieCache = c.TrieCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.NoFreezer = c.NoFreezer
+	enc.FreezerMargin = c.FreezerMargin
+	enc.AddressIndex = c.AddressIndex
+	enc.ParallelTxs = c.ParallelTxs
 //Enc.EtherBase=C.EtherBase
 	enc.Validator = c.Validator
 	enc.Coinbase = c.Coinbase
@@ -75,6 +87,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerExtraData = c.MinerExtraData
 	enc.MinerGasPrice = c.MinerGasPrice
 	enc.MinerRecommit = c.MinerRecommit
+	enc.MinerDposQuota = c.MinerDposQuota
+	enc.DposSigner = c.DposSigner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -97,6 +111,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseCache           *int
 		TrieCache               *int
 		TrieTimeout             *time.Duration
+		DatabaseFreezer         *string `toml:",omitempty"`
+		NoFreezer               *bool   `toml:",omitempty"`
+		FreezerMargin           *uint64 `toml:",omitempty"`
+		AddressIndex            *bool   `toml:",omitempty"`
+		ParallelTxs             *int    `toml:",omitempty"`
 //etherbase*common.address`toml:“，omitempty”`
 		Validator               *common.Address `toml:",omitempty"`
 		Coinbase                *common.Address `toml:",omitempty"`
@@ -105,6 +124,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		MinerExtraData          *hexutil.Bytes  `toml:",omitempty"`
 		MinerGasPrice           *big.Int
 		MinerRecommit           *time.Duration
+		MinerDposQuota          *int
+		DposSigner              *string `toml:",omitempty"`
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
@@ -148,6 +169,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
+	if dec.NoFreezer != nil {
+		c.NoFreezer = *dec.NoFreezer
+	}
+	if dec.FreezerMargin != nil {
+		c.FreezerMargin = *dec.FreezerMargin
+	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.ParallelTxs != nil {
+		c.ParallelTxs = *dec.ParallelTxs
+	}
  /*
  如果是12月以太坊！= nIL{
   C.EtherBase=*十二月EtherBase
@@ -174,6 +210,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.MinerRecommit != nil {
 		c.MinerRecommit = *dec.MinerRecommit
 	}
+	if dec.MinerDposQuota != nil {
+		c.MinerDposQuota = *dec.MinerDposQuota
+	}
+	if dec.DposSigner != nil {
+		c.DposSigner = *dec.DposSigner
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -36,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -45,6 +47,9 @@ estHeaderRlpSize  = 500             //RLP编码块头的近似大小
 //txchanSize是侦听newtxSevent的频道的大小。
 //该数字是根据Tx池的大小引用的。
 	txChanSize = 4096
+
+//maxPrivateTxs是记住的私有交易哈希的最大数目，这些交易不会被广播
+	maxPrivateTxs = 4096
 )
 
 var (
@@ -68,8 +73,12 @@ acceptTxs uint32 //标记是否被认为是同步的（启用事务处理）
 	txpool      txPool
 	blockchain  *core.BlockChain
 	chainconfig *params.ChainConfig
+	engine      consensus.Engine
 	maxPeers    int
 
+privateTxs *lru.Cache      //只转发给验证人的私有交易哈希，不在公网广播
+self       discover.NodeID //本节点ID，用于验证对等机的验证人声明
+
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
@@ -98,12 +107,15 @@ acceptTxs uint32 //标记是否被认为是同步的（启用事务处理）
 //使用以太坊网络。
 func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, networkID uint64, mux *event.TypeMux, txpool txPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb ethdb.Database) (*ProtocolManager, error) {
 //使用基本字段创建协议管理器
+	privateTxs, _ := lru.New(maxPrivateTxs)
 	manager := &ProtocolManager{
 		networkID:   networkID,
 		eventMux:    mux,
 		txpool:      txpool,
 		blockchain:  blockchain,
 		chainconfig: config,
+		engine:      engine,
+		privateTxs:  privateTxs,
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
@@ -285,6 +297,10 @@ func (pm *ProtocolManager) handle(p *peer) error {
 //之后将通过广播发送。
 	pm.syncTransactions(p)
 
+//如果本节点是验证人，向对等机声明身份，以便接收私有交易
+	if p.version >= dpos1 {
+		pm.announceValidator(p)
+	}
 //如果我们知道DAO Hard Fork，请验证与Hard Fork相关的任何远程对等。
 	if daoBlock := pm.chainconfig.DAOForkBlock; daoBlock != nil {
 //请求对等端的DAO分叉头进行额外的数据验证
@@ -682,6 +698,37 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		pm.txpool.AddRemotes(txs)
 
+	case p.version >= dpos1 && msg.Code == ValidatorMsg:
+//对等机声明自己是验证人，签名必须针对本节点ID
+		var request validatorData
+		if err := msg.Decode(&request); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		signer, err := dpos.RecoverValidatorAnnounce(pm.self[:], request.Sig)
+		if err != nil || signer != request.Address {
+			return errResp(ErrInvalidValidator, "address %x", request.Address)
+		}
+		p.SetValidator(request.Address)
+		p.Log().Debug("Peer announced validator", "address", request.Address)
+
+	case p.version >= dpos1 && msg.Code == PrivateTxMsg:
+//私有交易只能由即将出块的验证人打包，加入交易池但不广播
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for i, tx := range txs {
+			if tx == nil {
+				return errResp(ErrDecode, "transaction %d is nil", i)
+			}
+			p.MarkTransaction(tx.Hash())
+			pm.privateTxs.Add(tx.Hash(), struct{}{})
+		}
+		pm.txpool.AddRemotes(txs)
+
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
@@ -728,6 +775,9 @@ func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
 
 //将事务广播给一批不知道它的对等方
 	for _, tx := range txs {
+		if pm.privateTxs.Contains(tx.Hash()) {
+			continue
+		}
 		peers := pm.peers.PeersWithoutTx(tx.Hash())
 		for _, peer := range peers {
 			txset[peer] = append(txset[peer], tx)
@@ -740,6 +790,64 @@ func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
 	}
 }
 
+//SendPrivateTxs把交易只发送给给定验证人中已连接的对等机，交易被标记为私有，
+//本节点不会再广播。返回接收交易的对等机数目。
+func (pm *ProtocolManager) SendPrivateTxs(txs types.Transactions, validators []common.Address) int {
+	for _, tx := range txs {
+		pm.privateTxs.Add(tx.Hash(), struct{}{})
+	}
+	peers := pm.peers.PeersWithValidator(validators)
+	sent := 0
+	for _, peer := range peers {
+		if err := peer.SendPrivateTransactions(txs); err != nil {
+			peer.Log().Debug("Failed to send private transactions", "err", err)
+			continue
+		}
+		sent++
+	}
+	log.Trace("Sent private transactions", "count", len(txs), "recipients", sent)
+	return sent
+}
+
+//MarkPrivateTxs把交易标记为私有，本节点不会广播
+func (pm *ProtocolManager) MarkPrivateTxs(txs types.Transactions) {
+	for _, tx := range txs {
+		pm.privateTxs.Add(tx.Hash(), struct{}{})
+	}
+}
+
+//announceValidator在本节点已授权出块时向对等机声明验证人身份
+func (pm *ProtocolManager) announceValidator(p *peer) {
+	engine, ok := pm.engine.(*dpos.Dpos)
+	if !ok {
+		return
+	}
+	id := p.ID()
+	address, sig, err := engine.SignValidatorAnnounce(id[:])
+	if err != nil {
+		return
+	}
+	if err := p.SendValidator(address, sig); err != nil {
+		p.Log().Debug("Failed to announce validator", "err", err)
+	}
+}
+
+//AnnounceValidator向所有支持DPoS扩展版本的对等机声明本节点的验证人身份
+func (pm *ProtocolManager) AnnounceValidator() {
+	pm.peers.lock.RLock()
+	peers := make([]*peer, 0, len(pm.peers.peers))
+	for _, p := range pm.peers.peers {
+		if p.version >= dpos1 {
+			peers = append(peers, p)
+		}
+	}
+	pm.peers.lock.RUnlock()
+
+	for _, p := range peers {
+		pm.announceValidator(p)
+	}
+}
+
 //地雷广播回路
 func (pm *ProtocolManager) minedBroadcastLoop() {
 //如果取消订阅，则自动停止
@@ -79,9 +79,10 @@ type peer struct {
 version  int         //协议版本协商
 forkDrop *time.Timer //如果未及时验证分叉，则为定时连接滴管
 
-	head common.Hash
-	td   *big.Int
-	lock sync.RWMutex
+	head      common.Hash
+	td        *big.Int
+validator common.Address //对等机通过ValidatorMsg证明的验证人地址，未证明时为空
+	lock      sync.RWMutex
 
 knownTxs    mapset.Set                //此对等方已知的事务哈希集
 knownBlocks mapset.Set                //此对等方已知的块哈希集
@@ -213,6 +214,35 @@ func (p *peer) AsyncSendTransactions(txs []*types.Transaction) {
 	}
 }
 
+//SendPrivateTransactions把私有交易直接发给对等的验证人，对方不会再广播
+func (p *peer) SendPrivateTransactions(txs types.Transactions) error {
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p2p.Send(p.rw, PrivateTxMsg, txs)
+}
+
+//SendValidator向对等机声明本节点的验证人身份
+func (p *peer) SendValidator(address common.Address, sig []byte) error {
+	return p2p.Send(p.rw, ValidatorMsg, &validatorData{Address: address, Sig: sig})
+}
+
+//Validator返回对等机已证明的验证人地址
+func (p *peer) Validator() common.Address {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.validator
+}
+
+//SetValidator记录对等机已证明的验证人地址
+func (p *peer) SetValidator(address common.Address) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.validator = address
+}
+
 //sendNewBlockHashes宣布通过
 //哈希通知。
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -490,6 +520,30 @@ func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 	return list
 }
 
+//PeersWithValidator检索已证明是给定验证人之一的对等方列表
+func (ps *peerSet) PeersWithValidator(validators []common.Address) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(validators))
+	for _, p := range ps.peers {
+		if p.version < dpos1 {
+			continue
+		}
+		validator := p.Validator()
+		if validator == (common.Address{}) {
+			continue
+		}
+		for _, v := range validators {
+			if validator == v {
+				list = append(list, p)
+				break
+			}
+		}
+	}
+	return list
+}
+
 //BestPeer以当前最高的总难度检索已知的对等。
 func (ps *peerSet) BestPeer() *peer {
 	ps.lock.RLock()
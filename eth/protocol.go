@@ -27,16 +27,18 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+//DPoS扩展版本在ETH/63之上增加验证人声明和私有交易，版本号避开上游的eth/64及之后的版本
+	dpos1 = 100
 )
 
 //ProtocolName是在能力协商期间使用的协议的官方简称。
 var ProtocolName = "eth"
 
 //协议版本是ETH协议的支持版本（首先是主要版本）。
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{dpos1, eth63, eth62}
 
 //Protocollength是对应于不同协议版本的已实现消息数。
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 //协议消息大小的最大上限
 
@@ -57,6 +59,10 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+//属于DPoS扩展版本的协议消息
+ValidatorMsg = 0x11 //声明对等机是DPoS验证人，附带对本节点ID的签名
+PrivateTxMsg = 0x12 //只转发给即将出块的验证人的私有交易，不再广播
 )
 
 type errCode int
@@ -71,6 +77,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrInvalidValidator
 )
 
 func (e errCode) String() string {
@@ -88,6 +95,7 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrInvalidValidator:        "Invalid validator announcement",
 }
 
 type txPool interface {
@@ -177,3 +185,10 @@ Uncles       []*types.Header      //一个街区内的叔叔
 //blockbodiesdata是用于块内容分发的网络包。
 type blockBodiesData []*blockBody
 
+
+//validatorData是验证人声明消息的网络包，Sig是验证人对
+//接收方节点ID的签名，防止声明被转发给其他节点冒用。
+type validatorData struct {
+	Address common.Address
+	Sig     []byte
+}
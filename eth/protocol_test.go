@@ -18,11 +18,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -218,3 +221,94 @@ func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	}
 }
 
+
+//测试对等机以对本节点ID的有效签名声明验证人身份后被记录，签名不符时断开连接。
+func TestRecvValidatorMsg(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	copy(pm.self[:], crypto.Keccak512([]byte("self")))
+	defer pm.Stop()
+
+	key, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+	engine := dpos.New(&params.DposConfig{}, nil)
+	engine.Authorize(validator, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	_, sig, err := engine.SignValidatorAnnounce(pm.self[:])
+	if err != nil {
+		t.Fatalf("failed to sign validator announce: %v", err)
+	}
+	p, errc := newTestPeer("peer", dpos1, pm, true)
+	defer p.close()
+
+	if err := p2p.Send(p.app, ValidatorMsg, &validatorData{Address: validator, Sig: sig}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); p.Validator() != validator; {
+		if time.Now().After(deadline) {
+			t.Fatalf("validator not recorded: have %x, want %x", p.Validator(), validator)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if peers := pm.peers.PeersWithValidator([]common.Address{validator}); len(peers) != 1 {
+		t.Fatalf("validator peers mismatch: have %d, want 1", len(peers))
+	}
+//签给其他节点ID的声明不能冒充验证人
+	_, other, err := engine.SignValidatorAnnounce(crypto.Keccak512([]byte("other")))
+	if err != nil {
+		t.Fatalf("failed to sign validator announce: %v", err)
+	}
+	if err := p2p.Send(p.app, ValidatorMsg, &validatorData{Address: validator, Sig: other}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if want := errResp(ErrInvalidValidator, "address %x", validator); err == nil || err.Error() != want.Error() {
+			t.Errorf("wrong error: got %v, want %v", err, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("protocol did not shut down within 2 seconds")
+	}
+}
+
+//测试私有交易被加入交易池并记为私有，旧版本的对等机不能发送私有交易。
+func TestRecvPrivateTransactions(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+pm.acceptTxs = 1 //标记为同步以接受交易记录
+	defer pm.Stop()
+
+	p, _ := newTestPeer("peer", dpos1, pm, true)
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, PrivateTxMsg, []interface{}{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Errorf("added wrong transactions: got %v, want %x", added, tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no private transaction added within 2 seconds")
+	}
+	if !pm.privateTxs.Contains(tx.Hash()) {
+		t.Errorf("transaction not marked private")
+	}
+
+	old, errc := newTestPeer("old", eth63, pm, true)
+	defer old.close()
+
+	if err := p2p.Send(old.app, PrivateTxMsg, []interface{}{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if want := errResp(ErrInvalidMsgCode, "%v", PrivateTxMsg); err == nil || err.Error() != want.Error() {
+			t.Errorf("wrong error: got %v, want %v", err, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("protocol did not shut down within 2 seconds")
+	}
+}
@@ -54,3 +54,6 @@ func (s *senderFromServer) SignatureValues(tx *types.Transaction, sig []byte) (R
 	panic("can't sign with senderFromServer")
 }
 
+func (s *senderFromServer) ChainID() *big.Int {
+	panic("can't sign with senderFromServer")
+}
@@ -0,0 +1,298 @@
+package ethdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+//badgerGCInterval是回收值日志空间的间隔
+	badgerGCInterval = 10 * time.Minute
+
+//badgerGCDiscardRatio是值日志文件中可回收数据超过多少比例时重写该文件
+	badgerGCDiscardRatio = 0.5
+)
+
+var errBadgerNotFound = errors.New("not found")
+
+//badgerKey返回存储使用的键。BadgerDB不接受空键，因此所有键前面加一个字节，
+//不影响键的顺序。返回的是新切片，调用者之后修改原键不影响未提交的事务。
+func badgerKey(key []byte) []byte {
+	return append([]byte{'k'}, key...)
+}
+
+//BadgerDatabase是基于BadgerDB的Store实现。BadgerDB把键放在LSM树中，
+//值放在单独的日志中，写入放大比LevelDB小，适合大量写入状态数据的场景。
+type BadgerDatabase struct {
+fn string      //数据库目录
+db *badger.DB //BadgerDB实例
+
+diskReadMeter  metrics.Meter //测量读取数据量的仪表
+diskWriteMeter metrics.Meter //测量写入数据量的仪表
+
+quitLock sync.Mutex    //互斥保护退出通道访问
+quitChan chan struct{} //关闭数据库前停止后台值日志回收
+
+log log.Logger //上下文记录器跟踪数据库路径
+}
+
+//NewBadgerDatabase打开或创建给定目录下的BadgerDB数据库。
+//BadgerDB自己管理缓存和文件句柄，cache和handles只用于日志输出。
+func NewBadgerDatabase(file string, cache int, handles int) (*BadgerDatabase, error) {
+	logger := log.New("database", file)
+	logger.Info("Opening badger database", "cache", cache, "handles", handles)
+
+	opts := badger.DefaultOptions(file)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	bdb := &BadgerDatabase{
+		fn:       file,
+		db:       db,
+		quitChan: make(chan struct{}),
+		log:      logger,
+	}
+	go bdb.collectGarbage()
+	return bdb, nil
+}
+
+//Path返回数据库目录的路径
+func (db *BadgerDatabase) Path() string {
+	return db.fn
+}
+
+//Put写入给定的键值对
+func (db *BadgerDatabase) Put(key []byte, value []byte) error {
+	if db.diskWriteMeter != nil {
+		db.diskWriteMeter.Mark(int64(len(key) + len(value)))
+	}
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(badgerKey(key), common.CopyBytes(value))
+	})
+}
+
+//Has判断键是否存在
+func (db *BadgerDatabase) Has(key []byte) (bool, error) {
+	err := db.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(badgerKey(key))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//Get返回键对应的值，键不存在时返回错误
+func (db *BadgerDatabase) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(badgerKey(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, errBadgerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if db.diskReadMeter != nil {
+		db.diskReadMeter.Mark(int64(len(value)))
+	}
+	return value, nil
+}
+
+//Delete删除给定的键
+func (db *BadgerDatabase) Delete(key []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(badgerKey(key))
+	})
+}
+
+//NewIteratorWithPrefix返回遍历以prefix开头的所有键的迭代器
+func (db *BadgerDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	txn := db.db.NewTransaction(false)
+	return &badgerIterator{
+		txn:    txn,
+		it:     txn.NewIterator(badger.DefaultIteratorOptions),
+		prefix: badgerKey(prefix),
+	}
+}
+
+//Compact把所有层合并到最底层并回收值日志空间。BadgerDB不支持按范围压缩，
+//因此start和limit被忽略。
+func (db *BadgerDatabase) Compact(start []byte, limit []byte) error {
+	if err := db.db.Flatten(1); err != nil {
+		return err
+	}
+	for db.db.RunValueLogGC(badgerGCDiscardRatio) == nil {
+	}
+	return nil
+}
+
+//Meter以给定前缀注册读写数据量的度量收集器
+func (db *BadgerDatabase) Meter(prefix string) {
+	if metrics.Enabled {
+		db.diskReadMeter = metrics.NewRegisteredMeter(prefix+"disk/read", nil)
+		db.diskWriteMeter = metrics.NewRegisteredMeter(prefix+"disk/write", nil)
+	}
+}
+
+//Close停止后台回收并关闭数据库
+func (db *BadgerDatabase) Close() {
+	db.quitLock.Lock()
+	defer db.quitLock.Unlock()
+
+	if db.quitChan != nil {
+		close(db.quitChan)
+		db.quitChan = nil
+	}
+	if err := db.db.Close(); err == nil {
+		db.log.Info("Database closed")
+	} else {
+		db.log.Error("Failed to close database", "err", err)
+	}
+}
+
+//collectGarbage定期回收值日志中被覆盖和删除的数据
+func (db *BadgerDatabase) collectGarbage() {
+	db.quitLock.Lock()
+	quit := db.quitChan
+	db.quitLock.Unlock()
+
+	ticker := time.NewTicker(badgerGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for db.db.RunValueLogGC(badgerGCDiscardRatio) == nil {
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+//NewBatch创建一个写入批次
+func (db *BadgerDatabase) NewBatch() Batch {
+	return &badgerBatch{db: db.db}
+}
+
+//badgerOp是批次中的一个写入或删除操作
+type badgerOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+//badgerBatch在内存中收集写操作，Write时用事务提交。BadgerDB要求事务提交前
+//不能修改传入的切片，因此值先拷贝。单个事务超过大小上限时拆分成多个事务提交。
+type badgerBatch struct {
+	db   *badger.DB
+	ops  []badgerOp
+	size int
+}
+
+func (b *badgerBatch) Put(key, value []byte) error {
+	b.ops = append(b.ops, badgerOp{key: badgerKey(key), value: common.CopyBytes(value)})
+	b.size += len(value)
+	return nil
+}
+
+func (b *badgerBatch) Delete(key []byte) error {
+	b.ops = append(b.ops, badgerOp{key: badgerKey(key), delete: true})
+	b.size += 1
+	return nil
+}
+
+func (b *badgerBatch) Write() error {
+	txn := b.db.NewTransaction(true)
+	for i := 0; i < len(b.ops); i++ {
+		err := b.apply(txn, b.ops[i])
+		if err == badger.ErrTxnTooBig {
+//事务已满，提交已有的操作后在新事务中重试
+			if err = txn.Commit(); err != nil {
+				return err
+			}
+			txn = b.db.NewTransaction(true)
+			err = b.apply(txn, b.ops[i])
+		}
+		if err != nil {
+			txn.Discard()
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+func (b *badgerBatch) apply(txn *badger.Txn, op badgerOp) error {
+	if op.delete {
+		return txn.Delete(op.key)
+	}
+	return txn.Set(op.key, op.value)
+}
+
+func (b *badgerBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *badgerBatch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+//badgerIterator把BadgerDB的迭代器包装成Iterator，键和值都是拷贝
+type badgerIterator struct {
+	txn     *badger.Txn
+	it      *badger.Iterator
+	prefix  []byte
+	started bool
+	done    bool //遍历结束后不能再移动底层迭代器
+	key     []byte
+	value   []byte
+	err     error
+}
+
+func (it *badgerIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if !it.started {
+		it.it.Seek(it.prefix)
+		it.started = true
+	} else {
+		it.it.Next()
+	}
+	if !it.it.ValidForPrefix(it.prefix) {
+		it.key, it.value, it.done = nil, nil, true
+		return false
+	}
+	item := it.it.Item()
+	it.key = item.KeyCopy(nil)[1:]
+	if it.value, it.err = item.ValueCopy(nil); it.err != nil {
+		it.key, it.value, it.done = nil, nil, true
+		return false
+	}
+	return true
+}
+
+func (it *badgerIterator) Key() []byte   { return it.key }
+func (it *badgerIterator) Value() []byte { return it.value }
+func (it *badgerIterator) Error() error  { return it.err }
+
+func (it *badgerIterator) Release() {
+	it.it.Close()
+	it.txn.Discard()
+}
@@ -120,10 +120,15 @@ func (db *LDBDatabase) NewIterator() iterator.Iterator {
 }
 
 //NewIteratorWithPrefix返回一个迭代器，用于使用特定前缀对数据库内容的子集进行迭代。
-func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+//Compact压缩给定的键范围，两者都为空时压缩整个数据库
+func (db *LDBDatabase) Compact(start []byte, limit []byte) error {
+	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
 func (db *LDBDatabase) Close() {
 //停止度量集合以避免内部数据库争用
 	db.quitLock.Lock()
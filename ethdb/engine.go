@@ -0,0 +1,151 @@
+package ethdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+//LevelDBEngine是默认的存储引擎
+	LevelDBEngine = "leveldb"
+
+//BadgerEngine是基于BadgerDB的LSM树存储引擎，键和值分开存储
+	BadgerEngine = "badger"
+)
+
+//Opener打开或创建给定目录下的数据库，cache以MB为单位，handles是允许打开的文件数
+type Opener func(file string, cache int, handles int) (Store, error)
+
+//engine描述一个已注册的存储引擎
+type engine struct {
+	open   Opener
+	detect func(file string) bool //判断目录中是否是该引擎创建的数据库
+}
+
+var (
+	enginesLock sync.RWMutex
+	engines     = make(map[string]engine)
+)
+
+func init() {
+	RegisterEngine(LevelDBEngine, func(file string, cache int, handles int) (Store, error) {
+		return NewLDBDatabase(file, cache, handles)
+	}, func(file string) bool {
+		return hasFiles(file, "CURRENT")
+	})
+	RegisterEngine(BadgerEngine, func(file string, cache int, handles int) (Store, error) {
+		return NewBadgerDatabase(file, cache, handles)
+	}, func(file string) bool {
+		return hasFiles(file, "*.vlog")
+	})
+}
+
+//RegisterEngine注册一个存储引擎。detect用于识别已有数据库使用的引擎，
+//防止用错误的引擎打开数据目录，可以为空。重复注册同名引擎会panic。
+func RegisterEngine(name string, open Opener, detect func(file string) bool) {
+	enginesLock.Lock()
+	defer enginesLock.Unlock()
+
+	if _, exists := engines[name]; exists {
+		panic(fmt.Sprintf("ethdb: engine %q registered twice", name))
+	}
+	engines[name] = engine{open: open, detect: detect}
+}
+
+//Engines返回所有已注册引擎的名称
+func Engines() []string {
+	enginesLock.RLock()
+	defer enginesLock.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//DetectEngine返回目录中已有数据库使用的引擎，目录不存在或为空时返回空字符串
+func DetectEngine(file string) string {
+	for _, name := range Engines() {
+		enginesLock.RLock()
+		e := engines[name]
+		enginesLock.RUnlock()
+
+		if e.detect != nil && e.detect(file) {
+			return name
+		}
+	}
+	return ""
+}
+
+//Open用给定引擎打开数据库。name为空时使用目录中已有数据库的引擎，
+//新目录使用LevelDB。指定的引擎与已有数据不一致时返回错误，不会覆盖数据。
+func Open(name string, file string, cache int, handles int) (Store, error) {
+	existing := DetectEngine(file)
+	switch {
+	case name == "" && existing == "":
+		name = LevelDBEngine
+	case name == "":
+		name = existing
+	case existing != "" && existing != name:
+		return nil, fmt.Errorf("database %s was created by the %s engine, not %s", file, existing, name)
+	}
+	enginesLock.RLock()
+	e, ok := engines[name]
+	enginesLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown database engine %q (available: %s)", name, strings.Join(Engines(), ", "))
+	}
+	return e.open(file, cache, handles)
+}
+
+//hasFiles判断目录中是否存在匹配pattern的文件
+func hasFiles(dir string, pattern string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+	return len(matches) > 0
+}
+
+//Copy把src中的所有键值对批量写入dst，返回复制的条目数。用于在存储引擎之间迁移数据。
+func Copy(dst Database, src Store) (int, error) {
+	var (
+		it     = src.NewIteratorWithPrefix(nil)
+		batch  = dst.NewBatch()
+		count  = 0
+		start  = time.Now()
+		logged = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return count, err
+		}
+		count++
+		if batch.ValueSize() >= IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return count, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Copying database", "entries", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return count, err
+	}
+	if err := batch.Write(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
@@ -0,0 +1,83 @@
+package ethdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+//TestOpenDetectsEngine检查已有数据库沿用创建时的引擎，且不能用其它引擎打开
+func TestOpenDetectsEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethdb_engine_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "chaindata")
+
+	db, err := ethdb.Open(ethdb.BadgerEngine, path, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Close()
+
+	if engine := ethdb.DetectEngine(path); engine != ethdb.BadgerEngine {
+		t.Fatalf("detected engine mismatch: have %q, want %q", engine, ethdb.BadgerEngine)
+	}
+	if _, err := ethdb.Open(ethdb.LevelDBEngine, path, 0, 0); err == nil {
+		t.Fatal("opened badger database with leveldb engine")
+	}
+	db, err = ethdb.Open("", path, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	if _, ok := db.(*ethdb.BadgerDatabase); !ok {
+		t.Fatalf("reopened database has wrong type %T", db)
+	}
+	db.Close()
+
+	if _, err := ethdb.Open("nosuchengine", filepath.Join(dir, "other"), 0, 0); err == nil {
+		t.Fatal("opened database with unknown engine")
+	}
+}
+
+//TestCopy检查数据在引擎之间迁移后完全一致，包括遍历的顺序
+func TestCopy(t *testing.T) {
+	src, removeSrc := newTestLDB()
+	defer removeSrc()
+	dst, removeDst := newTestBadger()
+	defer removeDst()
+
+	for i := 0; i < 1000; i++ {
+		if err := src.Put([]byte(fmt.Sprintf("key-%04d", i)), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count, err := ethdb.Copy(dst, src)
+	if err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+	if count != 1000 {
+		t.Fatalf("copied entry count mismatch: have %d, want %d", count, 1000)
+	}
+	srcIt, dstIt := src.NewIteratorWithPrefix([]byte("key-")), dst.NewIteratorWithPrefix([]byte("key-"))
+	defer srcIt.Release()
+	defer dstIt.Release()
+
+	for srcIt.Next() {
+		if !dstIt.Next() {
+			t.Fatalf("missing key %q in copy", srcIt.Key())
+		}
+		if !bytes.Equal(srcIt.Key(), dstIt.Key()) || !bytes.Equal(srcIt.Value(), dstIt.Value()) {
+			t.Fatalf("entry mismatch: have %q=%q, want %q=%q", dstIt.Key(), dstIt.Value(), srcIt.Key(), srcIt.Value())
+		}
+	}
+	if dstIt.Next() {
+		t.Fatalf("unexpected extra key %q in copy", dstIt.Key())
+	}
+}
@@ -36,6 +36,34 @@ type Database interface {
 	NewBatch() Batch
 }
 
+//Iterator按键的字节序遍历数据库内容。第一次调用Next之前迭代器不指向任何条目，
+//用完后必须调用Release释放资源。
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Release()
+}
+
+//Store是持久化的键值存储引擎，除了数据库操作之外还支持遍历、压缩和度量。
+//所有通过RegisterEngine注册的引擎都实现这个接口。
+type Store interface {
+	Database
+
+//NewIteratorWithPrefix返回遍历以prefix开头的所有键的迭代器，prefix为空时遍历全部
+	NewIteratorWithPrefix(prefix []byte) Iterator
+
+//Compact压缩键范围[start, limit)，两者都为空时压缩整个数据库
+	Compact(start []byte, limit []byte) error
+
+//Meter以给定前缀注册数据库的度量收集器
+	Meter(prefix string)
+
+//Path返回数据库所在的目录
+	Path() string
+}
+
 //批处理是一个只写的数据库，它将更改提交到其主机数据库。
 //当调用写入时。批处理不能同时使用。
 type Batch interface {
@@ -0,0 +1,230 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+//TestBuildSchema检查schema能被解析，且每个字段都有对应的resolver方法
+func TestBuildSchema(t *testing.T) {
+	if _, err := graphqlgo.ParseSchema(schema, &Resolver{}); err != nil {
+		t.Fatalf("Could not construct GraphQL handler: %v", err)
+	}
+}
+
+//testBackend只实现查询区块用到的方法，其余方法调用时panic
+type testBackend struct {
+	ethapi.Backend
+	blocks []*types.Block
+}
+
+func newTestBackend(n int) *testBackend {
+	b := new(testBackend)
+	parent := common.Hash{}
+	for i := 0; i < n; i++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Time:       big.NewInt(int64(i * 10)),
+			Difficulty: big.NewInt(1),
+		})
+		b.blocks = append(b.blocks, block)
+		parent = block.Hash()
+	}
+	return b
+}
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.blocks[len(b.blocks)-1] }
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.CurrentBlock(), nil
+	}
+	if int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func execQuery(t *testing.T, backend ethapi.Backend, sendTx bool, query string) *graphqlgo.Response {
+	schema, err := graphqlgo.ParseSchema(schema, &Resolver{backend: backend, sendTx: sendTx})
+	if err != nil {
+		t.Fatalf("Could not construct GraphQL schema: %v", err)
+	}
+	return schema.Exec(context.Background(), query, "", nil)
+}
+
+func TestBlocksQuery(t *testing.T) {
+	backend := newTestBackend(5)
+
+	resp := execQuery(t, backend, false, `{ blocks(from: 1, to: 3) { number hash parent { number } } }`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("query failed: %v", resp.Errors)
+	}
+	var result struct {
+		Blocks []struct {
+			Number hexutil.Uint64 `json:"number"`
+			Hash   common.Hash   `json:"hash"`
+			Parent struct {
+				Number hexutil.Uint64 `json:"number"`
+			} `json:"parent"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(resp.Data, &result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(result.Blocks) != 3 {
+		t.Fatalf("block count mismatch: have %d, want 3", len(result.Blocks))
+	}
+	for i, block := range result.Blocks {
+		want := backend.blocks[i+1]
+		if uint64(block.Number) != want.NumberU64() || block.Hash != want.Hash() {
+			t.Errorf("block %d mismatch: have #%d %x, want #%d %x", i, block.Number, block.Hash, want.NumberU64(), want.Hash())
+		}
+		if uint64(block.Parent.Number) != want.NumberU64()-1 {
+			t.Errorf("block %d parent mismatch: have #%d", i, block.Parent.Number)
+		}
+	}
+
+//未指定to时查询到最新区块
+	resp = execQuery(t, backend, false, `{ blocks(from: 3) { number } }`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("query failed: %v", resp.Errors)
+	}
+	if err := json.Unmarshal(resp.Data, &result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(result.Blocks) != 2 {
+		t.Errorf("block count mismatch: have %d, want 2", len(result.Blocks))
+	}
+}
+
+func TestBlocksRangeLimit(t *testing.T) {
+	backend := newTestBackend(1)
+
+	resp := execQuery(t, backend, false, `{ blocks(from: 0, to: 1023) { number } }`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("range of %d blocks rejected: %v", maxBlockRange, resp.Errors)
+	}
+	resp = execQuery(t, backend, false, `{ blocks(from: 0, to: 1024) { number } }`)
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, errBlockRange.Error()) {
+		t.Fatalf("range of %d blocks not rejected: %v", maxBlockRange+1, resp.Errors)
+	}
+}
+
+func TestSendRawTransactionDisabled(t *testing.T) {
+	resp := execQuery(t, newTestBackend(1), false, `mutation { sendRawTransaction(data: "0x00") }`)
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, errSendTxDisabled.Error()) {
+		t.Fatalf("sendRawTransaction not rejected: %v", resp.Errors)
+	}
+}
+
+//GraphQL与HTTP RPC一样只接受允许的虚拟主机名，并按配置返回跨域头
+func TestServiceHostAndCors(t *testing.T) {
+	service, err := New(newTestBackend(1), "127.0.0.1:0", []string{"https://explorer.example"}, []string{"localhost"}, nil, rpc.DefaultHTTPTimeouts, nil, rpc.RateLimits{})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	srv, err := service.newServer()
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	request := func(host string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "http://"+host+"/graphql", strings.NewReader(`{"query": "{ block { number } }"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", "https://explorer.example")
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := request("evil.example"); rec.Code != http.StatusForbidden {
+		t.Errorf("unknown virtual host accepted: status %d", rec.Code)
+	}
+	rec := request("localhost")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("request failed: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "https://explorer.example" {
+		t.Errorf("cors origin mismatch: have %q", origin)
+	}
+}
+
+//启用认证后，GraphQL请求与HTTP RPC一样必须携带有效的令牌，令牌必须允许eth命名空间
+func TestServiceAuthentication(t *testing.T) {
+	auth := rpc.NewAuthenticator(nil, map[string][]string{"reader": {"eth"}, "admin": {"admin"}})
+	service, err := New(newTestBackend(1), "127.0.0.1:0", nil, []string{"localhost"}, []string{"eth"}, rpc.DefaultHTTPTimeouts, auth, rpc.RateLimits{})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	srv, err := service.newServer()
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "http://localhost/graphql", strings.NewReader(`{"query": "{ block { number } }"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := request(""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request without token accepted: status %d", rec.Code)
+	}
+	if rec := request("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request with invalid token accepted: status %d", rec.Code)
+	}
+	if rec := request("admin"); rec.Code != http.StatusForbidden {
+		t.Errorf("token without eth namespace accepted: status %d", rec.Code)
+	}
+	if rec := request("reader"); rec.Code != http.StatusOK {
+		t.Errorf("request with valid token failed: status %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+//GraphQL请求按客户端IP计入HTTP RPC的限流
+func TestServiceRateLimit(t *testing.T) {
+	service, err := New(newTestBackend(1), "127.0.0.1:0", nil, []string{"localhost"}, nil, rpc.DefaultHTTPTimeouts, nil, rpc.RateLimits{Rate: 0.001, Burst: 1})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	srv, err := service.newServer()
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	request := func() int {
+		req := httptest.NewRequest("POST", "http://localhost/graphql", strings.NewReader(`{"query": "{ block { number } }"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("first request failed: status %d", code)
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit accepted: status %d", code)
+	}
+}
@@ -0,0 +1,147 @@
+package graphql
+
+const schema string = `
+    # Bytes32是32字节的二进制字符串，以0x开头的十六进制表示。
+    scalar Bytes32
+    # Address是20字节的以太坊地址，以0x开头的十六进制表示。
+    scalar Address
+    # Bytes是任意长度的二进制字符串，以0x开头的十六进制表示。
+    scalar Bytes
+    # BigInt是大整数，输入可以是JSON数字或十六进制字符串，输出为十六进制字符串。
+    scalar BigInt
+    # Long是64位无符号整数，输出为十六进制字符串。
+    scalar Long
+
+    schema {
+        query: Query
+        mutation: Mutation
+    }
+
+    # Account是某个区块状态下的以太坊账户。
+    type Account {
+        address: Address!
+        balance: BigInt!
+        transactionCount: Long!
+        code: Bytes!
+        storage(slot: Bytes32!): Bytes32!
+    }
+
+    # Log是交易执行时产生的日志。
+    type Log {
+        index: Int!
+        # account是产生日志的合约账户，block为空时取最新区块的状态。
+        account(block: Long): Account!
+        topics: [Bytes32!]!
+        data: Bytes!
+        transaction: Transaction!
+    }
+
+    # Transaction是以太坊交易，包括DPoS的投票和候选人交易。
+    type Transaction {
+        hash: Bytes32!
+        nonce: Long!
+        # index是交易在区块中的序号，交易还在交易池中时为空。
+        index: Int
+        from(block: Long): Account!
+        # to为空表示创建合约的交易。
+        to(block: Long): Account
+        value: BigInt!
+        gasPrice: BigInt!
+        gas: Long!
+        inputData: Bytes!
+        # type是DPoS交易类型：0普通交易，1注册候选人，2注销候选人，3投票，4取消投票。
+        type: Int!
+        # block是交易所在的区块，交易还在交易池中时为空。
+        block: Block
+        # 以下字段取自交易收据，交易还在交易池中时为空。
+        status: Long
+        gasUsed: Long
+        cumulativeGasUsed: Long
+        createdContract(block: Long): Account
+        logs: [Log!]
+    }
+
+    # BlockFilterCriteria是单个区块内的日志过滤条件。
+    input BlockFilterCriteria {
+        addresses: [Address!]
+        topics: [[Bytes32!]!]
+    }
+
+    # DposContext是区块头中DPoS各个trie的根哈希。
+    type DposContext {
+        epochHash: Bytes32!
+        delegateHash: Bytes32!
+        candidateHash: Bytes32!
+        voteHash: Bytes32!
+        mintCntHash: Bytes32!
+    }
+
+    # Candidate是DPoS候选人及投票给它的账户。
+    type Candidate {
+        address: Address!
+        delegators: [Address!]!
+    }
+
+    # Block是以太坊区块。
+    type Block {
+        number: Long!
+        hash: Bytes32!
+        parent: Block
+        nonce: Bytes!
+        transactionsRoot: Bytes32!
+        transactionCount: Int
+        stateRoot: Bytes32!
+        receiptsRoot: Bytes32!
+        miner(block: Long): Account!
+        # validator是签名出块的验证人。
+        validator: Address!
+        extraData: Bytes!
+        gasLimit: Long!
+        gasUsed: Long!
+        timestamp: BigInt!
+        logsBloom: Bytes!
+        mixHash: Bytes32!
+        difficulty: BigInt!
+        totalDifficulty: BigInt!
+        transactions: [Transaction!]
+        transactionAt(index: Int!): Transaction
+        logs(filter: BlockFilterCriteria!): [Log!]!
+        account(address: Address!): Account!
+        dposContext: DposContext!
+        # validators是该区块之后生效的验证人集合。
+        validators: [Address!]!
+        # candidates是该区块状态下的全部候选人。
+        candidates: [Candidate!]!
+    }
+
+    # FilterCriteria是跨区块的日志过滤条件。
+    input FilterCriteria {
+        fromBlock: Long
+        toBlock: Long
+        addresses: [Address!]
+        topics: [[Bytes32!]!]
+    }
+
+    # Pending是交易池中待打包的交易。
+    type Pending {
+        transactionCount: Int!
+        transactions: [Transaction!]
+    }
+
+    type Query {
+        # block按高度或哈希查询区块，两者都为空时返回最新区块。
+        block(number: Long, hash: Bytes32): Block
+        # blocks返回from到to（含）之间的区块，to为空时到最新区块为止。
+        blocks(from: Long!, to: Long): [Block!]!
+        pending: Pending!
+        transaction(hash: Bytes32!): Transaction
+        logs(filter: FilterCriteria!): [Log!]!
+        gasPrice: BigInt!
+        protocolVersion: Int!
+    }
+
+    type Mutation {
+        # sendRawTransaction发送已签名的RLP编码交易，返回交易哈希。
+        sendRawTransaction(data: Bytes!): Bytes32!
+    }
+`
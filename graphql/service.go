@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/les"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//Service是作为node.Service运行的GraphQL终结点，与HTTP/WS RPC终结点并列
+type Service struct {
+	endpoint string             //GraphQL监听的地址
+	cors     []string           //允许跨域请求的域名
+	vhosts   []string           //允许的虚拟主机名
+	sendTx   bool               //HTTP RPC白名单中有eth模块时才允许发送交易
+	timeouts rpc.HTTPTimeouts   //HTTP服务器的超时设置
+	auth     *rpc.Authenticator //HTTP RPC的认证器，nil表示不认证
+	limits   rpc.RateLimits     //HTTP RPC的限流配置
+	backend  ethapi.Backend     //查询使用的以太坊后端
+
+	listener net.Listener
+}
+
+//New创建GraphQL服务。modules是HTTP RPC的模块白名单，
+//GraphQL的sendRawTransaction与eth_sendRawTransaction遵守同样的限制；
+//auth和limits是HTTP RPC的认证器和限流配置，GraphQL请求同样要经过它们
+func New(backend ethapi.Backend, endpoint string, cors, vhosts, modules []string, timeouts rpc.HTTPTimeouts, auth *rpc.Authenticator, limits rpc.RateLimits) (*Service, error) {
+	s := &Service{
+		endpoint: endpoint,
+		cors:     cors,
+		vhosts:   vhosts,
+		timeouts: timeouts,
+		auth:     auth,
+		limits:   limits,
+		backend:  backend,
+	}
+	for _, module := range modules {
+		if module == "eth" {
+			s.sendTx = true
+		}
+	}
+	return s, nil
+}
+
+//Protocols实现node.Service，GraphQL不提供p2p协议
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+//APIs实现node.Service，GraphQL不提供RPC方法
+func (s *Service) APIs() []rpc.API { return nil }
+
+//Start实现node.Service，启动GraphQL的HTTP监听
+func (s *Service) Start(server *p2p.Server) error {
+	srv, err := s.newServer()
+	if err != nil {
+		return err
+	}
+	if s.listener, err = net.Listen("tcp", s.endpoint); err != nil {
+		return err
+	}
+	go srv.Serve(s.listener)
+	log.Info("GraphQL endpoint opened", "url", fmt.Sprintf("http://%s", s.endpoint))
+	return nil
+}
+
+//Stop实现node.Service，关闭GraphQL监听
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+		log.Info("GraphQL endpoint closed", "url", fmt.Sprintf("http://%s", s.endpoint))
+	}
+	return nil
+}
+
+//newServer创建GraphQL的HTTP服务器，与HTTP RPC一样检查跨域来源、虚拟主机名和令牌并限流。
+//GraphQL提供eth命名空间的数据，令牌限制了命名空间时必须允许eth
+func (s *Service) newServer() (*http.Server, error) {
+	handler, err := newHandler(s.backend, s.sendTx)
+	if err != nil {
+		return nil, err
+	}
+	handler = rpc.NewGuardHandler(s.auth, s.limits, "eth", "graphql", handler)
+	return rpc.NewHTTPServer(s.cors, s.vhosts, s.timeouts, handler), nil
+}
+
+//newHandler解析schema并返回处理GraphQL请求的http.Handler
+func newHandler(backend ethapi.Backend, sendTx bool) (http.Handler, error) {
+	q := Resolver{backend: backend, sendTx: sendTx}
+
+	s, err := graphqlgo.ParseSchema(schema, &q)
+	if err != nil {
+		return nil, err
+	}
+	h := &relay.Handler{Schema: s}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle("/graphql", h)
+	mux.Handle("/graphql/", h)
+	return mux, nil
+}
+
+//RegisterGraphQLService把GraphQL服务加入节点，查询使用全节点或轻节点的API后端
+func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts, modules []string, timeouts rpc.HTTPTimeouts, auth *rpc.Authenticator, limits rpc.RateLimits) error {
+	return stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethereum *eth.Ethereum
+		if err := ctx.Service(&ethereum); err == nil {
+			return New(ethereum.APIBackend, endpoint, cors, vhosts, modules, timeouts, auth, limits)
+		}
+		var lightEthereum *les.LightEthereum
+		if err := ctx.Service(&lightEthereum); err == nil {
+			return New(lightEthereum.ApiBackend, endpoint, cors, vhosts, modules, timeouts, auth, limits)
+		}
+		return nil, errors.New("no Ethereum service")
+	})
+}
@@ -114,6 +114,8 @@ Gas      uint64          //如果为0，则调用以接近无穷大的气体执
 GasPrice *big.Int        //气体交换率
 Value    *big.Int        //随呼叫发送的wei数量
 Data     []byte          //输入数据，通常是ABI编码的合同方法调用
+
+AccessList types.AccessList //预先声明要访问的帐户和存储槽，访问列表分叉之后有效
 }
 
 //ContractCaller提供合同调用，本质上是由
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -63,6 +64,43 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+//feeHistoryResult是eth_feeHistory的返回结果
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+//FeeHistory返回以lastBlock结尾的若干区块的基础费用、燃气使用率和小费分位数，
+//供钱包估算费用市场交易的最高费用和小费。
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, reward, baseFee, gasUsed, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	results := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsed,
+	}
+	if reward != nil {
+		results.Reward = make([][]*hexutil.Big, len(reward))
+		for i, w := range reward {
+			results.Reward[i] = make([]*hexutil.Big, len(w))
+			for j, v := range w {
+				results.Reward[i][j] = (*hexutil.Big)(v)
+			}
+		}
+	}
+	if baseFee != nil {
+		results.BaseFee = make([]*hexutil.Big, len(baseFee))
+		for i, v := range baseFee {
+			results.BaseFee[i] = (*hexutil.Big)(v)
+		}
+	}
+	return results, nil
+}
+
 //ProtocolVersion返回此节点支持的当前以太坊协议版本
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -370,7 +408,7 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return SubmitTransaction(ctx, s.b, signed)
 }
 
 //signTransaction将根据给定的参数和
@@ -393,7 +431,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -605,6 +643,8 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+//访问列表分叉之后预先声明要访问的帐户和存储槽
+	AccessList *types.AccessList `json:"accessList,omitempty"`
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
@@ -633,7 +673,11 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	}
 
 //创建新的呼叫消息
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, accessList, false)
 
 //设置上下文，以便取消调用
 //或者，对于未计量的气体，设置一个超时上下文。
@@ -725,6 +769,50 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	return hexutil.Uint64(hi), nil
 }
 
+//accessListResult是CreateAccessList的返回值
+type accessListResult struct {
+	AccessList *types.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+	Failed     bool              `json:"failed"`
+}
+
+//CreateAccessList在给定块号的状态上执行调用，返回调用访问的帐户和存储槽组成的
+//访问列表，以及带上该访问列表时用掉的燃气。访问列表会改变燃气用量，进而可能改变
+//执行路径，因此反复执行直到访问列表不再变化。blockNr默认为挂起块。
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNr *rpc.BlockNumber) (*accessListResult, error) {
+	number := rpc.PendingBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, number)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if !s.b.ChainConfig().IsAccessList(header.Number) {
+		return nil, types.ErrTxTypeNotSupported
+	}
+	var prev *vm.AccessListTracer
+	if args.AccessList != nil {
+		prev = vm.NewAccessListTracer(*args.AccessList)
+	} else {
+		prev = vm.NewAccessListTracer(nil)
+	}
+	for {
+		accessList := prev.AccessList()
+		args.AccessList = &accessList
+
+		tracer := vm.NewAccessListTracer(accessList)
+		_, gas, failed, err := s.doCall(ctx, args, number, vm.Config{Debug: true, Tracer: tracer}, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if tracer.Equal(prev) {
+			return &accessListResult{AccessList: &accessList, GasUsed: hexutil.Uint64(gas), Failed: failed}, nil
+		}
+		prev = tracer
+	}
+}
+
 //ExecutionResult将EVM发出的所有结构化日志分组
 //在调试模式和事务中重播事务时
 //执行状态、使用的气体量和返回值
@@ -810,6 +898,9 @@ head := b.Header() //复制头一次
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+	if head.BaseFee != nil {
+		fields["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
+	}
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -854,20 +945,26 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 
 //rpc transaction表示将序列化为事务的rpc表示形式的事务
 type RPCTransaction struct {
-	BlockHash        common.Hash     `json:"blockHash"`
-	BlockNumber      *hexutil.Big    `json:"blockNumber"`
-	From             common.Address  `json:"from"`
-	Gas              hexutil.Uint64  `json:"gas"`
-	GasPrice         *hexutil.Big    `json:"gasPrice"`
-	Hash             common.Hash     `json:"hash"`
-	Input            hexutil.Bytes   `json:"input"`
-	Nonce            hexutil.Uint64  `json:"nonce"`
-	To               *common.Address `json:"to"`
-	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
-	Value            *hexutil.Big    `json:"value"`
-	V                *hexutil.Big    `json:"v"`
-	R                *hexutil.Big    `json:"r"`
-	S                *hexutil.Big    `json:"s"`
+	BlockHash        common.Hash       `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	GasFeeCap        *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	GasTipCap        *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex hexutil.Uint      `json:"transactionIndex"`
+	Value            *hexutil.Big      `json:"value"`
+	Type             hexutil.Uint64    `json:"type"`
+	Action           hexutil.Uint64    `json:"action,omitempty"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	AccessList       *types.AccessList `json:"accessList,omitempty"`
+	V                *hexutil.Big      `json:"v"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
 }
 
 //NewRpcTransaction返回将序列化到RPC的事务
@@ -875,7 +972,7 @@ type RPCTransaction struct {
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewFeeMarketSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
@@ -889,10 +986,22 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		Nonce:    hexutil.Uint64(tx.Nonce()),
 		To:       tx.To(),
 		Value:    (*hexutil.Big)(tx.Value()),
+		Type:     hexutil.Uint64(tx.EnvelopeType()),
+		Action:   hexutil.Uint64(tx.Type()),
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
 	}
+	if tx.EnvelopeType() != types.LegacyTxType {
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
+	if al := tx.AccessList(); al != nil {
+		result.AccessList = &al
+	}
+	if tx.IsDynamicFee() {
+		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
+		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
@@ -901,6 +1010,11 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
+//NewRPCTransaction返回区块中交易的RPC表示形式，供ethapi之外的服务使用
+func NewRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
+	return newRPCTransaction(tx, blockHash, blockNumber, index)
+}
+
 //NewRpcPendingTransaction返回一个挂起的事务，该事务将序列化为RPC表示形式
 func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
@@ -921,7 +1035,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1031,8 +1145,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-//序列化到rlp并返回
-	return rlp.EncodeToBytes(tx)
+//序列化为规范编码并返回
+	return tx.MarshalBinary()
 }
 
 //GetTransactionReceipt返回给定事务哈希的事务回执。
@@ -1052,7 +1166,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.NewFeeMarketSigner(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -1115,7 +1229,17 @@ type SendTxArgs struct {
 //更新的名称，应为客户首选。
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
-	Type     types.TxType    `json:"type"`
+//DPoS操作类型，"type"留给交易信封类型
+	Type types.TxType `json:"action"`
+//设置了最高费用或小费时发送费用市场交易，此时不能再设置gasPrice
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
+	ChainID              *hexutil.Big `json:"chainId,omitempty"`
+//设置了访问列表时发送访问列表交易，不能同时设置最高费用或小费
+	AccessList *types.AccessList `json:"accessList,omitempty"`
+
+//下一个区块在交易信封分叉之前时按旧编码创建交易
+	preEnvelope bool
 }
 
 //setdefaults是一个帮助函数，它为未指定的tx字段填充默认值。
@@ -1124,16 +1248,26 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		args.Gas = new(hexutil.Uint64)
 		*(*uint64)(args.Gas) = 90000
 	}
-	if args.GasPrice == nil {
+	if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+		if err := args.setFeeMarketDefaults(ctx, b); err != nil {
+			return err
+		}
+	} else if args.GasPrice == nil {
 		price, err := b.SuggestPrice(ctx)
 		if err != nil {
 			return err
 		}
 		args.GasPrice = (*hexutil.Big)(price)
 	}
+	if args.AccessList != nil {
+		if err := args.setAccessListDefaults(b); err != nil {
+			return err
+		}
+	}
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
 	}
+	args.preEnvelope = !b.ChainConfig().IsTxEnvelope(new(big.Int).Add(b.CurrentBlock().Number(), common.Big1))
 	if args.Nonce == nil {
 		nonce, err := b.GetPoolNonce(ctx, args.From)
 		if err != nil {
@@ -1158,6 +1292,59 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	}
 	return nil
 }
+//setFeeMarketDefaults为费用市场交易填充小费、最高费用和链ID。默认小费是建议价格
+//超出下一个区块基础费用的部分，默认最高费用能承受基础费用翻倍。
+func (args *SendTxArgs) setFeeMarketDefaults(ctx context.Context, b Backend) error {
+	if args.GasPrice != nil {
+		return errors.New(`Both "gasPrice" and "maxFeePerGas"/"maxPriorityFeePerGas" are set. Please use only one of them.`)
+	}
+	if args.Type != types.Binary {
+		return types.ErrTxTypeNotSupported
+	}
+	config, head := b.ChainConfig(), b.CurrentBlock().Header()
+	if !config.IsFeeMarket(new(big.Int).Add(head.Number, common.Big1)) {
+		return types.ErrTxTypeNotSupported
+	}
+	baseFee := misc.CalcBaseFee(config, head)
+	if args.MaxPriorityFeePerGas == nil {
+		price, err := b.SuggestPrice(ctx)
+		if err != nil {
+			return err
+		}
+		tip := new(big.Int).Sub(price, baseFee)
+		if tip.Sign() < 0 {
+			tip.SetUint64(0)
+		}
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+	}
+	if args.MaxFeePerGas == nil {
+		feeCap := new(big.Int).Add(args.MaxPriorityFeePerGas.ToInt(), new(big.Int).Mul(baseFee, big.NewInt(2)))
+		args.MaxFeePerGas = (*hexutil.Big)(feeCap)
+	}
+	if args.MaxFeePerGas.ToInt().Cmp(args.MaxPriorityFeePerGas.ToInt()) < 0 {
+		return fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(config.ChainID)
+	}
+	return nil
+}
+
+//setAccessListDefaults检查访问列表交易能否发送并填充链ID
+func (args *SendTxArgs) setAccessListDefaults(b Backend) error {
+	if args.Type != types.Binary || args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+		return types.ErrTxTypeNotSupported
+	}
+	config := b.ChainConfig()
+	if !config.IsAccessList(new(big.Int).Add(b.CurrentBlock().Number(), common.Big1)) {
+		return types.ErrTxTypeNotSupported
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(config.ChainID)
+	}
+	return nil
+}
+
 /*
 func（args*sendtxargs）toTransaction（）*types.transaction_
  var输入[]字节
@@ -1173,12 +1360,31 @@ func（args*sendtxargs）toTransaction（）*types.transaction_
 */
 
 func (args *SendTxArgs) toTransaction() *types.Transaction {
+	tx := args.newTransaction()
+	if args.preEnvelope {
+		return tx
+	}
+	return tx.WithEnvelope()
+}
+
+//newTransaction按参数创建交易
+func (args *SendTxArgs) newTransaction() *types.Transaction {
 	var input []byte
 	if args.Data != nil {
 		input = *args.Data
 	} else if args.Input != nil {
 		input = *args.Input
 	}
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	if args.MaxFeePerGas != nil {
+		return types.NewDynamicFeeTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.MaxPriorityFeePerGas), (*big.Int)(args.MaxFeePerGas), input)
+	}
+	if args.AccessList != nil {
+		return types.NewAccessListTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input, accessList)
+	}
 	if args.Type == types.Binary && args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), *args.Data)
 	}
@@ -1189,7 +1395,7 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 }
 
 //SubmitTransaction是一个助手函数，它将Tx提交到TxPool并记录消息。
-func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
@@ -1241,17 +1447,17 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return SubmitTransaction(ctx, s.b, signed)
 }
 
 //sendrawtransaction将把签名的事务添加到事务池中。
 //发送方负责签署事务并使用正确的nonce。
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, tx)
+	return SubmitTransaction(ctx, s.b, tx)
 }
 
 //sign为以下项计算ECDSA签名：
@@ -1305,7 +1511,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1329,7 +1535,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.NewFeeMarketSigner(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, exists := accounts[from]; exists {
@@ -0,0 +1,110 @@
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//testBackend只实现执行调用所需的后端方法，每次返回一份新的状态
+type testBackend struct {
+	Backend
+	config *params.ChainConfig
+	db     state.Database
+	root   common.Hash
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	statedb, err := state.New(b.root, b.db)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		GasLimit:   10000000,
+		Time:       big.NewInt(10),
+		Difficulty: big.NewInt(1),
+	}
+	return statedb, header, nil
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, nil, &header.Coinbase)
+	return vm.NewEVM(context, state, b.config, vmCfg), func() error { return nil }, nil
+}
+
+//测试eth_createAccessList返回调用读取的存储槽和访问的帐户，并按带上该访问列表时的燃气计价；
+//访问列表分叉之前拒绝请求。
+func TestCreateAccessList(t *testing.T) {
+	var (
+		from     = common.Address{0xf0}
+		contract = common.Address{0xcc}
+		target   = common.Address{0xaa}
+		db       = state.NewDatabase(ethdb.NewMemDatabase())
+	)
+//读取存储槽1并查询target的余额
+	code := []byte{byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.POP), byte(vm.PUSH20)}
+	code = append(code, target.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP), byte(vm.STOP))
+
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.SetCode(contract, code)
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	config := *params.TestChainConfig
+	config.AccessListBlock = big.NewInt(0)
+	api := NewPublicBlockChainAPI(&testBackend{config: &config, db: db, root: root})
+
+	number := rpc.LatestBlockNumber
+	res, err := api.CreateAccessList(context.Background(), CallArgs{From: from, To: &contract}, &number)
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if res.Failed {
+		t.Fatalf("call failed")
+	}
+	want := map[common.Address][]common.Hash{
+		contract: {common.BigToHash(big.NewInt(1))},
+		target:   {},
+	}
+	if len(*res.AccessList) != len(want) {
+		t.Fatalf("access list length mismatch: have %d, want %d", len(*res.AccessList), len(want))
+	}
+	for _, tuple := range *res.AccessList {
+		keys, ok := want[tuple.Address]
+		if !ok {
+			t.Fatalf("unexpected account %x in access list", tuple.Address)
+		}
+		if len(tuple.StorageKeys) != len(keys) || (len(keys) > 0 && tuple.StorageKeys[0] != keys[0]) {
+			t.Fatalf("storage keys of %x mismatch: have %x, want %x", tuple.Address, tuple.StorageKeys, keys)
+		}
+	}
+//内在燃气、两个地址和一个存储槽，加上热读取的执行燃气
+	wantGas := params.TxGas + 2*params.TxAccessListAddressGas + params.TxAccessListStorageKeyGas + 3 + 100 + 2 + 3 + 100 + 2
+	if uint64(res.GasUsed) != wantGas {
+		t.Fatalf("gas used mismatch: have %d, want %d", res.GasUsed, wantGas)
+	}
+
+	config.AccessListBlock = big.NewInt(2)
+	if _, err := api.CreateAccessList(context.Background(), CallArgs{From: from, To: &contract}, &number); err != types.ErrTxTypeNotSupported {
+		t.Fatalf("pre-fork error mismatch: have %v, want %v", err, types.ErrTxTypeNotSupported)
+	}
+}
@@ -0,0 +1,121 @@
+package miner
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+//maxBundles是等待打包的交易包的最大数目
+	maxBundles = 256
+
+//maxBundleTxs是一个交易包中交易的最大数目
+	maxBundleTxs = 64
+)
+
+var (
+	errEmptyBundle     = errors.New("empty bundle")
+	errBundleTooLarge  = errors.New("bundle too large")
+	errBundlePoolFull  = errors.New("bundle pool full")
+	errBundleStale     = errors.New("bundle target block already mined")
+	errBundleDuplicate = errors.New("bundle already known")
+)
+
+//Bundle是一组有序的签名交易，要么全部按顺序打包在目标区块的开头，
+//要么全部不打包。
+type Bundle struct {
+	Txs         types.Transactions
+BlockNumber uint64 //目标区块高度
+}
+
+//Hash返回交易包的哈希，即所有交易哈希拼接后的哈希
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([][]byte, len(b.Txs))
+	for i, tx := range b.Txs {
+		hashes[i] = tx.Hash().Bytes()
+	}
+	return crypto.Keccak256Hash(hashes...)
+}
+
+//bundlePool保存等待打包的交易包。交易包一直保留到目标区块被开采，
+//在模拟中回滚的交易包被丢弃。
+type bundlePool struct {
+	bundles map[common.Hash]*Bundle
+	mu      sync.Mutex
+}
+
+func newBundlePool() *bundlePool {
+	return &bundlePool{bundles: make(map[common.Hash]*Bundle)}
+}
+
+//add把交易包加入池中，head是当前链头高度
+func (p *bundlePool) add(bundle *Bundle, head uint64) error {
+	if len(bundle.Txs) == 0 {
+		return errEmptyBundle
+	}
+	if len(bundle.Txs) > maxBundleTxs {
+		return errBundleTooLarge
+	}
+	if bundle.BlockNumber <= head {
+		return errBundleStale
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(head)
+	hash := bundle.Hash()
+	if _, ok := p.bundles[hash]; ok {
+		return errBundleDuplicate
+	}
+	if len(p.bundles) >= maxBundles {
+		return errBundlePoolFull
+	}
+	p.bundles[hash] = bundle
+	return nil
+}
+
+//pending返回以给定区块为目标的交易包，并删除目标区块已经过去的交易包。
+//返回的交易包按哈希排序，保证重复出块时顺序一致。
+func (p *bundlePool) pending(number uint64) []*Bundle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(number - 1)
+	var hashes []common.Hash
+	for hash, bundle := range p.bundles {
+		if bundle.BlockNumber == number {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	bundles := make([]*Bundle, len(hashes))
+	for i, hash := range hashes {
+		bundles[i] = p.bundles[hash]
+	}
+	return bundles
+}
+
+//remove丢弃给定的交易包
+func (p *bundlePool) remove(hash common.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.bundles, hash)
+}
+
+//prune删除目标区块不高于head的交易包，调用者必须持有锁
+func (p *bundlePool) prune(head uint64) {
+	for hash, bundle := range p.bundles {
+		if bundle.BlockNumber <= head {
+			delete(p.bundles, hash)
+		}
+	}
+}
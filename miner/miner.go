@@ -139,6 +139,27 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+//SetDposQuota设置每个区块优先打包的DPoS交易数量，0表示不设优先通道
+func (self *Miner) SetDposQuota(quota int) {
+	self.worker.setDposQuota(quota)
+}
+
+
+//SubmitBundle提交以给定区块为目标的交易包，交易包在模拟执行成功时
+//按顺序全部打包在区块开头，任何交易回滚时整个交易包被丢弃。
+func (self *Miner) SubmitBundle(txs types.Transactions, blockNumber uint64) (common.Hash, error) {
+	signer := types.NewEIP155Signer(self.worker.config.ChainID)
+	for _, tx := range txs {
+		if _, err := types.Sender(signer, tx); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	bundle := &Bundle{Txs: txs, BlockNumber: blockNumber}
+	if err := self.worker.bundles.add(bundle, self.eth.BlockChain().CurrentBlock().NumberU64()); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
 
 //挂起返回当前挂起的块和关联状态。
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
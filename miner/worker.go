@@ -28,7 +28,6 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"math/big"
-	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -86,6 +85,8 @@ gasPool   *core.GasPool  //用于包装交易的可用气体
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+
+uncleHeaders []*types.Header //打包进区块的叔块头
 }
 
 //任务包含共识引擎密封和结果提交的所有信息。
@@ -121,10 +122,15 @@ type worker struct {
 current        *environment                 //当前运行周期的环境。
 possibleUncles map[common.Hash]*types.Block //一组侧块作为可能的叔叔块。
 unconfirmed    *unconfirmedBlocks           //一组本地挖掘的块，等待规范性确认。
+bundles        *bundlePool                  //等待打包在区块开头的交易包
 
 mu       sync.RWMutex //用于保护coinbase和额外字段的锁
-	coinbase common.Address
-	extra    []byte
+	coinbase  common.Address
+	extra     []byte
+	dposQuota int //每个区块优先打包的DPoS交易数量，为0时不设优先通道
+
+buildMu       sync.Mutex //用于保护当前环境的锁，预构建区块时交易会并发执行
+blockInterval uint64     //出块间隔，用于计算预构建区块的时间槽
 
 snapshotMu    sync.RWMutex //用于保护块快照和状态快照的锁
 	snapshotBlock *types.Block
@@ -153,6 +159,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, eth Backend,
 		chain:              eth.BlockChain(),
 		possibleUncles:     make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), miningLogAtDepth),
+		bundles:            newBundlePool(),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
 		taskCh:             make(chan *task),
@@ -195,6 +202,13 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+//setDposQuota设置每个区块优先打包的DPoS交易数量
+func (w *worker) setDposQuota(quota int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dposQuota = quota
+}
+
 
 //Pending返回Pending状态和相应的块。
 func (w *worker) pending() (*types.Block, *state.StateDB) {
@@ -217,7 +231,9 @@ func (w *worker) pendingBlock() *types.Block {
 
 //Start将运行状态设置为1并触发新工作提交。
 func (w *worker) start(blockInterval uint64) {
+	atomic.StoreUint64(&w.blockInterval, blockInterval)
 	atomic.StoreInt32(&w.running, 1)
+	go w.prebuild()
 	go w.mintLoop(blockInterval)
 }
 
@@ -241,6 +257,10 @@ func (self *worker) mintBlock(now int64,blockInterval uint64) {
 		}
 		return
 	}
+//时间槽到了，优先密封预先构建好的区块，没有时才从头构建
+	if self.commitPrebuilt(now) {
+		return
+	}
 	self.createNewWork()
  /*
  //如果是：创建一个新的块任务
@@ -261,15 +281,39 @@ func (self *worker) mintBlock(now int64,blockInterval uint64) {
 
 }
 
+//clock返回出块使用的时钟，DPOS引擎可以替换为模拟时钟
+func (w *worker) clock() dpos.Clock {
+	if engine, ok := w.engine.(*dpos.Dpos); ok {
+		return engine.Clock()
+	}
+	return nil
+}
+
+//now返回出块使用的当前时间
+func (w *worker) now() time.Time {
+	if clock := w.clock(); clock != nil {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
 func (self *worker) mintLoop(blockInterval uint64) {
 	wt := time.Duration(int64(blockInterval))
 //默认wt为“time.second”，accouding blockinterval获取等待时间
-ticker := time.NewTicker(wt * time.Second /10).C   //香奈儿
+	interval := wt * time.Second / 10
 	for {
+		var tick <-chan time.Time
+		if clock := self.clock(); clock != nil {
+			tick = clock.After(interval)
+		} else {
+			tick = time.After(interval)
+		}
 		select {
-		case now := <-ticker:
+		case <-tick:
 			atomic.StoreInt32(&self.newTxs, 0)
-			self.mintBlock(now.Unix(),blockInterval)
+			self.mintBlock(self.now().Unix(),blockInterval)
+//没有新区块时（例如验证人缺席）时间槽过去后重新预构建
+			self.prebuild()
 		case <-self.stopper:
 			close(self.quitCh)
 			self.quitCh = make(chan struct{}, 1)
@@ -315,26 +359,35 @@ func (w *worker) mainLoop() {
 			close(w.quitCh)
 			w.quitCh = make(chan struct{}, 1)
 
+//链头变化后立即开始预构建下一个区块
+			if w.isRunning() {
+				w.prebuild()
+			}
+
 		case ev := <-w.txsCh:
 //如果不挖掘，将事务应用于挂起状态。
 //
 //注意：收到的所有交易可能与交易不连续。
 //已包含在当前挖掘块中。这些交易将
 //自动消除。
-			if !w.isRunning() && w.current != nil {
-				w.mu.RLock()
-				coinbase := w.coinbase
-				w.mu.RUnlock()
+//
+//挖掘时，把交易增量执行到以当前链头为父块的预构建区块中。
+			w.mu.RLock()
+			coinbase := w.coinbase
+			w.mu.RUnlock()
 
+			w.buildMu.Lock()
+			if w.current != nil && (!w.isRunning() || w.current.header.ParentHash == w.chain.CurrentBlock().Hash()) {
 				txs := make(map[common.Address]types.Transactions)
 				for _, tx := range ev.Txs {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
-				w.commitTransactions(txset, coinbase)
+				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				w.commitTransactions(txset, coinbase, 0)
 				w.updateSnapshot()
 			}
+			w.buildMu.Unlock()
 			atomic.AddInt32(&w.newTxs, int32(len(ev.Txs)))
 
 //系统停止
@@ -476,7 +529,7 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 		return err
 	}
 	env := &environment{
-		signer:    types.NewEIP155Signer(w.config.ChainID),
+		signer:    types.MakeSigner(w.config, header.Number),
 		state:     state,
 		dposContext: dposContext,
 		ancestors: mapset.NewSet(),
@@ -562,7 +615,8 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address) bool {
+//commitTransactions按价格和nonce顺序执行交易，limit大于0时最多打包limit笔
+func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, limit int) bool {
 //电流为零时短路
 	if w.current == nil {
 		return true
@@ -572,9 +626,14 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
 	}
 
-	var coalescedLogs []*types.Log
-
+	var (
+		coalescedLogs []*types.Log
+		committed     int
+	)
 	for {
+		if limit > 0 && committed >= limit {
+			break
+		}
 
 //如果我们没有足够的汽油进行进一步的交易，那么我们就完成了
 		if w.current.gasPool.Gas() < params.TxGas {
@@ -596,6 +655,13 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		if tx.Protected() && !w.config.IsEIP155(w.current.header.Number) {
 			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", w.config.EIP155Block)
 
+			txs.Pop()
+			continue
+		}
+//最高费用低于当前基础费用的交易无法打包，跳过该账户
+		if baseFee := w.current.header.BaseFee; baseFee != nil && tx.GasFeeCap().Cmp(baseFee) < 0 {
+			log.Trace("Skipping account with fee cap below base fee", "sender", from, "feecap", tx.GasFeeCap(), "basefee", baseFee)
+
 			txs.Pop()
 			continue
 		}
@@ -623,6 +689,7 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 //一切正常，收集日志并从同一帐户转入下一个事务
 			coalescedLogs = append(coalescedLogs, logs...)
 			w.current.tcount++
+			committed++
 			txs.Shift()
 
 		default:
@@ -657,29 +724,177 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 	return false
 }
 
+//commitBundle在当前状态的副本上按顺序执行交易包中的全部交易，全部成功后才替换
+//当前环境的状态，任何交易出错或执行失败（回滚）时丢弃副本并返回错误。
+func (w *worker) commitBundle(bundle *Bundle, coinbase common.Address) error {
+	env := w.current
+	var (
+		statedb     = env.state.Copy()
+		dposContext = env.dposContext.Copy()
+		gasPool     = *env.gasPool
+		gasUsed     = env.header.GasUsed
+		tcount      = env.tcount
+		receipts    = make([]*types.Receipt, 0, len(bundle.Txs))
+	)
+	for _, tx := range bundle.Txs {
+		if tx.Protected() && !w.config.IsEIP155(env.header.Number) {
+			return fmt.Errorf("replay protected transaction %x before EIP155", tx.Hash())
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, tcount)
+		receipt, _, err := core.ApplyTransaction(w.config, dposContext, w.chain, &coinbase, &gasPool, statedb, env.header, tx, &gasUsed, vm.Config{})
+		if err != nil {
+			return err
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return fmt.Errorf("transaction %x reverted", tx.Hash())
+		}
+		receipts = append(receipts, receipt)
+		tcount++
+	}
+	env.state, env.dposContext = statedb, dposContext
+	*env.gasPool = gasPool
+	env.header.GasUsed = gasUsed
+	env.txs = append(env.txs, bundle.Txs...)
+	env.receipts = append(env.receipts, receipts...)
+	env.tcount = tcount
+	return nil
+}
+
+//commitBundles把以当前区块为目标的交易包打包在区块开头，回滚的交易包被丢弃
+func (w *worker) commitBundles(coinbase common.Address) {
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	for _, bundle := range w.bundles.pending(w.current.header.Number.Uint64()) {
+		if err := w.commitBundle(bundle, coinbase); err != nil {
+			log.Debug("Discarding bundle", "hash", bundle.Hash(), "err", err)
+			w.bundles.remove(bundle.Hash())
+			continue
+		}
+		log.Debug("Committed bundle", "hash", bundle.Hash(), "txs", len(bundle.Txs))
+	}
+}
+
+//prebuildSlot返回以parentTime为父块时间、在now时预构建的区块应使用的时间槽
+func prebuildSlot(parentTime, now int64, blockInterval uint64) int64 {
+	slot := dpos.NextSlot(now, blockInterval)
+	if slot <= parentTime {
+		slot = dpos.NextSlot(parentTime+1, blockInterval)
+	}
+	return slot
+}
+
+//prebuilt返回当前环境是否是以parent为父块、在slot时间槽出块的区块
+func (w *worker) prebuilt(parent *types.Block, slot int64) bool {
+	return w.current != nil && w.current.header.ParentHash == parent.Hash() && w.current.header.Time.Int64() == slot
+}
+
+//prebuild在出块时间槽到来之前预先构建下一个区块并更新挂起区块。
+//之后到达的交易会增量执行到这个区块中，时间槽到来时直接密封，
+//出块间隔很短时交易执行不再挤在时间槽内。
+func (w *worker) prebuild() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	w.buildMu.Lock()
+	defer w.buildMu.Unlock()
+
+	if !w.isRunning() {
+		return
+	}
+	parent := w.chain.CurrentBlock()
+	blockInterval := atomic.LoadUint64(&w.blockInterval)
+	slot := prebuildSlot(parent.Time().Int64(), w.now().Unix(), blockInterval)
+	if w.prebuilt(parent, slot) {
+		return
+	}
+//本节点不是下一个时间槽的出块人时不预构建
+	if engine, ok := w.engine.(*dpos.Dpos); ok {
+		scheduled, err := engine.IsSlotValidator(parent, slot, blockInterval)
+		if err != nil {
+			log.Debug("Skipping prebuild, failed to look up slot validator", "slot", slot, "err", err)
+			return
+		}
+		if !scheduled {
+			return
+		}
+	}
+	if w.prepareWork(parent, slot) {
+		w.updateSnapshot()
+	}
+}
+
+//commitPrebuilt在预构建的区块正好以当前链头为父块、在now时间槽出块时
+//提交它进行密封，否则返回false。
+func (w *worker) commitPrebuilt(now int64) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	w.buildMu.Lock()
+	defer w.buildMu.Unlock()
+
+	if !w.prebuilt(w.chain.CurrentBlock(), now) {
+		return false
+	}
+	if err := w.commit(w.current.uncleHeaders, w.fullTaskHook, time.Now()); err != nil {
+		log.Error("Failed to commit prebuilt block", "err", err)
+	}
+	return true
+}
+
+//dposLane返回每个帐户挂起交易中开头连续的DPoS交易。帐户前面还有
+//普通交易时，它的DPoS交易必须按nonce顺序等待，不进入优先通道。
+//已经打包的交易在之后的普通打包中会因nonce过低被跳过。
+func dposLane(pending map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	lane := make(map[common.Address]types.Transactions)
+	for addr, txs := range pending {
+		n := 0
+		for n < len(txs) && txs[n].Type() != types.Binary {
+			n++
+		}
+		if n > 0 {
+			lane[addr] = txs[:n]
+		}
+	}
+	return lane
+}
+
 //CommitnewWork基于父块生成几个新的密封任务。
 func (w *worker) createNewWork() (){
 	w.mu.RLock()
 	defer w.mu.RUnlock()
+	w.buildMu.Lock()
+	defer w.buildMu.Unlock()
 
 	tstart := time.Now()
 	parent := w.chain.CurrentBlock()
-	fmt.Print("+++++++++++++++++++++++++++++++++++++Genesis Block MaxvalidatorSize**********\n")
-	Maxvalidatorsize  :=  w.chain.GenesisBlock().Header().MaxValidatorSize
-	blockInterVal :=w.chain.GenesisBlock().Header().BlockInterval
-	fmt.Printf("+++++++++++++++++++++++++++++++++++++MaxValidatorSize:%v +++++++++++++++++++++++++++++++++++++\n", int(Maxvalidatorsize))
-	log.Info("Currently Set Dpos Configuration","Maxvalidatorsize", int(Maxvalidatorsize),"BlockInterval", blockInterVal)
 
-	tstamp := tstart.Unix()
+	tstamp := w.now().Unix()
 	if parent.Time().Cmp(new(big.Int).SetInt64(tstamp)) >= 0 {
 		tstamp = parent.Time().Int64() + 1
 	}
 //这将确保我们今后不会走得太远。
-	if now := time.Now().Unix(); tstamp > now+1 {
+	if now := w.now().Unix(); tstamp > now+1 {
 		wait := time.Duration(tstamp-now) * time.Second
 		log.Info("Mining too far in the future", "wait", common.PrettyDuration(wait))
-		time.Sleep(wait)
+		if clock := w.clock(); clock != nil {
+			<-clock.After(wait)
+		} else {
+			time.Sleep(wait)
+		}
 	}
+	if !w.prepareWork(parent, tstamp) {
+		return
+	}
+	if err := w.commit(w.current.uncleHeaders, w.fullTaskHook, tstart); err != nil {
+		log.Error("Failed to commit new work", "err", err)
+	}
+}
+
+//prepareWork以parent为父块、tstamp为时间戳创建新的环境，并用交易包和
+//挂起的交易填充区块。调用者必须持有mu和buildMu。
+func (w *worker) prepareWork(parent *types.Block, tstamp int64) bool {
+	Maxvalidatorsize  :=  w.chain.GenesisBlock().Header().MaxValidatorSize
+	blockInterVal :=w.chain.GenesisBlock().Header().BlockInterval
+	log.Debug("Currently Set Dpos Configuration","Maxvalidatorsize", int(Maxvalidatorsize),"BlockInterval", blockInterVal)
 
 	num := parent.Number()
 	header := &types.Header{
@@ -695,13 +910,13 @@ MaxValidatorSize: Maxvalidatorsize,//为新的区块链生成新的标题
 	if w.isRunning() {
 		if w.coinbase == (common.Address{}) {
 			log.Error("Refusing to mine without etherbase")
-			return
+			return false
 		}
 		header.Coinbase = w.coinbase
 	}
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for mining", "err", err)
-		return
+		return false
 	}
 //如果我们关心DAO硬分叉，请检查是否覆盖额外的数据
 	if daoBlock := w.config.DAOForkBlock; daoBlock != nil {
@@ -720,7 +935,7 @@ header.Extra = []byte{} //如果Miner反对，不要让它使用保留的额外
 	err := w.makeCurrent(parent, header)
 	if err != nil {
 		log.Error("Failed to create mining context", "err", err)
-		return
+		return false
 	}
 //创建当前工作任务并检查所需的任何分叉转换
 	env := w.current
@@ -750,13 +965,26 @@ header.Extra = []byte{} //如果Miner反对，不要让它使用保留的额外
 	for _, hash := range badUncles {
 		delete(w.possibleUncles, hash)
 	}
+	env.uncleHeaders = uncles
 //用所有可用的挂起事务填充块。
 	pending, err := w.eth.TxPool().Pending()
 	if err != nil {
 		log.Error("Failed to fetch pending transactions", "err", err)
-		return
+		return false
 	}
 
+//交易包总是打包在区块开头
+	w.commitBundles(w.coinbase)
+
+//先在配额内打包DPoS交易，拥堵时投票和注销候选人不会被高价交易挤出
+	if w.dposQuota > 0 {
+		if dposTxs := dposLane(pending); len(dposTxs) > 0 {
+			txs := types.NewTransactionsByPriceAndNonce(w.current.signer, dposTxs, w.current.header.BaseFee)
+			if w.commitTransactions(txs, w.coinbase, w.dposQuota) {
+				return false
+			}
+		}
+	}
 //将挂起的事务拆分为本地和远程
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
@@ -766,25 +994,18 @@ header.Extra = []byte{} //如果Miner反对，不要让它使用保留的额外
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		if w.commitTransactions(txs, w.coinbase) {
-			return
+		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs, w.current.header.BaseFee)
+		if w.commitTransactions(txs, w.coinbase, 0) {
+			return false
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
-		if w.commitTransactions(txs, w.coinbase) {
-			return
+		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs, w.current.header.BaseFee)
+		if w.commitTransactions(txs, w.coinbase, 0) {
+			return false
 		}
 	}
-//
-	fmt.Print("+++++++++++++++++++++++++++Judged dpos config: maxvalidatorsize++++++++++++++++++++++++\n")
-	err1 := w.commit(uncles, w.fullTaskHook, tstart)
-	if err1 != nil{
-		log.Error(err1.Error())
-		os.Exit(0)
-	}
-	return
+	return true
 }
 
 //commit运行任何事务后状态修改，组装最终块
@@ -797,12 +1018,15 @@ func (w *worker) commit(uncles []*types.Header, interval func(), start time.Time
 		*receipts[i] = *l
 	}
 	s := w.current.state.Copy()
+//在副本上定稿，预构建的区块之后还可以继续执行新交易
+	header := types.CopyHeader(w.current.header)
+	dposContext := w.current.dposContext.Copy()
 
-	block, err := w.engine.Finalize(w.chain, w.current.header, s, w.current.txs, uncles, w.current.receipts, w.current.dposContext)
+	block, err := w.engine.Finalize(w.chain, header, s, w.current.txs, uncles, receipts, dposContext)
 	if err != nil {
 		return err
 	}
-	block.DposContext = w.current.dposContext
+	block.DposContext = dposContext
 	if w.isRunning() {
 		if interval != nil {
 			interval()
@@ -58,9 +58,9 @@ func init() {
 		Period: 10,
 		Epoch:  30000,
 	}
-	tx1, _ := types.SignTx(types.NewTransaction(0, acc1Addr, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	tx1, _ := types.SignTx(types.NewTransaction(types.Binary, 0, acc1Addr, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
 	pendingTxs = append(pendingTxs, tx1)
-	tx2, _ := types.SignTx(types.NewTransaction(1, acc1Addr, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+	tx2, _ := types.SignTx(types.NewTransaction(types.Binary, 1, acc1Addr, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
 	newTxs = append(newTxs, tx2)
 }
 
@@ -434,3 +434,153 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 	}
 }
 
+
+//测试DPoS优先通道只包含每个帐户开头连续的DPoS交易
+func TestDposLane(t *testing.T) {
+	var (
+		voter    = common.Address{0x01}
+		mixed    = common.Address{0x02}
+		transfer = common.Address{0x03}
+		to       = common.Address{0xff}
+	)
+	pending := map[common.Address]types.Transactions{
+		voter: {
+			types.NewTransaction(types.Delegate, 0, to, big.NewInt(0), 100000, big.NewInt(1), nil),
+			types.NewTransaction(types.UnDelegate, 1, to, big.NewInt(0), 100000, big.NewInt(1), nil),
+			types.NewTransaction(types.Binary, 2, to, big.NewInt(0), 100000, big.NewInt(1), nil),
+		},
+		mixed: {
+			types.NewTransaction(types.Binary, 0, to, big.NewInt(0), 100000, big.NewInt(1), nil),
+			types.NewTransaction(types.Delegate, 1, to, big.NewInt(0), 100000, big.NewInt(1), nil),
+		},
+		transfer: {
+			types.NewTransaction(types.Binary, 0, to, big.NewInt(0), 100000, big.NewInt(1), nil),
+		},
+	}
+	lane := dposLane(pending)
+	if len(lane) != 1 {
+		t.Fatalf("lane accounts mismatch: have %d, want %d", len(lane), 1)
+	}
+	if len(lane[voter]) != 2 {
+		t.Fatalf("lane transactions mismatch: have %d, want %d", len(lane[voter]), 2)
+	}
+	if len(pending[voter]) != 3 {
+		t.Fatalf("pending transactions modified")
+	}
+}
+
+//测试交易包池拒绝过期和重复的交易包，并在目标区块过去后删除交易包
+func TestBundlePool(t *testing.T) {
+	to := common.Address{0xff}
+	newBundle := func(nonce uint64, number uint64) *Bundle {
+		tx := types.NewTransaction(types.Binary, nonce, to, big.NewInt(0), 100000, big.NewInt(1), nil)
+		return &Bundle{Txs: types.Transactions{tx}, BlockNumber: number}
+	}
+	pool := newBundlePool()
+
+	if err := pool.add(&Bundle{BlockNumber: 11}, 10); err != errEmptyBundle {
+		t.Fatalf("empty bundle error mismatch: have %v, want %v", err, errEmptyBundle)
+	}
+	if err := pool.add(newBundle(0, 10), 10); err != errBundleStale {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, errBundleStale)
+	}
+	if err := pool.add(newBundle(0, 11), 10); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := pool.add(newBundle(0, 11), 10); err != errBundleDuplicate {
+		t.Fatalf("duplicate bundle error mismatch: have %v, want %v", err, errBundleDuplicate)
+	}
+	if err := pool.add(newBundle(1, 12), 10); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if bundles := pool.pending(11); len(bundles) != 1 {
+		t.Fatalf("pending bundles mismatch: have %d, want %d", len(bundles), 1)
+	}
+	if bundles := pool.pending(12); len(bundles) != 1 {
+		t.Fatalf("pending bundles mismatch: have %d, want %d", len(bundles), 1)
+	}
+	if len(pool.bundles) != 1 {
+		t.Fatalf("stale bundles not pruned: have %d, want %d", len(pool.bundles), 1)
+	}
+	pool.remove(newBundle(1, 12).Hash())
+	if len(pool.bundles) != 0 {
+		t.Fatalf("bundle not removed")
+	}
+}
+
+//测试交易包中后面的交易回滚时整个交易包被丢弃，当前环境的状态不受影响
+func TestCommitBundleRevert(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	b := newTestWorkerBackend(t, ethashChainConfig, engine)
+	w := &worker{config: ethashChainConfig, engine: engine, chain: b.chain}
+
+	parent := b.chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Time:       new(big.Int).Add(parent.Time(), common.Big1),
+		Difficulty: common.Big1,
+	}
+	if err := w.makeCurrent(parent, header); err != nil {
+		t.Fatalf("failed to prepare environment: %v", err)
+	}
+	w.current.gasPool = new(core.GasPool).AddGas(header.GasLimit)
+	root := w.current.state.IntermediateRoot(true)
+	dposRoot := w.current.dposContext.Root()
+
+//第二笔交易创建合约时执行REVERT
+	signer := types.HomesteadSigner{}
+	transfer, _ := types.SignTx(types.NewTransaction(types.Binary, 0, acc1Addr, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+	revert, _ := types.SignTx(types.NewContractCreation(1, big.NewInt(0), 100000, nil, []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}), signer, testBankKey)
+
+	bundle := &Bundle{Txs: types.Transactions{transfer, revert}, BlockNumber: header.Number.Uint64()}
+	if err := w.commitBundle(bundle, testBankAddress); err == nil {
+		t.Fatalf("reverted bundle committed")
+	}
+	if len(w.current.txs) != 0 || len(w.current.receipts) != 0 || w.current.tcount != 0 {
+		t.Fatalf("bundle transactions left in environment: txs %d, receipts %d, tcount %d", len(w.current.txs), len(w.current.receipts), w.current.tcount)
+	}
+	if header.GasUsed != 0 || w.current.gasPool.Gas() != header.GasLimit {
+		t.Fatalf("bundle gas not restored: used %d, pool %d", header.GasUsed, w.current.gasPool.Gas())
+	}
+	if have := w.current.state.IntermediateRoot(true); have != root {
+		t.Fatalf("state root mismatch: have %x, want %x", have, root)
+	}
+	if have := w.current.dposContext.Root(); have != dposRoot {
+		t.Fatalf("dpos root mismatch: have %x, want %x", have, dposRoot)
+	}
+//去掉回滚的交易后交易包整体生效
+	bundle = &Bundle{Txs: types.Transactions{transfer}, BlockNumber: header.Number.Uint64()}
+	if err := w.commitBundle(bundle, testBankAddress); err != nil {
+		t.Fatalf("failed to commit bundle: %v", err)
+	}
+	if len(w.current.txs) != 1 || w.current.tcount != 1 {
+		t.Fatalf("bundle transactions mismatch: txs %d, tcount %d", len(w.current.txs), w.current.tcount)
+	}
+	if balance := w.current.state.GetBalance(acc1Addr); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("account balance mismatch: have %d, want %d", balance, 1000)
+	}
+}
+
+//测试预构建区块总是使用父块之后、当前时间之后的第一个时间槽
+func TestPrebuildSlot(t *testing.T) {
+	tests := []struct {
+		parent, now int64
+		interval    uint64
+		slot        int64
+	}{
+		{parent: 100, now: 101, interval: 2, slot: 102},
+		{parent: 100, now: 102, interval: 2, slot: 102},
+		{parent: 102, now: 102, interval: 2, slot: 104},
+		{parent: 100, now: 107, interval: 5, slot: 110},
+		{parent: 110, now: 107, interval: 5, slot: 115},
+	}
+	for i, tt := range tests {
+		if slot := prebuildSlot(tt.parent, tt.now, tt.interval); slot != tt.slot {
+			t.Errorf("test %d: slot mismatch: have %d, want %d", i, slot, tt.slot)
+		}
+	}
+}
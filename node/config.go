@@ -63,6 +63,10 @@ type Config struct {
 //在记忆中。
 	DataDir string
 
+//DBEngine是新建数据库使用的存储引擎（见ethdb.Engines）。为空时已有数据库
+//沿用创建时的引擎，新数据库使用LevelDB。
+	DBEngine string `toml:",omitempty"`
+
 //对等网络的配置。
 	P2P p2p.Config
 
@@ -146,6 +150,32 @@ type Config struct {
 //对不受信任用户的私有API是一个主要的安全风险。
 	WSExposeAll bool `toml:",omitempty"`
 
+//RPCRateLimits是HTTP和WebSocket RPC终结点的请求限流配置，包括每个客户端的
+//速率和并发上限以及按方法的限额。IPC和进程内终结点只供本机使用，不受限制。
+	RPCRateLimits rpc.RateLimits `toml:",omitempty"`
+
+//RPCJWTSecret是十六进制编码的32字节JWT密钥文件的路径。设置后HTTP和WebSocket
+//终结点只接受以该密钥HS256签名的令牌，以及RPCAPIKeys中的API密钥。
+	RPCJWTSecret string `toml:",omitempty"`
+
+//RPCAPIKeys把API密钥映射到它能访问的命名空间，"*"表示全部命名空间。
+//设置后HTTP和WebSocket终结点要求认证。
+	RPCAPIKeys map[string][]string `toml:",omitempty"`
+
+//graphqlhost是启动GraphQL服务器的主机接口。如果该字段为空，
+//不会启动GraphQL服务。
+	GraphQLHost string `toml:",omitempty"`
+
+//graphqlport是启动GraphQL服务器的TCP端口号。
+	GraphQLPort int `toml:",omitempty"`
+
+//graphqlcors是发送给GraphQL客户端的跨源资源共享头。
+	GraphQLCors []string `toml:",omitempty"`
+
+//graphqlvirtualhosts是GraphQL请求允许的虚拟主机名列表，
+//作用与httpvirtualhosts相同。
+	GraphQLVirtualHosts []string `toml:",omitempty"`
+
 //logger是用于p2p.server的自定义记录器。
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -219,6 +249,14 @@ func (c *Config) WSEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
 }
 
+//graphqlendpoint基于配置的主机接口和端口解析GraphQL终结点。
+func (c *Config) GraphQLEndpoint() string {
+	if c.GraphQLHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.GraphQLHost, c.GraphQLPort)
+}
+
 //defaultwsendpoint返回默认情况下使用的WebSocket端点。
 func DefaultWSEndpoint() string {
 	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
@@ -263,6 +301,25 @@ var isOldGethResource = map[string]bool{
 	"trusted-nodes.json": true,
 }
 
+//RPCAuthenticator根据配置创建HTTP、WebSocket和GraphQL终结点的认证器，未配置认证时返回nil
+func (c *Config) RPCAuthenticator() (*rpc.Authenticator, error) {
+	if c.RPCJWTSecret == "" && len(c.RPCAPIKeys) == 0 {
+		return nil, nil
+	}
+	var secret []byte
+	if c.RPCJWTSecret != "" {
+		path := c.RPCJWTSecret
+		if !filepath.IsAbs(path) && c.DataDir != "" {
+			path = c.ResolvePath(path)
+		}
+		var err error
+		if secret, err = rpc.LoadJWTSecret(path); err != nil {
+			return nil, err
+		}
+	}
+	return rpc.NewAuthenticator(secret, c.RPCAPIKeys), nil
+}
+
 //resolvepath解析实例目录中的路径。
 func (c *Config) ResolvePath(path string) string {
 	if filepath.IsAbs(path) {
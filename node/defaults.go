@@ -28,6 +28,8 @@ DefaultHTTPHost = "localhost" //HTTP RPC服务器的默认主机接口
 DefaultHTTPPort = 8545        //HTTP RPC服务器的默认TCP端口
 DefaultWSHost   = "localhost" //WebSocket RPC服务器的默认主机接口
 DefaultWSPort   = 8546        //WebSocket RPC服务器的默认TCP端口
+DefaultGraphQLHost = "localhost" //GraphQL服务器的默认主机接口
+DefaultGraphQLPort = 8547        //GraphQL服务器的默认TCP端口
 )
 
 //默认配置包含合理的默认设置。
@@ -39,6 +41,8 @@ var DefaultConfig = Config{
 	HTTPTimeouts:     rpc.DefaultHTTPTimeouts,
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
+	GraphQLPort:         DefaultGraphQLPort,
+	GraphQLVirtualHosts: []string{"localhost"},
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   25,
@@ -251,6 +251,11 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
+//HTTP和WebSocket终结点共用同一个认证器，在开始服务前创建
+	auth, err := n.config.RPCAuthenticator()
+	if err != nil {
+		return err
+	}
 //启动各种API端点，在出现错误时终止所有端点
 	if err := n.startInProc(apis); err != nil {
 		return err
@@ -259,12 +264,12 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts); err != nil {
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts, auth); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, auth); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
@@ -327,15 +332,16 @@ func (n *Node) stopIPC() {
 }
 
 //StartHTTP初始化并启动HTTP RPC终结点。
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) error {
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts, auth *rpc.Authenticator) error {
 //如果HTTP端点未暴露，则短路
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts, auth)
 	if err != nil {
 		return err
 	}
+	handler.SetRateLimits(n.config.RPCRateLimits)
 n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s“，终结点），”cors“，strings.join（cors，”，“），”vhosts“，strings.join（vhosts，”，“））
 //所有侦听器都已成功启动
 	n.httpEndpoint = endpoint
@@ -360,15 +366,16 @@ n.log.Info("HTTP endpoint closed", "url", fmt.Sprintf("http://%s“，n.httpendp
 }
 
 //startws初始化并启动WebSocket RPC终结点。
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, auth *rpc.Authenticator) error {
 //如果没有暴露WS端点，则短路
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, auth)
 	if err != nil {
 		return err
 	}
+	handler.SetRateLimits(n.config.RPCRateLimits)
 n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s“，listener.addr（）））
 //所有侦听器都已成功启动
 	n.wsEndpoint = endpoint
@@ -566,7 +573,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase(), nil
 	}
-	return ethdb.NewLDBDatabase(n.config.ResolvePath(name), cache, handles)
+	return ethdb.Open(n.config.DBEngine, n.config.ResolvePath(name), cache, handles)
 }
 
 //resolvepath返回实例目录中资源的绝对路径。
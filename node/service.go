@@ -39,7 +39,7 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	if ctx.config.DataDir == "" {
 		return ethdb.NewMemDatabase(), nil
 	}
-	db, err := ethdb.NewLDBDatabase(ctx.config.ResolvePath(name), cache, handles)
+	db, err := ethdb.Open(ctx.config.DBEngine, ctx.config.ResolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
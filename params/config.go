@@ -91,16 +91,16 @@ var (
 //
 //此配置有意不使用键字段强制任何人
 //向配置中添加标志也必须设置这些字段。
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil,nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil,nil}
 
 //AllCliqueProtocolChanges包含引入的每个协议更改（EIP）
 //并被以太坊核心开发者接纳为集团共识。
 //
 //此配置有意不使用键字段强制任何人
 //向配置中添加标志也必须设置这些字段。
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000},nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000},nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil,nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil,nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -126,6 +126,9 @@ EIP158Block *big.Int `json:"eip158Block,omitempty"` //EIP158高频阻滞
 
 ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      //拜占庭开关块（nil=无分叉，0=已在拜占庭）
 ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` //君士坦丁堡开关块（nil=无叉，0=已激活）
+TxEnvelopeBlock     *big.Int `json:"txEnvelopeBlock,omitempty"`     //交易信封开关块，之前交易按类型在首位的旧列表编码（nil=无叉，0=已激活）
+FeeMarketBlock      *big.Int `json:"feeMarketBlock,omitempty"`      //费用市场（基础费用销毁）开关块（nil=无叉，0=已激活）
+AccessListBlock     *big.Int `json:"accessListBlock,omitempty"`     //访问列表交易和冷热存取燃气开关块（nil=无叉，0=已激活）
 
 //各种共识引擎
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -138,12 +141,29 @@ type DposConfig struct {
 Validators []common.Address `json:"validators"` //Genesis验证程序列表
 MaxValidatorSize uint64		`json:"maxValidatorSize"` //Genesis最大验证大小
 	BlockInterval 	 uint64		`json:"blockInterval"`
+
+PrecompileBlock *big.Int `json:"precompileBlock,omitempty"` //读取DPoS状态的预编译合约开关块（nil=不启用，0=已启用）
+StakingBlock    *big.Int `json:"stakingBlock,omitempty"`    //合约可调用的DPoS质押系统合约开关块（nil=不启用，0=已启用）
+ValidatorListBlock *big.Int `json:"validatorListBlock,omitempty"` //区块头携带验证人列表的开关块（nil=不启用，0=已启用）
+VacancyBlock       *big.Int `json:"vacancyBlock,omitempty"`       //周期内由备选候选人补位空缺验证人的开关块（nil=不启用，0=已启用）
 }
 
 //字符串实现Stringer接口，返回共识引擎详细信息。
 func (d *DposConfig) String() string {
 	return "dpos"
 }
+
+//IsValidatorList返回num处的区块头是否携带验证人列表，
+//之前的区块只能从父块的DposContext中取得验证人集合
+func (d *DposConfig) IsValidatorList(num *big.Int) bool {
+	return d != nil && isForked(d.ValidatorListBlock, num)
+}
+
+//IsVacancy返回num处的区块是否在周期内由备选候选人补位空缺的验证人，
+//之前的区块中空缺位置一直保留到下一个周期选举
+func (d *DposConfig) IsVacancy(num *big.Int) bool {
+	return d != nil && isForked(d.VacancyBlock, num)
+}
 //ethashconfig是基于工作证明的密封的共识引擎配置。
 type EthashConfig struct{}
 
@@ -174,7 +194,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v TxEnvelope: %v FeeMarket: %v AccessList: %v Engine: %v}",
 		c.ChainID,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -184,6 +204,9 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.TxEnvelopeBlock,
+		c.FeeMarketBlock,
+		c.AccessListBlock,
 		engine,
 	)
 }
@@ -223,6 +246,31 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+//IsTxEnvelope返回num是否等于或大于交易信封分叉块。
+func (c *ChainConfig) IsTxEnvelope(num *big.Int) bool {
+	return isForked(c.TxEnvelopeBlock, num)
+}
+
+//IsFeeMarket返回num是否等于或大于费用市场分叉块。
+func (c *ChainConfig) IsFeeMarket(num *big.Int) bool {
+	return isForked(c.FeeMarketBlock, num)
+}
+
+//IsAccessList返回num是否等于或大于访问列表分叉块。
+func (c *ChainConfig) IsAccessList(num *big.Int) bool {
+	return isForked(c.AccessListBlock, num)
+}
+
+//IsDposPrecompile返回num处是否启用了读取DPoS状态的预编译合约
+func (c *ChainConfig) IsDposPrecompile(num *big.Int) bool {
+	return c.Dpos != nil && isForked(c.Dpos.PrecompileBlock, num)
+}
+
+//IsDposStaking返回num处是否启用了合约可调用的DPoS质押系统合约
+func (c *ChainConfig) IsDposStaking(num *big.Int) bool {
+	return c.Dpos != nil && isForked(c.Dpos.StakingBlock, num)
+}
+
 //Gastable返回与当前阶段（宅基地或宅基地重印）对应的气体表。
 //
 //在任何情况下，返回的加斯塔布尔的字段都不应该更改。
@@ -288,9 +336,58 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.TxEnvelopeBlock, newcfg.TxEnvelopeBlock, head) {
+		return newCompatError("Transaction envelope fork block", c.TxEnvelopeBlock, newcfg.TxEnvelopeBlock)
+	}
+	if isForkIncompatible(c.FeeMarketBlock, newcfg.FeeMarketBlock, head) {
+		return newCompatError("Fee market fork block", c.FeeMarketBlock, newcfg.FeeMarketBlock)
+	}
+	if isForkIncompatible(c.AccessListBlock, newcfg.AccessListBlock, head) {
+		return newCompatError("Access list fork block", c.AccessListBlock, newcfg.AccessListBlock)
+	}
+	if isForkIncompatible(c.dposPrecompileBlock(), newcfg.dposPrecompileBlock(), head) {
+		return newCompatError("DPoS precompile fork block", c.dposPrecompileBlock(), newcfg.dposPrecompileBlock())
+	}
+	if isForkIncompatible(c.dposStakingBlock(), newcfg.dposStakingBlock(), head) {
+		return newCompatError("DPoS staking fork block", c.dposStakingBlock(), newcfg.dposStakingBlock())
+	}
+	if isForkIncompatible(c.dposValidatorListBlock(), newcfg.dposValidatorListBlock(), head) {
+		return newCompatError("DPoS validator list fork block", c.dposValidatorListBlock(), newcfg.dposValidatorListBlock())
+	}
+	if isForkIncompatible(c.dposVacancyBlock(), newcfg.dposVacancyBlock(), head) {
+		return newCompatError("DPoS vacancy fork block", c.dposVacancyBlock(), newcfg.dposVacancyBlock())
+	}
 	return nil
 }
 
+func (c *ChainConfig) dposPrecompileBlock() *big.Int {
+	if c.Dpos == nil {
+		return nil
+	}
+	return c.Dpos.PrecompileBlock
+}
+
+func (c *ChainConfig) dposStakingBlock() *big.Int {
+	if c.Dpos == nil {
+		return nil
+	}
+	return c.Dpos.StakingBlock
+}
+
+func (c *ChainConfig) dposValidatorListBlock() *big.Int {
+	if c.Dpos == nil {
+		return nil
+	}
+	return c.Dpos.ValidatorListBlock
+}
+
+func (c *ChainConfig) dposVacancyBlock() *big.Int {
+	if c.Dpos == nil {
+		return nil
+	}
+	return c.Dpos.VacancyBlock
+}
+
 //如果无法将在s1上计划的分叉重新计划为，则IsForkCompatible返回true
 //阻塞s2，因为头已经过了分叉。
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -354,7 +451,7 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainID                                   *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	IsByzantium, IsAccessList                 bool
 }
 
 //规则确保C的chainID不为零。
@@ -363,6 +460,6 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainID == nil {
 		chainID = new(big.Int)
 	}
-	return Rules{ChainID: new(big.Int).Set(chainID), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainID: new(big.Int).Set(chainID), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsAccessList: c.IsAccessList(num)}
 }
 
@@ -74,6 +74,20 @@ Bn256AddGas             uint64 = 500    //椭圆曲线加法所需的气体
 Bn256ScalarMulGas       uint64 = 40000  //椭圆曲线标量乘法所需的气体
 Bn256PairingBaseGas     uint64 = 100000 //椭圆曲线配对检验的基价
 Bn256PairingPerPointGas uint64 = 80000  //椭圆曲线配对检查的每点价格
+DposReadGas             uint64 = 800    //读取DPoS状态的预编译合约的基价
+DposPerItemGas          uint64 = 200    //DPoS预编译合约返回或统计的每项价格
+DposDelegatorGas        uint64 = 2600   //统计票数时每个投票人的价格，需要读取投票人余额，不低于冷帐户读取
+DposStakingGas          uint64 = 20000  //通过系统合约修改DPoS状态的价格
+
+InitialBaseFee           = 1000000000 //费用市场分叉块的基础费用
+BaseFeeChangeDenominator = 8          //基础费用每个区块最多变化1/8
+ElasticityMultiplier     = 2          //区块燃气上限是燃气目标的倍数
+
+ColdAccountAccessCostEIP2929 = uint64(2600) //交易中第一次访问帐户的价格
+ColdSloadCostEIP2929         = uint64(2100) //交易中第一次读取存储槽的价格
+WarmStorageReadCostEIP2929   = uint64(100)  //再次访问帐户或存储槽的价格
+TxAccessListAddressGas       = uint64(2400) //访问列表中每个地址的价格
+TxAccessListStorageKeyGas    = uint64(1900) //访问列表中每个存储槽的价格
 )
 
 var (
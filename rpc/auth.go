@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//jwtClockSkew是校验JWT时间声明时允许的时钟偏差，iat声明必须在当前时间前后这个范围内
+const jwtClockSkew = 60 * time.Second
+
+var (
+	errMissingToken = errors.New("missing authentication token")
+	errInvalidToken = errors.New("invalid authentication token")
+	errExpiredToken = errors.New("authentication token expired")
+	errStaleToken   = errors.New("authentication token issued-at claim missing or out of range")
+)
+
+//authNamespacesKey是上下文中保存已认证客户端允许访问的命名空间的键
+type authNamespacesKey struct{}
+
+//unauthorizedError在已认证的客户端调用不允许的命名空间时返回
+type unauthorizedError struct{ message string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string { return e.message }
+
+//Authenticator校验HTTP和WebSocket请求携带的令牌。令牌可以是用共享密钥
+//以HS256签名的JWT，也可以是配置好的API密钥，每个API密钥只能访问指定的命名空间。
+//
+//令牌通过"Authorization: Bearer <token>"请求头传递；浏览器无法为WebSocket
+//设置请求头，因此WebSocket也接受"?token=<token>"查询参数。
+type Authenticator struct {
+	secret  []byte                     //JWT共享密钥，为空时不接受JWT
+	apiKeys map[string]map[string]bool //API密钥到允许的命名空间，nil表示全部
+	now     func() time.Time
+}
+
+//NewAuthenticator创建认证器。apiKeys把API密钥映射到允许访问的命名空间，
+//命名空间"*"表示允许访问终结点上的全部命名空间。
+func NewAuthenticator(secret []byte, apiKeys map[string][]string) *Authenticator {
+	a := &Authenticator{
+		secret:  secret,
+		apiKeys: make(map[string]map[string]bool),
+		now:     time.Now,
+	}
+	for key, namespaces := range apiKeys {
+		a.apiKeys[key] = namespaceSet(namespaces)
+	}
+	return a
+}
+
+//LoadJWTSecret从文件读取十六进制编码的32字节JWT密钥
+func LoadJWTSecret(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("invalid JWT secret in %s: want 32 bytes, have %d", path, len(secret))
+	}
+	return secret, nil
+}
+
+//namespaceSet把命名空间列表转换成集合，包含"*"时返回nil表示不限制
+func namespaceSet(namespaces []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, namespace := range namespaces {
+		if namespace == "*" {
+			return nil
+		}
+		set[namespace] = true
+	}
+	return set
+}
+
+//authenticate校验请求的令牌，返回允许访问的命名空间，nil表示全部
+func (a *Authenticator) authenticate(r *http.Request) (map[string]bool, error) {
+	token := ""
+	if header := r.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return nil, errInvalidToken
+		}
+		token = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	} else {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return nil, errMissingToken
+	}
+	if namespaces, ok := a.apiKeys[token]; ok {
+		return namespaces, nil
+	}
+	if strings.Count(token, ".") == 2 && len(a.secret) > 0 {
+		return a.verifyJWT(token)
+	}
+	return nil, errInvalidToken
+}
+
+//verifyJWT校验HS256签名的JWT。令牌必须带有"iat"声明且与当前时间相差不超过jwtClockSkew，
+//这样泄露的令牌很快就会失效，客户端需要每次请求时重新签发。
+//可选的"namespaces"声明限制令牌能访问的命名空间
+func (a *Authenticator) verifyJWT(token string) (map[string]bool, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+
+	var claims struct {
+		Exp        *int64   `json:"exp"`
+		Nbf        *int64   `json:"nbf"`
+		Iat        *int64   `json:"iat"`
+		Namespaces []string `json:"namespaces"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errInvalidToken
+	}
+	now := a.now()
+	skew := int64(jwtClockSkew / time.Second)
+	if claims.Exp != nil && now.Unix() >= *claims.Exp+skew {
+		return nil, errExpiredToken
+	}
+	if claims.Nbf != nil && now.Unix() < *claims.Nbf-skew {
+		return nil, errInvalidToken
+	}
+	if claims.Iat == nil || now.Unix() < *claims.Iat-skew || now.Unix() > *claims.Iat+skew {
+		return nil, errStaleToken
+	}
+	if len(claims.Namespaces) == 0 {
+		return nil, nil
+	}
+	return namespaceSet(claims.Namespaces), nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+//withAuth把允许的命名空间放入上下文
+func withAuth(ctx context.Context, namespaces map[string]bool) context.Context {
+	if namespaces == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, authNamespacesKey{}, namespaces)
+}
+
+//authorized判断上下文中的客户端是否允许调用给定命名空间，元数据命名空间总是允许
+func authorized(ctx context.Context, namespace string) bool {
+	namespaces, ok := ctx.Value(authNamespacesKey{}).(map[string]bool)
+	if !ok || namespace == MetadataApi {
+		return true
+	}
+	return namespaces[namespace]
+}
@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//signJWT用给定密钥和声明生成HS256签名的JWT
+func signJWT(secret []byte, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	body, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(body)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticatorJWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Unix(1000000, 0)
+	auth := NewAuthenticator(secret, nil)
+	auth.now = func() time.Time { return now }
+
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{signJWT(secret, map[string]interface{}{"iat": now.Unix()}), nil},
+		{signJWT(secret, map[string]interface{}{"iat": now.Unix() - 30}), nil},
+		{signJWT(secret, map[string]interface{}{"iat": now.Unix(), "exp": now.Unix() - 3600}), errExpiredToken},
+		{signJWT(secret, map[string]interface{}{"iat": now.Unix() + 3600}), errStaleToken},
+		{signJWT(secret, map[string]interface{}{"iat": now.Unix() - 3600}), errStaleToken},
+//没有iat和exp的令牌不能永久有效
+		{signJWT(secret, map[string]interface{}{}), errStaleToken},
+		{signJWT(secret, map[string]interface{}{"exp": now.Unix() + 3600}), errStaleToken},
+		{signJWT([]byte("wrong secret"), map[string]interface{}{"iat": now.Unix()}), errInvalidToken},
+		{"", errMissingToken},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if _, err := auth.authenticate(req); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestAuthenticatorNamespaces(t *testing.T) {
+	auth := NewAuthenticator(nil, map[string][]string{
+		"reader": {"eth", "net"},
+		"admin":  {"*"},
+	})
+//WebSocket客户端可以用查询参数传递令牌
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/?token=reader", nil)
+	namespaces, err := auth.authenticate(req)
+	if err != nil {
+		t.Fatalf("api key rejected: %v", err)
+	}
+	ctx := withAuth(context.Background(), namespaces)
+	if !authorized(ctx, "eth") || !authorized(ctx, MetadataApi) {
+		t.Error("allowed namespace rejected")
+	}
+	if authorized(ctx, "admin") {
+		t.Error("disallowed namespace accepted")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/?token=admin", nil)
+	if namespaces, err = auth.authenticate(req); err != nil {
+		t.Fatalf("api key rejected: %v", err)
+	}
+	if !authorized(withAuth(context.Background(), namespaces), "admin") {
+		t.Error("wildcard key rejected")
+	}
+}
+
+func TestHTTPAuthentication(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.auth = NewAuthenticator(nil, map[string][]string{"key": {"test"}})
+	defer server.Stop()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["hello",10,{"S":"world"}]}`
+	post := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+		req.Header.Set("content-type", contentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post(""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: have status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := post("bad"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token: have status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := post("key"); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"error"`) {
+		t.Fatalf("valid token rejected: %d %s", rec.Code, rec.Body.String())
+	}
+}
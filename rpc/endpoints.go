@@ -19,7 +19,8 @@ import (
 )
 
 //starthttpendpoint启动用cors/vhosts/modules配置的HTTP RPC终结点
-func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts) (net.Listener, *Server, error) {
+//auth不为空时，所有请求都必须携带有效的令牌
+func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts, auth *Authenticator) (net.Listener, *Server, error) {
 //根据允许的模块生成白名单
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -27,6 +28,7 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 //注册服务公开的所有API
 	handler := NewServer()
+	handler.auth = auth
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -48,7 +50,8 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 }
 
 //startwsendpoint启动WebSocket终结点
-func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
+//auth不为空时，所有连接都必须携带有效的令牌
+func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, auth *Authenticator) (net.Listener, *Server, error) {
 
 //根据允许的模块生成白名单
 	whitelist := make(map[string]bool)
@@ -57,6 +60,7 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 	}
 //注册服务公开的所有API
 	handler := NewServer()
+	handler.auth = auth
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -187,10 +187,10 @@ func (t *httpReadWriteNopCloser) Close() error {
 	return nil
 }
 
-//new http server围绕API提供程序创建一个新的HTTP RPC服务器。
+//new http server围绕给定的处理程序（RPC服务器或GraphQL处理程序）创建一个新的HTTP服务器。
 //
 //已弃用：服务器实现http.handler
-func NewHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv *Server) *http.Server {
+func NewHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv http.Handler) *http.Server {
 //在主机处理程序中包装CORS处理程序
 	handler := newCorsHandler(srv, cors)
 	handler = newVHostHandler(vhosts, handler)
@@ -231,6 +231,14 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 //直到并将响应写入w，然后命令服务器处理
 //单一请求。
 	ctx := r.Context()
+	if srv.auth != nil {
+		namespaces, err := srv.auth.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		ctx = withAuth(ctx, namespaces)
+	}
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
@@ -261,7 +269,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 //如果用户未指定自定义CORS配置，则禁用CORS支持
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -322,3 +330,54 @@ func newVHostHandler(vhosts []string, next http.Handler) http.Handler {
 	return &virtualHostHandler{vhostMap, next}
 }
 
+
+//guardHandler让不经过RPC服务器的HTTP处理程序（例如GraphQL）与HTTP RPC终结点
+//一样校验令牌并按客户端IP限流
+type guardHandler struct {
+	auth      *Authenticator
+	limiter   *limiter
+	method    string //RateLimits.Methods中使用的名称
+	namespace string //令牌限制了命名空间时必须允许的命名空间
+	next      http.Handler
+}
+
+func (h *guardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.auth != nil {
+		namespaces, err := h.auth.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if namespaces != nil && !namespaces[h.namespace] {
+			http.Error(w, fmt.Sprintf("namespace %s not allowed", h.namespace), http.StatusForbidden)
+			return
+		}
+	}
+	if h.limiter != nil {
+		client := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(client); err == nil {
+			client = host
+		}
+		release, err := h.limiter.acquire(client, h.method)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+//NewGuardHandler用HTTP RPC终结点的认证器和限流配置包装next。令牌限制了命名空间时
+//必须允许namespace；method是RateLimits.Methods中对应这个处理程序的名称。
+//auth为nil且没有配置限流时直接返回next。
+func NewGuardHandler(auth *Authenticator, limits RateLimits, namespace, method string, next http.Handler) http.Handler {
+	h := &guardHandler{auth: auth, method: method, namespace: namespace, next: next}
+	if limits.enabled() {
+		h.limiter = newLimiter(limits)
+	}
+	if h.auth == nil && h.limiter == nil {
+		return next
+	}
+	return h
+}
@@ -0,0 +1,196 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+//limiterIdleTimeout是客户端限流状态在空闲多久之后被清理
+const limiterIdleTimeout = 5 * time.Minute
+
+//RateLimits配置RPC服务器的请求限流。所有数值为零时表示不限制。
+//
+//客户端限额按连接计算：WebSocket和IPC每个连接单独计数，
+//HTTP请求每次都是新的连接，因此按远程IP地址计数。
+type RateLimits struct {
+	Rate          float64 `toml:",omitempty"` //每个客户端每秒允许的请求数
+	Burst         int     `toml:",omitempty"` //每个客户端允许的突发请求数，默认为Rate向上取整
+	MaxConcurrent int     `toml:",omitempty"` //每个客户端同时执行的请求数上限
+
+//Methods按方法名（例如"debug_traceTransaction"）设置所有客户端合计的限额
+	Methods map[string]MethodLimit `toml:",omitempty"`
+}
+
+//MethodLimit是单个RPC方法在所有客户端之间共享的限额
+type MethodLimit struct {
+	Rate          float64 `toml:",omitempty"` //每秒允许的调用数
+	Burst         int     `toml:",omitempty"` //允许的突发调用数，默认为Rate向上取整
+	MaxConcurrent int     `toml:",omitempty"` //同时执行的调用数上限
+}
+
+//enabled判断是否配置了任何限额
+func (l RateLimits) enabled() bool {
+	return l.Rate > 0 || l.MaxConcurrent > 0 || len(l.Methods) > 0
+}
+
+//rateLimitError在请求超过限额时返回给客户端
+type rateLimitError struct{ message string }
+
+func (e *rateLimitError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitError) Error() string { return e.message }
+
+//bucket是令牌桶，配合计数器同时限制请求速率和并发数
+type bucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time //上次补充令牌的时间
+	seen     time.Time //上次使用的时间，用于清理空闲客户端
+	inflight int
+	limit    int
+}
+
+func newBucket(rate float64, burst int, concurrent int, now time.Time) *bucket {
+	b := &bucket{rate: rate, burst: float64(burst), limit: concurrent, last: now, seen: now}
+	if b.burst < 1 {
+		b.burst = float64(int(rate + 0.999999))
+		if b.burst < 1 {
+			b.burst = 1
+		}
+	}
+	b.tokens = b.burst
+	return b
+}
+
+//take尝试取走一个令牌并占用一个并发名额，失败时返回原因
+func (b *bucket) take(now time.Time) string {
+	b.seen = now
+	if b.limit > 0 && b.inflight >= b.limit {
+		return "too many concurrent requests"
+	}
+	if b.rate > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return "request rate exceeded"
+		}
+		b.tokens--
+	}
+	b.inflight++
+	return ""
+}
+
+//refund归还take取走的令牌，用于后续检查失败时
+func (b *bucket) refund() {
+	b.inflight--
+	if b.rate > 0 {
+		b.tokens++
+	}
+}
+
+//limiter保存客户端和方法两级的限流状态
+type limiter struct {
+	limits  RateLimits
+	clients map[interface{}]*bucket
+	methods map[string]*bucket
+	pruned  time.Time
+	now     func() time.Time
+
+	lock sync.Mutex
+}
+
+func newLimiter(limits RateLimits) *limiter {
+	l := &limiter{
+		limits:  limits,
+		clients: make(map[interface{}]*bucket),
+		methods: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	l.pruned = l.now()
+	for name, limit := range limits.Methods {
+		l.methods[name] = newBucket(limit.Rate, limit.Burst, limit.MaxConcurrent, l.pruned)
+	}
+	return l
+}
+
+//acquire为客户端调用方法申请名额，成功时返回的release必须在请求完成后调用
+func (l *limiter) acquire(client interface{}, method string) (func(), Error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	var cb *bucket
+	if l.limits.Rate > 0 || l.limits.MaxConcurrent > 0 {
+		if cb = l.clients[client]; cb == nil {
+			cb = newBucket(l.limits.Rate, l.limits.Burst, l.limits.MaxConcurrent, now)
+			l.clients[client] = cb
+		}
+		if reason := cb.take(now); reason != "" {
+			return nil, &rateLimitError{fmt.Sprintf("rate limit exceeded: %s", reason)}
+		}
+	}
+	mb := l.methods[method]
+	if mb != nil {
+		if reason := mb.take(now); reason != "" {
+			if cb != nil {
+				cb.refund()
+			}
+			return nil, &rateLimitError{fmt.Sprintf("rate limit exceeded for %s: %s", method, reason)}
+		}
+	}
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		if cb != nil {
+			cb.inflight--
+			cb.seen = l.now()
+		}
+		if mb != nil {
+			mb.inflight--
+		}
+	}, nil
+}
+
+//forget在连接关闭时删除它的限流状态
+func (l *limiter) forget(client interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if cb := l.clients[client]; cb != nil && cb.inflight == 0 {
+		delete(l.clients, client)
+	}
+}
+
+//prune清理长时间空闲的客户端，主要是按IP计数的HTTP客户端
+func (l *limiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < limiterIdleTimeout {
+		return
+	}
+	for client, cb := range l.clients {
+		if cb.inflight == 0 && now.Sub(cb.seen) > limiterIdleTimeout {
+			delete(l.clients, client)
+		}
+	}
+	l.pruned = now
+}
+
+//clientKey返回限流使用的客户端标识：HTTP请求使用远程IP，其它连接使用编解码器本身
+func clientKey(ctx context.Context, codec ServerCodec) interface{} {
+	if remote, ok := ctx.Value("remote").(string); ok && remote != "" {
+		if host, _, err := net.SplitHostPort(remote); err == nil {
+			return host
+		}
+		return remote
+	}
+	return codec
+}
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterRateAndConcurrency(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(RateLimits{
+		Rate:          1,
+		Burst:         2,
+		MaxConcurrent: 2,
+		Methods: map[string]MethodLimit{
+			"debug_traceTransaction": {MaxConcurrent: 1},
+		},
+	})
+	l.now = func() time.Time { return now }
+
+//突发额度内的两个请求通过，第三个被拒绝
+	release1, err := l.acquire("a", "eth_blockNumber")
+	if err != nil {
+		t.Fatalf("first request rejected: %v", err)
+	}
+	release1()
+	release2, err := l.acquire("a", "eth_blockNumber")
+	if err != nil {
+		t.Fatalf("second request rejected: %v", err)
+	}
+	release2()
+	if _, err := l.acquire("a", "eth_blockNumber"); err == nil || err.ErrorCode() != -32005 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+//其它客户端有各自的额度
+	if _, err := l.acquire("b", "eth_blockNumber"); err != nil {
+		t.Fatalf("other client rejected: %v", err)
+	}
+//令牌随时间补充
+	now = now.Add(2 * time.Second)
+	trace, err := l.acquire("a", "debug_traceTransaction")
+	if err != nil {
+		t.Fatalf("request after refill rejected: %v", err)
+	}
+//方法的并发上限由所有客户端共享
+	if _, err := l.acquire("c", "debug_traceTransaction"); err == nil {
+		t.Fatal("expected method concurrency limit")
+	}
+	trace()
+	if _, err := l.acquire("c", "debug_traceTransaction"); err != nil {
+		t.Fatalf("request after release rejected: %v", err)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetRateLimits(RateLimits{
+		Methods: map[string]MethodLimit{"test_echo": {Rate: 0.001, Burst: 1}},
+	})
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"})
+	if rerr, ok := err.(*jsonError); !ok || rerr.Code != -32005 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+//没有限额的方法不受影响
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("unlimited method failed: %v", err)
+	}
+}
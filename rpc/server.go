@@ -112,6 +112,22 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 	return nil
 }
 
+//SetRateLimits设置服务器的请求限流，超过限额的请求返回-32005错误。
+//限额全部为零时取消限流
+func (s *Server) SetRateLimits(limits RateLimits) {
+	if limits.enabled() {
+		s.limiter.Store(newLimiter(limits))
+	} else {
+		s.limiter.Store((*limiter)(nil))
+	}
+}
+
+//rateLimiter返回当前的限流器，未配置时返回nil
+func (s *Server) rateLimiter() *limiter {
+	l, _ := s.limiter.Load().(*limiter)
+	return l
+}
+
 //ServerRequest将从编解码器读取请求，调用RPC回调和
 //将响应写入给定的编解码器。
 //
@@ -131,6 +147,10 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 		s.codecsMu.Lock()
 		s.codecs.Remove(codec)
 		s.codecsMu.Unlock()
+
+		if l := s.rateLimiter(); l != nil {
+			l.forget(codec)
+		}
 	}()
 
 //ctx，取消：=context.withcancel（context.background（））
@@ -253,6 +273,11 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
 
+//认证的客户端只能调用令牌允许的命名空间
+	if !authorized(ctx, req.svcname) {
+		return codec.CreateErrorResponse(&req.id, &unauthorizedError{fmt.Sprintf("namespace %s not allowed", req.svcname)}), nil
+	}
+
 if req.isUnsubscribe { //取消订阅，第一个参数必须是订阅ID
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
 			notifier, supported := NotifierFromContext(ctx)
@@ -270,6 +295,16 @@ if !supported { //接口不支持订阅（例如http）
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+//检查客户端和方法的限额
+	if l := s.rateLimiter(); l != nil {
+		method := req.svcname + serviceMethodSeparator + formatName(req.callb.method.Name)
+		release, err := l.acquire(clientKey(ctx, codec), method)
+		if err != nil {
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+		defer release()
+	}
+
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
@@ -18,6 +18,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -70,6 +71,9 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   mapset.Set
+
+	limiter atomic.Value   //*limiter，未配置限流时为空
+	auth    *Authenticator //HTTP和WebSocket请求的认证器，为空时不认证
 }
 
 //rpc request表示原始传入的rpc请求
@@ -52,9 +52,31 @@ var websocketJSONCodec = websocket.Codec{
 //allowedorigins应该是允许的原始URL的逗号分隔列表。
 //要允许与任何来源的连接，请通过“*”。
 func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
+	validateOrigin := wsHandshakeValidator(allowedOrigins)
 	return websocket.Server{
-		Handshake: wsHandshakeValidator(allowedOrigins),
+		Handshake: func(cfg *websocket.Config, req *http.Request) error {
+			if err := validateOrigin(cfg, req); err != nil {
+				return err
+			}
+//配置了认证时，握手阶段就拒绝没有有效令牌的连接
+			if srv.auth != nil {
+				if _, err := srv.auth.authenticate(req); err != nil {
+					log.Warn("Unauthenticated WS-RPC connection rejected", "remote", req.RemoteAddr, "err", err)
+					return err
+				}
+			}
+			return nil
+		},
 		Handler: func(conn *websocket.Conn) {
+			ctx := context.Background()
+			if srv.auth != nil {
+				namespaces, err := srv.auth.authenticate(conn.Request())
+				if err != nil {
+					conn.Close()
+					return
+				}
+				ctx = withAuth(ctx, namespaces)
+			}
 //创建自定义编码/解码对以强制有效负载大小和数字编码
 			conn.MaxPayloadBytes = maxRequestContentLength
 
@@ -64,7 +86,9 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -29,7 +29,6 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/rlp"
 )
 
 //ExternalAPI定义用于发出签名请求的外部API。
@@ -352,7 +351,7 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		return nil, err
 	}
 
-	rlpdata, err := rlp.EncodeToBytes(signedTx)
+	rlpdata, err := signedTx.MarshalBinary()
 	response := ethapi.SignTransactionResult{Raw: rlpdata, Tx: signedTx}
 
 //最后，将签名的Tx发送到UI
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+var (
+//ErrSealerNotAllowed在请求签名的账号没有被配置为出块账号时返回
+	ErrSealerNotAllowed = errors.New("account not allowed to seal blocks")
+//ErrSealerDoubleSign在请求签名的区块时间槽不大于上一次签名时返回
+	ErrSealerDoubleSign = errors.New("refusing to sign block at non-increasing slot")
+)
+
+//SealerAPI为DPOS验证人提供出块签名服务。出块是自动进行的，
+//不能每个区块都让用户确认，所以只允许预先配置的账号签名，
+//并且在签名前用自身的存储检查时间槽单调递增，
+//即使节点被攻破也不能让验证人对同一个时间槽签两个区块。
+//DPoS中重复签名是按时间槽判断的，链重组之后验证人需要在之后的时间槽里
+//重新签名同一高度或更低高度的区块，所以不检查高度。
+//出块签名需要对任意哈希签名，accounts/usbwallet中的硬件钱包固件不支持，
+//因此出块账号只能是clef管理的keystore账号。
+type SealerAPI struct {
+	am          *accounts.Manager
+	validators  map[common.Address]bool
+	history     storage.Storage
+	credentials storage.Storage
+
+	lock sync.Mutex
+}
+
+//NewSealerAPI创建出块签名服务。history用于持久化每个验证人
+//最后签名的高度和时间槽，credentials保存keystore账号的密码
+func NewSealerAPI(api *SignerAPI, validators []common.Address, history, credentials storage.Storage) *SealerAPI {
+	allowed := make(map[common.Address]bool)
+	for _, validator := range validators {
+		allowed[validator] = true
+	}
+	return &SealerAPI{
+		am:          api.am,
+		validators:  allowed,
+		history:     history,
+		credentials: credentials,
+	}
+}
+
+//SignBlock对RLP编码的区块头签名，返回65字节的签名，
+//可以直接写入区块头额外数据的末尾
+func (api *SealerAPI) SignBlock(ctx context.Context, addr common.MixedcaseAddress, headerRLP hexutil.Bytes) (hexutil.Bytes, error) {
+	address := addr.Address()
+	if !api.validators[address] {
+		return nil, ErrSealerNotAllowed
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(headerRLP, header); err != nil {
+		return nil, err
+	}
+	if header.Number == nil || header.Time == nil || header.DposContext == nil {
+		return nil, errors.New("incomplete block header")
+	}
+	if header.Validator != address {
+		return nil, fmt.Errorf("header validator %s mismatch signer %s", header.Validator.Hex(), address.Hex())
+	}
+	if len(header.Extra) < 65 {
+		return nil, errors.New("extra-data 65 byte suffix signature missing")
+	}
+	number, slot := header.Number.Uint64(), header.Time.Uint64()
+
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+//先检查并记录签名历史再签名，签名失败也不会回退，宁可漏块也不重复签名
+	key := strings.ToLower(address.String())
+	lastNumber, lastSlot, err := parseSealHistory(api.history.Get(key))
+	if err != nil {
+		return nil, err
+	}
+	if slot <= lastSlot {
+		log.Warn("Refused to seal block", "validator", address, "number", number, "slot", slot, "lastNumber", lastNumber, "lastSlot", lastSlot)
+		return nil, ErrSealerDoubleSign
+	}
+	api.history.Put(key, fmt.Sprintf("%d:%d", number, slot))
+
+	account := accounts.Account{Address: address}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignHashWithPassphrase(account, api.credentials.Get(key), dpos.SealHash(header).Bytes())
+	if err == accounts.ErrNotSupported {
+		return nil, fmt.Errorf("wallet %s cannot sign block hashes: %v", wallet.URL(), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Sealed block", "validator", address, "number", number, "slot", slot, "hash", header.Hash())
+	return signature, nil
+}
+
+//parseSealHistory解析存储中的"高度:时间槽"记录，空记录表示从未签过名
+func parseSealHistory(value string) (uint64, uint64, error) {
+	if value == "" {
+		return 0, 0, nil
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("corrupted seal history: %q", value)
+	}
+	number, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("corrupted seal history: %v", err)
+	}
+	slot, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("corrupted seal history: %v", err)
+	}
+	return number, slot, nil
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/dpos"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+func sealHeader(t *testing.T, validator common.Address, number, slot int64) hexutil.Bytes {
+	header := &types.Header{
+		Validator:   validator,
+		Number:      big.NewInt(number),
+		Time:        big.NewInt(slot),
+		Difficulty:  big.NewInt(1),
+		Extra:       make([]byte, 32+65),
+		DposContext: &types.DposContextProto{},
+	}
+	blob, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
+
+func TestSealerSignBlock(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	validator := list(control, api, t)[0].Address
+	credentials := storage.NewEphemeralStorage()
+	credentials.Put(strings.ToLower(validator.String()), "apassword")
+
+	sealer := NewSealerAPI(api, []common.Address{validator}, storage.NewEphemeralStorage(), credentials)
+	addr := common.NewMixedcaseAddress(validator)
+
+//未配置的账号不能出块签名
+	other := common.NewMixedcaseAddress(common.HexToAddress("0x1111111111111111111111111111111111111111"))
+	if _, err := sealer.SignBlock(context.Background(), other, sealHeader(t, other.Address(), 1, 10)); err != ErrSealerNotAllowed {
+		t.Fatalf("expected %v, got %v", ErrSealerNotAllowed, err)
+	}
+//签名可以恢复出验证人地址
+	blob := sealHeader(t, validator, 1, 10)
+	sig, err := sealer.SignBlock(context.Background(), addr, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := new(types.Header)
+	rlp.DecodeBytes(blob, header)
+	pubkey, err := crypto.SigToPub(dpos.SealHash(header).Bytes(), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(*pubkey) != validator {
+		t.Fatalf("signature recovered wrong address")
+	}
+//同一时间槽或更早的时间槽不能重复签名
+	for _, c := range []struct{ number, slot int64 }{{2, 10}, {0, 5}} {
+		if _, err := sealer.SignBlock(context.Background(), addr, sealHeader(t, validator, c.number, c.slot)); err != ErrSealerDoubleSign {
+			t.Errorf("number %d slot %d: expected %v, got %v", c.number, c.slot, ErrSealerDoubleSign, err)
+		}
+	}
+	if _, err := sealer.SignBlock(context.Background(), addr, sealHeader(t, validator, 2, 20)); err != nil {
+		t.Fatal(err)
+	}
+//链重组之后在之后的时间槽里重新签名同一高度或更低高度的区块
+	if _, err := sealer.SignBlock(context.Background(), addr, sealHeader(t, validator, 2, 30)); err != nil {
+		t.Fatalf("re-seal at same height in later slot: %v", err)
+	}
+	if _, err := sealer.SignBlock(context.Background(), addr, sealHeader(t, validator, 1, 40)); err != nil {
+		t.Fatalf("re-seal at lower height in later slot: %v", err)
+	}
+	if _, err := sealer.SignBlock(context.Background(), addr, sealHeader(t, validator, 3, 40)); err != ErrSealerDoubleSign {
+		t.Fatalf("expected %v, got %v", ErrSealerDoubleSign, err)
+	}
+}